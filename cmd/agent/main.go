@@ -3,10 +3,16 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 
+	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/admin"
 	"github.com/mikeziminio/go-custom-metrics/internal/agent"
 	"github.com/mikeziminio/go-custom-metrics/internal/agent/config"
 	"github.com/mikeziminio/go-custom-metrics/internal/log"
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/tracing"
 )
 
 func main() {
@@ -15,13 +21,57 @@ func main() {
 
 	c := config.NewFromFlags()
 	logger := log.New()
+	shutdownTracing, err := tracing.Setup(ctx, "metrics-agent", tracing.Config{Exporter: c.TraceExporter, Endpoint: c.TraceEndpoint})
+	if err != nil {
+		logger.Fatal("failed to setup tracing", zap.Error(err))
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Error("failed to shutdown tracing", zap.Error(err))
+		}
+	}()
+
+	var transport agent.Transport
+	switch c.Transport {
+	case config.TransportHTTP:
+		transport = agent.NewHTTPTransport(
+			fmt.Sprintf("http://%s", c.Address),
+			c.ConcurrentRequests,
+			logger,
+		)
+	case config.TransportGRPC:
+		t, err := agent.NewGRPCTransport(c.Address, c.Key, logger)
+		if err != nil {
+			logger.Fatal("failed to init grpc transport", zap.Error(err))
+		}
+		transport = t
+	default:
+		logger.Fatal("unknown transport", zap.String("transport", c.Transport))
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		logger.Fatal("failed to get hostname", zap.Error(err))
+	}
+	instance := c.Instance
+	if instance == "" {
+		instance = hostname
+	}
+
 	a := agent.New(
-		fmt.Sprintf("http://%s", c.Address),
+		transport,
 		c.PollInterval,
 		c.ReportInterval,
-		c.ConcurrentRequests,
+		model.NewLabels(agent.LabelHost, hostname, agent.LabelInstance, instance),
 		logger,
 	)
 
+	if c.StatusAddress != "" {
+		go a.ServeStatus(ctx, c.StatusAddress)
+	}
+	if c.PprofAddress != "" {
+		go admin.Serve(ctx, c.PprofAddress, logger)
+	}
+
 	a.Run(ctx)
 }
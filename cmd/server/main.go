@@ -2,11 +2,20 @@ package main
 
 import (
 	"context"
+	"net"
+	"sync"
 
+	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/admin"
+	"github.com/mikeziminio/go-custom-metrics/internal/graphite"
+	"github.com/mikeziminio/go-custom-metrics/internal/grpcserver"
 	"github.com/mikeziminio/go-custom-metrics/internal/log"
 	"github.com/mikeziminio/go-custom-metrics/internal/memstorage"
 	"github.com/mikeziminio/go-custom-metrics/internal/server"
 	"github.com/mikeziminio/go-custom-metrics/internal/server/config"
+	"github.com/mikeziminio/go-custom-metrics/internal/statsd"
+	"github.com/mikeziminio/go-custom-metrics/internal/tracing"
 )
 
 func main() {
@@ -15,9 +24,120 @@ func main() {
 
 	c := config.NewFromFlags()
 	logger := log.New()
-	ms := memstorage.New()
+	shutdownTracing, err := tracing.Setup(ctx, "metrics-server", tracing.Config{Exporter: c.TraceExporter, Endpoint: c.TraceEndpoint})
+	if err != nil {
+		logger.Fatal("failed to setup tracing", zap.Error(err))
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Error("failed to shutdown tracing", zap.Error(err))
+		}
+	}()
 
+	tiers := memstorage.DefaultTiers
+	if len(c.RollupTiers) > 0 {
+		tiers = make([]memstorage.Tier, 0, len(c.RollupTiers))
+		for _, s := range c.RollupTiers {
+			t, err := memstorage.ParseTier(s)
+			if err != nil {
+				logger.Fatal("failed to parse rollup tier", zap.Error(err))
+			}
+			tiers = append(tiers, t)
+		}
+	}
+	ms := memstorage.New(c.HistoryRetention, tiers...)
+	// MemStorage не реализует server.Pinger и ничего не восстанавливает при запуске, поэтому
+	// дополнительные проверки /readyz (AddReadinessCheck) не регистрируются: сервер готов сразу.
 	s := server.New(c.Address, ms, logger)
+	s.SetShutdownDelay(c.ShutdownDelay)
+	if err := s.SetWebSocketOrigins(c.WebSocketOrigins); err != nil {
+		logger.Fatal("failed to set websocket origins", zap.Error(err))
+	}
 	s.RegisterRoutes()
+	// обновления из всех приемников проходят через хранилище сервера для рассылки в поток обновлений
+	storage := s.Storage()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ms.RunCompaction(ctx, s.Telemetry(), logger)
+	}()
+
+	if c.PprofAddress != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			admin.Serve(ctx, c.PprofAddress, logger)
+		}()
+	}
+
+	if c.TelemetryInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.RunTelemetryExport(ctx, c.TelemetryInterval)
+		}()
+	}
+
+	if c.StatsdAddress != "" {
+		l := statsd.New(c.StatsdAddress, c.StatsdFlushInterval, storage, logger)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Run(ctx)
+		}()
+	}
+
+	if c.GraphiteAddress != "" {
+		templates, err := graphite.NewTemplates(c.GraphiteTemplates, c.GraphiteSeparator)
+		if err != nil {
+			logger.Fatal("failed to parse graphite templates", zap.Error(err))
+		}
+		l := graphite.New(c.GraphiteAddress, templates, c.GraphiteCounterSuffixes, storage, logger)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Run(ctx)
+		}()
+	}
+
+	if c.GRPCAddress != "" {
+		trustedSubnet, trustedProxies, err := trustedNetworks(c)
+		if err != nil {
+			logger.Fatal("failed to parse trusted subnet", zap.Error(err))
+		}
+		gs := grpcserver.New(c.GRPCAddress, storage, trustedSubnet, trustedProxies, c.Key, logger)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			gs.Run(ctx)
+		}()
+	}
+
 	s.Run(ctx)
+
+	cancel()
+	wg.Wait()
+}
+
+// trustedNetworks - доверенная подсеть клиентов gRPC и подсети прокси, nil - проверка не выполняется
+func trustedNetworks(c *config.Config) (*net.IPNet, []*net.IPNet, error) {
+	var subnet *net.IPNet
+	if c.TrustedSubnet != "" {
+		_, ipNet, err := net.ParseCIDR(c.TrustedSubnet)
+		if err != nil {
+			return nil, nil, err
+		}
+		subnet = ipNet
+	}
+	proxies := make([]*net.IPNet, 0, len(c.TrustedProxies))
+	for _, p := range c.TrustedProxies {
+		_, ipNet, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		proxies = append(proxies, ipNet)
+	}
+	return subnet, proxies, nil
 }
@@ -0,0 +1,45 @@
+// Package admin - служебный http сервер с профилированием net/http/pprof.
+// Запускается на отдельном адресе, чтобы профили не были доступны вместе с основным API.
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// readHeaderTimeout - ограничение времени чтения заголовков запроса
+const readHeaderTimeout = time.Second
+
+// Handler - обработчики pprof на /debug/pprof/
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// Serve - служебный сервер на address до завершения ctx.
+// WriteTimeout не задается: профиль CPU пишется в ответ все время сбора.
+func Serve(ctx context.Context, address string, logger *zap.Logger) {
+	srv := &http.Server{
+		Addr:              address,
+		Handler:           Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+	}()
+	logger.Info("Admin server started", zap.String("address", address))
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("failed to serve admin", zap.Error(err))
+	}
+}
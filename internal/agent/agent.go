@@ -2,21 +2,26 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"math/rand/v2"
-	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
-	"golang.org/x/sync/semaphore"
 
 	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/telemetry"
 )
 
 var (
@@ -49,41 +54,125 @@ var (
 	MetricTotalAlloc    = "TotalAlloc"
 	MetricPollCount     = "PollCount"
 	MetricRandomValue   = "RandomValue"
+	MetricGCPause       = "GCPause"
 )
 
+// tracer - спаны циклов сбора и отправки метрик, провайдер задается tracing.Setup
+var tracer = otel.Tracer("github.com/mikeziminio/go-custom-metrics/internal/agent")
+
+// GCPauseBuckets - границы корзин гистограммы пауз сборщика мусора, в секундах
+var GCPauseBuckets = []float64{0.00001, 0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5}
+
+// Метки, которые агент по умолчанию добавляет ко всем метрикам
+const (
+	LabelHost     = "host"
+	LabelInstance = "instance"
+)
+
+// Transport - способ доставки метрик на сервер
+type Transport interface {
+	// SendAll - ошибка *SendError означает, что остальные метрики доставлены,
+	// любая другая ошибка - что не доставлена ни одна
+	SendAll(ctx context.Context, metrics []model.Metric) error
+	// RegisterMetadata - заменяет на сервере метаданные метрик, ключ - имя метрики
+	RegisterMetadata(ctx context.Context, metadata map[string]model.Metadata) error
+	Close() error
+}
+
+// SendError - часть метрик не доставлена, Failed - их индексы в переданном SendAll срезе
+type SendError struct {
+	Failed []int
+	Err    error
+}
+
+func (e *SendError) Error() string {
+	return fmt.Sprintf("failed to send %d metrics: %v", len(e.Failed), e.Err)
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// failedMetrics - признаки недоставленных метрик по ошибке SendAll
+func failedMetrics(err error, n int) []bool {
+	failed := make([]bool, n)
+	var sendErr *SendError
+	if !errors.As(err, &sendErr) {
+		for i := range failed {
+			failed[i] = true
+		}
+		return failed
+	}
+	for _, i := range sendErr.Failed {
+		failed[i] = true
+	}
+	return failed
+}
+
 type Agent struct {
+	labels         model.Labels
 	pollInterval   float64
 	reportInterval float64
 	gauges         map[string]float64
 	counters       map[string]int64
-	mu             sync.RWMutex
-	client         *http.Client
-	baseURL        string
-	sem            *semaphore.Weighted
-	logger         *zap.Logger
+	histograms     map[string]*model.HistogramData
+	// numGC - количество циклов сборки мусора на момент предыдущего сбора метрик
+	numGC uint32
+	// metadataRegistered - метаданные встроенных метрик успешно отправлены на сервер
+	metadataRegistered atomic.Bool
+	// telemetry, exporter - собственные метрики агента, отправляются вместе с остальными
+	telemetry *telemetry.Registry
+	exporter  *telemetry.Exporter
+	// sendsSucceeded, sendsFailed, sendsAfterFailure - счетчики отправок с запуска
+	sendsSucceeded    atomic.Int64
+	sendsFailed       atomic.Int64
+	sendsAfterFailure atomic.Int64
+	// retrying - предыдущая отправка метрик завершилась ошибкой
+	retrying atomic.Bool
+	// lastSuccess - время последней успешной отправки в наносекундах Unix, 0 - отправок не было
+	lastSuccess atomic.Int64
+	// lastCollectDuration - длительность последнего сбора метрик в наносекундах
+	lastCollectDuration atomic.Int64
+	mu                  sync.RWMutex
+	transport           Transport
+	logger              *zap.Logger
 }
 
+// New - labels добавляются ко всем отправляемым метрикам
 func New(
-	baseURL string,
+	transport Transport,
 	pollInterval float64,
 	reportInterval float64,
-	concurrentRequests int,
+	labels model.Labels,
 	logger *zap.Logger,
 ) *Agent {
-	client := &http.Client{}
-	return &Agent{
+	reg := telemetry.NewRegistry()
+	a := &Agent{
+		labels:         labels,
 		pollInterval:   pollInterval,
 		reportInterval: reportInterval,
 		gauges:         make(map[string]float64),
 		counters:       make(map[string]int64),
-		client:         client,
-		baseURL:        baseURL,
-		sem:            semaphore.NewWeighted(int64(concurrentRequests)),
+		histograms:     make(map[string]*model.HistogramData),
+		telemetry:      reg,
+		exporter:       telemetry.NewExporter(reg, TelemetryPrefix),
+		transport:      transport,
 		logger:         logger,
 	}
+	a.registerTelemetry()
+	return a
 }
 
 func (a *Agent) Collect() {
+	_, span := tracer.Start(context.Background(), "agent.collect")
+	defer span.End()
+	start := time.Now()
+	defer func() {
+		d := time.Since(start)
+		a.lastCollectDuration.Store(int64(d))
+		a.telemetry.Observe(metricCollectDuration, nil, d.Seconds())
+	}()
+
 	var ms runtime.MemStats
 	runtime.ReadMemStats(&ms)
 
@@ -118,85 +207,148 @@ func (a *Agent) Collect() {
 	a.gauges[MetricTotalAlloc] = float64(ms.TotalAlloc)
 	a.gauges[MetricRandomValue] = rand.Float64() //nolint:gosec // it's ok
 	a.counters[MetricPollCount]++
+	a.observeGCPauses(&ms)
 }
 
-func (a *Agent) Send(ctx context.Context, m *model.Metric) error {
-	a.logger.Info("send metric start", zap.String("metric", fmt.Sprintf("%v", m)))
-	var u string
-	var err error
-	switch m.MType {
-	case model.Counter:
-		u, err = url.JoinPath(
-			a.baseURL, "/update",
-			fmt.Sprintf("/%s/%s/%d", string(m.MType), m.ID, *m.Delta),
-		)
-	case model.Gauge:
-		u, err = url.JoinPath(
-			a.baseURL, "/update",
-			fmt.Sprintf("/%s/%s/%.5f", string(m.MType), m.ID, *m.Value),
-		)
-	default:
-		panic(fmt.Sprintf("unknown metric type: %s", m.MType))
+// observeGCPauses - добавляет в гистограмму паузы циклов сборки мусора,
+// завершившихся после предыдущего сбора. MemStats хранит только 256 последних пауз.
+func (a *Agent) observeGCPauses(ms *runtime.MemStats) {
+	h, ok := a.histograms[MetricGCPause]
+	if !ok {
+		h, _ = model.NewHistogram(GCPauseBuckets)
+		a.histograms[MetricGCPause] = h
 	}
-	if err != nil {
-		return fmt.Errorf("failed to join url path for sending metric %s, %v", a.baseURL, m)
+	first := a.numGC + 1
+	if ms.NumGC > uint32(len(ms.PauseNs)) {
+		first = max(first, ms.NumGC-uint32(len(ms.PauseNs))+1)
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, http.NoBody)
-	if err != nil {
-		return fmt.Errorf("failed to init request: %w", err)
+	for n := first; n <= ms.NumGC; n++ {
+		pause := ms.PauseNs[(n+uint32(len(ms.PauseNs))-1)%uint32(len(ms.PauseNs))]
+		h.Observe(time.Duration(pause).Seconds()) //nolint:gosec // it's ok
 	}
-	_ = a.sem.Acquire(ctx, 1)
-	defer a.sem.Release(1)
-	res, err := a.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to do request: %w", err)
+	a.numGC = ms.NumGC
+}
+
+// Metrics - снимок текущих значений всех метрик
+func (a *Agent) Metrics() []model.Metric {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.metrics()
+}
+
+func (a *Agent) metrics() []model.Metric {
+	metrics := make([]model.Metric, 0, len(a.gauges)+len(a.counters)+len(a.histograms))
+	for name, val := range a.gauges {
+		metrics = append(metrics, model.Metric{
+			ID:     name,
+			MType:  model.Gauge,
+			Labels: a.labels,
+			Value:  &val,
+		})
 	}
-	defer res.Body.Close() //nolint:errcheck // it's ok
-	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code for request %s: %d", u, res.StatusCode)
+	for name, val := range a.counters {
+		metrics = append(metrics, model.Metric{
+			ID:     name,
+			MType:  model.Counter,
+			Labels: a.labels,
+			Delta:  &val,
+		})
 	}
-	a.logger.Info("sent metric successfully", zap.String("url", u))
-
-	return nil
+	for name, h := range a.histograms {
+		metrics = append(metrics, model.Metric{
+			ID:        name,
+			MType:     model.Histogram,
+			Labels:    a.labels,
+			Histogram: h.Clone(),
+		})
+	}
+	return metrics
 }
 
-// SerndAll - отправляет все метрики на сервер
+// SendAll - отправляет все метрики на сервер вместе с собственными метриками агента
 // В случае возникновения ошибок при отправке - просто выводит их в лог
+// Гистограммы отправляются приращениями: сервер объединяет их с уже полученными,
+// поэтому отправленные наблюдения сбрасываются, а обратно возвращаются только
+// наблюдения гистограмм, которые не удалось доставить.
+// Не вызывается одновременно из нескольких горутин.
 func (a *Agent) SendAll(ctx context.Context) {
-	var wg sync.WaitGroup
-	wg.Add(len(a.gauges))
-	for name, val := range a.gauges {
-		go func() {
-			defer wg.Done()
-			m := model.Metric{
-				ID:    name,
-				MType: model.Gauge,
-				Value: &val,
+	ctx, span := tracer.Start(ctx, "agent.send")
+	defer span.End()
+	if a.retrying.Load() {
+		a.sendsAfterFailure.Add(1)
+	}
+
+	a.mu.Lock()
+	metrics := a.metrics()
+	sent := a.histograms
+	a.histograms = make(map[string]*model.HistogramData, len(sent))
+	a.mu.Unlock()
+	// собственные метрики идут после остальных, с индекса own
+	own := len(metrics)
+	exported := a.exporter.Collect()
+	for _, m := range exported {
+		m.Labels = a.labels.Merge(m.Labels)
+		metrics = append(metrics, m)
+	}
+
+	span.SetAttributes(attribute.Int("metrics", len(metrics)))
+	err := a.transport.SendAll(ctx, metrics)
+	if err != nil {
+		recordError(span, err)
+		a.logger.Error("failed to send metrics", zap.Error(err))
+		a.sendsFailed.Add(1)
+		a.retrying.Store(true)
+		failed := failedMetrics(err, len(metrics))
+		for i, m := range metrics[:own] {
+			if !failed[i] && m.MType == model.Histogram {
+				delete(sent, m.ID)
 			}
-			err := a.Send(ctx, &m)
-			if err != nil {
-				a.logger.Error("failed to send metric", zap.Error(err))
+		}
+		a.restoreHistograms(sent)
+		// доставленные приращения собственных метрик повторно не отправляются
+		for i, m := range exported {
+			if failed[own+i] {
+				a.exporter.Discard(m)
 			}
-		}()
+		}
+		a.exporter.Commit()
+		return
 	}
-	wg.Add(len(a.counters))
-	for name, val := range a.counters {
-		go func() {
-			defer wg.Done()
-			m := model.Metric{
-				ID:    name,
-				MType: model.Counter,
-				Delta: &val,
-			}
-			err := a.Send(ctx, &m)
-			if err != nil {
-				a.logger.Error("failed to send metric", zap.Error(err))
+	a.exporter.Commit()
+	a.sendsSucceeded.Add(1)
+	a.lastSuccess.Store(time.Now().UnixNano())
+	a.retrying.Store(false)
+}
+
+func (a *Agent) restoreHistograms(sent map[string]*model.HistogramData) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for name, h := range sent {
+		if current, ok := a.histograms[name]; ok {
+			if merged, err := current.Merge(h); err == nil {
+				h = merged
 			}
-		}()
+		}
+		a.histograms[name] = h
 	}
-	wg.Wait()
 }
 
+// RegisterMetadata - отправляет на сервер метаданные встроенных метрик, см. Metadata,
+// и собственных метрик агента
+func (a *Agent) RegisterMetadata(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "agent.register_metadata")
+	defer span.End()
+	md := maps.Clone(Metadata)
+	maps.Copy(md, a.exporter.Metadata())
+	if err := a.transport.RegisterMetadata(ctx, md); err != nil {
+		recordError(span, err)
+		a.logger.Error("failed to register metadata", zap.Error(err))
+		return
+	}
+	a.metadataRegistered.Store(true)
+}
+
+// Run - метаданные регистрируются при запуске, при ошибке - повторно перед каждой отправкой метрик
 func (a *Agent) Run(ctx context.Context) {
 	ctx, cancel := context.WithCancel(ctx)
 
@@ -208,6 +360,8 @@ func (a *Agent) Run(ctx context.Context) {
 		cancel()
 	}()
 
+	a.RegisterMetadata(ctx)
+
 	var wg sync.WaitGroup
 	wg.Add(2)
 
@@ -226,17 +380,40 @@ func (a *Agent) Run(ctx context.Context) {
 
 	go func() {
 		defer wg.Done()
-		ticker := time.NewTicker(time.Duration(float64(time.Second) * a.reportInterval))
+		interval := time.Duration(float64(time.Second) * a.reportInterval)
+		ticker := time.NewTicker(interval)
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				a.SendAll(ctx)
+				a.report(ctx, interval)
 			}
 		}
 	}()
 
-	a.logger.Info("Agent started", zap.String("baseURL", a.baseURL))
+	a.logger.Info("Agent started")
 	wg.Wait()
+
+	if err := a.transport.Close(); err != nil {
+		a.logger.Error("failed to close transport", zap.Error(err))
+	}
+}
+
+// report - цикл отправки: повторная регистрация метаданных, если она не удалась, и отправка метрик.
+// Цикл дольше interval отмечается в спане, дочерние спаны показывают, на что ушло время.
+func (a *Agent) report(ctx context.Context, interval time.Duration) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "agent.report")
+	defer span.End()
+	if !a.metadataRegistered.Load() {
+		a.RegisterMetadata(ctx)
+	}
+	a.SendAll(ctx)
+	span.SetAttributes(attribute.Bool("overrun", time.Since(start) > interval))
+}
+
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
 }
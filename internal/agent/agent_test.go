@@ -1,12 +1,29 @@
 package agent
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"maps"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
 	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/test/helper"
 )
 
 func TestCollect(t *testing.T) {
@@ -59,7 +76,239 @@ func TestCollect(t *testing.T) {
 	}
 }
 
+type stubTransport struct {
+	sent     []model.Metric
+	metadata map[string]model.Metadata
+	err      error
+	// failed - метрики, которые не доставлены при отправке остальных
+	failed func(m model.Metric) bool
+}
+
+func (t *stubTransport) SendAll(_ context.Context, metrics []model.Metric) error {
+	t.sent = metrics
+	if t.err != nil || t.failed == nil {
+		return t.err
+	}
+	var failed []int
+	for i, m := range metrics {
+		if t.failed(m) {
+			failed = append(failed, i)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &SendError{Failed: failed, Err: errors.New("bad request")}
+}
+
+func (t *stubTransport) RegisterMetadata(_ context.Context, metadata map[string]model.Metadata) error {
+	t.metadata = metadata
+	return t.err
+}
+
+func (t *stubTransport) Close() error {
+	return nil
+}
+
+func TestSendAllHistograms(t *testing.T) {
+	transport := &stubTransport{err: errors.New("unavailable")}
+	a := New(transport, 1, 1, nil, zap.L())
+	a.Collect()
+	runtime.GC()
+	a.Collect()
+	count := a.histograms[MetricGCPause].Count
+	assert.Positive(t, count)
+
+	// при ошибке отправки наблюдения сохраняются до следующей отправки
+	a.SendAll(context.Background())
+	assert.Equal(t, count, a.histograms[MetricGCPause].Count)
+
+	transport.err = nil
+	a.SendAll(context.Background())
+	i := slices.IndexFunc(transport.sent, func(m model.Metric) bool { return m.ID == MetricGCPause })
+	assert.Equal(t, count, transport.sent[i].Histogram.Count)
+	assert.Empty(t, a.histograms)
+}
+
+func TestSendAllPartialFailure(t *testing.T) {
+	transport := &stubTransport{failed: func(m model.Metric) bool { return m.ID == "other" }}
+	a := New(transport, 1, 1, nil, zap.L())
+	a.Collect()
+	runtime.GC()
+	a.Collect()
+	other, err := model.NewHistogram([]float64{1})
+	require.NoError(t, err)
+	other.Observe(0.5)
+	a.histograms["other"] = other
+
+	// доставленные наблюдения не возвращаются, иначе сервер учел бы их дважды
+	a.SendAll(context.Background())
+	assert.Equal(t, int64(1), a.sendsFailed.Load())
+	assert.NotContains(t, a.histograms, MetricGCPause)
+	require.Contains(t, a.histograms, "other")
+	assert.Equal(t, uint64(1), a.histograms["other"].Count)
+
+	// доставленные приращения собственных метрик тоже не отправляются повторно
+	transport.failed = nil
+	a.SendAll(context.Background())
+	sent := make(map[string]model.Metric)
+	for _, m := range transport.sent {
+		sent[m.ID] = m
+	}
+	assert.Zero(t, sent["agent_collect_duration_seconds"].Histogram.Count)
+	assert.Equal(t, int64(1), *sent["agent_sends_failed_total"].Delta)
+}
+
+func TestHTTPTransportSendAll(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "/bad/") {
+			res.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	transport := NewHTTPTransport(srv.URL, 2, zap.L())
+	defer transport.Close() //nolint:errcheck // тестовый транспорт
+
+	metrics := []model.Metric{
+		{ID: "ok", MType: model.Gauge, Value: helper.NewFloat64(t, 1)},
+		{ID: "bad", MType: model.Gauge, Value: helper.NewFloat64(t, 1)},
+		{ID: "ok", MType: model.Counter, Delta: helper.NewInt64(t, 1)},
+	}
+	var sendErr *SendError
+	require.ErrorAs(t, transport.SendAll(context.Background(), metrics), &sendErr)
+	assert.Equal(t, []int{1}, sendErr.Failed)
+	require.NoError(t, transport.SendAll(context.Background(), metrics[:1]))
+}
+
+func TestRegisterMetadata(t *testing.T) {
+	transport := &stubTransport{err: errors.New("unavailable")}
+	a := New(transport, 1, 1, nil, zap.L())
+	a.RegisterMetadata(context.Background())
+	assert.False(t, a.metadataRegistered.Load())
+
+	transport.err = nil
+	a.RegisterMetadata(context.Background())
+	assert.True(t, a.metadataRegistered.Load())
+
+	// метаданные есть у всех встроенных метрик
+	a.Collect()
+	for _, m := range a.Metrics() {
+		if assert.Contains(t, transport.metadata, m.ID) {
+			assert.Equal(t, m.MType, transport.metadata[m.ID].Type, m.ID)
+		}
+	}
+}
+
 func testAgent(t *testing.T) *Agent {
 	t.Helper()
-	return New("", 1, 1, 100, zap.L())
+	return New(NewHTTPTransport("", 100, zap.L()), 1, 1, nil, zap.L())
+}
+
+func TestSendsAfterFailure(t *testing.T) {
+	transport := &stubTransport{err: errors.New("unavailable")}
+	a := New(transport, 1, 1, nil, zap.L())
+	a.Collect()
+	// метаданные не зарегистрированы в обоих циклах, но считается только отправка после неудачной
+	a.report(context.Background(), time.Second)
+	a.report(context.Background(), time.Second)
+	assert.Equal(t, int64(1), a.sendsAfterFailure.Load())
+}
+
+func TestTelemetry(t *testing.T) {
+	transport := &stubTransport{err: errors.New("unavailable")}
+	a := New(transport, 1, 1, model.NewLabels(LabelHost, "h"), zap.L())
+	a.Collect()
+	a.SendAll(context.Background())
+	transport.err = nil
+	a.SendAll(context.Background())
+
+	sent := make(map[string]model.Metric)
+	for _, m := range transport.sent {
+		sent[m.ID] = m
+	}
+	// значения счетчиков - приращения с предыдущей успешной отправки
+	assert.Equal(t, int64(1), *sent["agent_sends_failed_total"].Delta)
+	assert.Equal(t, int64(1), *sent["agent_sends_after_failure_total"].Delta)
+	assert.Equal(t, int64(0), *sent["agent_sends_succeeded_total"].Delta)
+	assert.Equal(t, uint64(1), sent["agent_collect_duration_seconds"].Histogram.Count)
+	assert.Equal(t, model.NewLabels(LabelHost, "h"), sent["agent_queue_depth"].Labels)
+
+	a.SendAll(context.Background())
+	sent = make(map[string]model.Metric)
+	for _, m := range transport.sent {
+		sent[m.ID] = m
+	}
+	assert.Equal(t, int64(0), *sent["agent_sends_failed_total"].Delta)
+	assert.Equal(t, int64(1), *sent["agent_sends_succeeded_total"].Delta)
+	assert.Positive(t, *sent["agent_last_success_timestamp_seconds"].Value)
+
+	rec := httptest.NewRecorder()
+	a.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", http.NoBody))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var status Status
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, int64(2), status.SendsSucceeded)
+	assert.Equal(t, int64(1), status.SendsFailed)
+	assert.Equal(t, int64(1), status.SendsAfterFailure)
+	assert.Zero(t, status.QueueDepth)
+	assert.WithinDuration(t, time.Now(), status.LastSuccess, time.Minute)
+	assert.False(t, status.MetadataRegistered)
+}
+
+// BenchmarkSendAll - отправка собранных метрик через HTTPTransport на тестовый сервер
+func BenchmarkSendAll(b *testing.B) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	transport := NewHTTPTransport(srv.URL, 10, zap.NewNop())
+	defer transport.Close() //nolint:errcheck // тестовый транспорт
+	a := New(transport, 1, 1, model.NewLabels(LabelHost, "h"), zap.NewNop())
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		b.StopTimer()
+		a.Collect()
+		b.StartTimer()
+		a.SendAll(ctx)
+	}
+	b.StopTimer()
+	if a.sendsFailed.Load() > 0 {
+		b.Fatal("failed to send metrics")
+	}
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var mu sync.Mutex
+	var traceparents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		traceparents = append(traceparents, req.Header.Get("traceparent"))
+	}))
+	defer srv.Close()
+	a := New(NewHTTPTransport(srv.URL, 10, zap.L()), 1, 1, nil, zap.L())
+	a.Collect()
+	a.SendAll(context.Background())
+
+	var send sdktrace.ReadOnlySpan
+	requests := 0
+	for _, s := range recorder.Ended() {
+		switch s.Name() {
+		case "agent.send":
+			send = s
+		case http.MethodPost:
+			requests++
+		}
+	}
+	require.NotNil(t, send)
+	require.NotEmpty(t, traceparents)
+	assert.Len(t, traceparents, requests)
+	// запросы - дочерние спаны отправки, их контекст передается серверу
+	for _, tp := range traceparents {
+		assert.Contains(t, tp, send.SpanContext().TraceID().String())
+	}
 }
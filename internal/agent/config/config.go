@@ -2,6 +2,13 @@ package config
 
 import (
 	"flag"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/tracing"
+)
+
+const (
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
 )
 
 type Config struct {
@@ -9,6 +16,13 @@ type Config struct {
 	ReportInterval     float64
 	PollInterval       float64
 	ConcurrentRequests int
+	Transport          string
+	Key                string
+	Instance           string
+	StatusAddress      string
+	PprofAddress       string
+	TraceExporter      string
+	TraceEndpoint      string
 }
 
 var (
@@ -25,7 +39,7 @@ func NewFromFlags() *Config {
 	// сейчас те что не задаются через флаги - просто хардкодятся
 	c.ConcurrentRequests = DefaultConcurrentRequests
 
-	flag.StringVar(&c.Address, "a", "localhost:8080", "хост:порт http сервера")
+	flag.StringVar(&c.Address, "a", "localhost:8080", "хост:порт сервера (http или gRPC, в зависимости от -transport)")
 	flag.Float64Var(
 		&c.ReportInterval,
 		"r",
@@ -33,6 +47,38 @@ func NewFromFlags() *Config {
 		"частота отправки метрик на сервер",
 	)
 	flag.Float64Var(&c.PollInterval, "p", DefaultPollInterval, "частота опроса метрик")
+	flag.StringVar(&c.Transport, "transport", TransportHTTP, "способ отправки метрик: http или grpc")
+	flag.StringVar(&c.Key, "k", "", "ключ подписи запросов gRPC")
+	flag.StringVar(
+		&c.Instance,
+		"instance",
+		"",
+		"значение метки instance для всех метрик агента, по умолчанию - имя хоста",
+	)
+	flag.StringVar(
+		&c.StatusAddress,
+		"status-address",
+		"",
+		"хост:порт локального http сервера с состоянием агента GET /status, если не задан - сервер не запускается",
+	)
+	flag.StringVar(
+		&c.PprofAddress,
+		"pprof-address",
+		"",
+		"хост:порт служебного http сервера с профилированием /debug/pprof/, если не задан - сервер не запускается",
+	)
+	flag.StringVar(
+		&c.TraceExporter,
+		"trace-exporter",
+		tracing.ExporterNone,
+		"экспортер спанов трассировки: none, stdout, file, otlp-http, otlp-grpc",
+	)
+	flag.StringVar(
+		&c.TraceEndpoint,
+		"trace-endpoint",
+		"",
+		"путь к файлу для экспортера file, URL коллектора для otlp-http и otlp-grpc",
+	)
 	flag.Parse()
 
 	return &c
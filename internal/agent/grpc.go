@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/pb"
+	"github.com/mikeziminio/go-custom-metrics/internal/sign"
+)
+
+// GRPCTransport - отправка всех метрик одним клиентским потоком UpdateBatch
+type GRPCTransport struct {
+	conn   *grpc.ClientConn
+	client pb.MetricsClient
+	signer *sign.Signer
+	logger *zap.Logger
+}
+
+var _ Transport = (*GRPCTransport)(nil)
+
+// NewGRPCTransport - если key не пустой, поток подписывается
+func NewGRPCTransport(address string, key string, logger *zap.Logger) (*GRPCTransport, error) {
+	conn, err := grpc.NewClient(address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		// спаны вызовов и передача контекста трассировки серверу
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init grpc client: %w", err)
+	}
+	t := &GRPCTransport{
+		conn:   conn,
+		client: pb.NewMetricsClient(conn),
+		logger: logger,
+	}
+	if key != "" {
+		t.signer = sign.New(key)
+	}
+	return t, nil
+}
+
+// SendAll - метрики отправляются одним потоком. Если сервер отклонил часть сообщений,
+// остальные применены, и возвращается *SendError с индексами отклоненных,
+// любая другая ошибка считается недоставкой всех метрик
+func (t *GRPCTransport) SendAll(ctx context.Context, metrics []model.Metric) error {
+	reqs := make([]*pb.UpdateRequest, 0, len(metrics))
+	for i := range metrics {
+		reqs = append(reqs, &pb.UpdateRequest{Metric: pb.FromMetric(&metrics[i])})
+	}
+
+	if t.signer != nil {
+		msgs := make([][]byte, 0, len(reqs))
+		for _, req := range reqs {
+			b, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+			if err != nil {
+				return fmt.Errorf("failed to marshal request: %w", err)
+			}
+			msgs = append(msgs, b)
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, pb.SignMetadataKey, t.signer.Sign(msgs...))
+	}
+
+	stream, err := t.client.UpdateBatch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open update stream: %w", err)
+	}
+	for _, req := range reqs {
+		if err := stream.Send(req); err != nil {
+			return fmt.Errorf("failed to send metric %s: %w", req.GetMetric().GetId(), err)
+		}
+	}
+	res, err := stream.CloseAndRecv()
+	if failed, ok := pb.BatchFailed(err); ok {
+		return &SendError{Failed: failed, Err: err}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update metrics: %w", err)
+	}
+	t.logger.Info("sent metrics successfully", zap.Int64("updated", res.GetUpdated()))
+
+	return nil
+}
+
+// RegisterMetadata - отправляет метаданные каждой метрики отдельным вызовом UpdateMetadata
+func (t *GRPCTransport) RegisterMetadata(ctx context.Context, metricMetadata map[string]model.Metadata) error {
+	for name, md := range metricMetadata {
+		req := &pb.UpdateMetadataRequest{Name: name, Metadata: pb.FromMetadata(&md)}
+		reqCtx := ctx
+		if t.signer != nil {
+			b, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+			if err != nil {
+				return fmt.Errorf("failed to marshal request: %w", err)
+			}
+			reqCtx = metadata.AppendToOutgoingContext(ctx, pb.SignMetadataKey, t.signer.Sign(b))
+		}
+		if _, err := t.client.UpdateMetadata(reqCtx, req); err != nil {
+			return fmt.Errorf("failed to update metadata %s: %w", name, err)
+		}
+	}
+	t.logger.Info("registered metadata successfully", zap.Int("metrics", len(metricMetadata)))
+	return nil
+}
+
+func (t *GRPCTransport) Close() error {
+	return t.conn.Close()
+}
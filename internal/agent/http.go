@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+// HTTPTransport - отправка метрик по одной через URL API сервера,
+// гистограммы и summary отправляются через JSON API
+type HTTPTransport struct {
+	client  *http.Client
+	baseURL string
+	sem     *semaphore.Weighted
+	logger  *zap.Logger
+}
+
+var _ Transport = (*HTTPTransport)(nil)
+
+func NewHTTPTransport(baseURL string, concurrentRequests int, logger *zap.Logger) *HTTPTransport {
+	// соединения всех одновременных запросов остаются открытыми до следующей отправки,
+	// по умолчанию сохраняется только 2 соединения на хост
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = concurrentRequests
+	return &HTTPTransport{
+		client:  &http.Client{Transport: transport},
+		baseURL: baseURL,
+		sem:     semaphore.NewWeighted(int64(concurrentRequests)),
+		logger:  logger,
+	}
+}
+
+func (t *HTTPTransport) Send(ctx context.Context, m *model.Metric) error {
+	t.logger.Debug("send metric start", zap.String("type", string(m.MType)), zap.String("id", m.ID))
+	if m.MType != model.Counter && m.MType != model.Gauge {
+		return t.sendJSON(ctx, m)
+	}
+	var value string
+	if m.MType == model.Counter {
+		value = strconv.FormatInt(*m.Delta, 10)
+	} else {
+		value = strconv.FormatFloat(*m.Value, 'f', 5, 64)
+	}
+	u, err := url.JoinPath(t.baseURL, "/update", string(m.MType), m.ID, value)
+	if err != nil {
+		return fmt.Errorf("failed to join url path for sending metric %s, %v", t.baseURL, m)
+	}
+	if len(m.Labels) > 0 {
+		q := make(url.Values, len(m.Labels))
+		for name, value := range m.Labels {
+			q.Set(model.LabelParamPrefix+name, value)
+		}
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("failed to init request: %w", err)
+	}
+	return t.do(req)
+}
+
+// sendJSON - отправляет метрику в теле запроса на /update/
+func (t *HTTPTransport) sendJSON(ctx context.Context, m *model.Metric) error {
+	u, err := url.JoinPath(t.baseURL, "/update/")
+	if err != nil {
+		return fmt.Errorf("failed to join url path for sending metric %s, %v", t.baseURL, m)
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metric %s: %w", m.ID, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to init request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return t.do(req)
+}
+
+// do - выполняет запрос в спане, который включает ожидание свободного места среди
+// concurrentRequests, контекст спана передается серверу в заголовке traceparent
+func (t *HTTPTransport) do(req *http.Request) (err error) {
+	ctx, span := tracer.Start(req.Context(), req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPRequestMethodKey.String(req.Method), semconv.URLFull(req.URL.String())),
+	)
+	defer func() {
+		if err != nil {
+			recordError(span, err)
+		}
+		span.End()
+	}()
+	req = req.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	_ = t.sem.Acquire(ctx, 1)
+	defer t.sem.Release(1)
+	span.AddEvent("acquired")
+	res, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to do request: %w", err)
+	}
+	defer res.Body.Close() //nolint:errcheck // it's ok
+	span.SetAttributes(semconv.HTTPResponseStatusCode(res.StatusCode))
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code for request %s: %d", req.URL, res.StatusCode)
+	}
+	t.logger.Debug("sent metric successfully", zap.Stringer("url", req.URL))
+
+	return nil
+}
+
+// SendAll - отправляет метрики параллельно, не более concurrentRequests запросов одновременно.
+// Каждая метрика отправляется отдельным запросом, поэтому при ошибках возвращается *SendError
+// с индексами недоставленных метрик.
+func (t *HTTPTransport) SendAll(ctx context.Context, metrics []model.Metric) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(metrics))
+	wg.Add(len(metrics))
+	for i := range metrics {
+		go func() {
+			defer wg.Done()
+			errs[i] = t.Send(ctx, &metrics[i])
+		}()
+	}
+	wg.Wait()
+	var failed []int
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, i)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &SendError{Failed: failed, Err: errors.Join(errs...)}
+}
+
+// RegisterMetadata - отправляет метаданные каждой метрики отдельным запросом
+// на /api/v1/metadata/{name}, не более concurrentRequests запросов одновременно
+func (t *HTTPTransport) RegisterMetadata(ctx context.Context, metadata map[string]model.Metadata) error {
+	var wg sync.WaitGroup
+	errs := make([]error, 0, len(metadata))
+	var mu sync.Mutex
+	wg.Add(len(metadata))
+	for name, md := range metadata {
+		go func() {
+			defer wg.Done()
+			if err := t.sendMetadata(ctx, name, md); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (t *HTTPTransport) sendMetadata(ctx context.Context, name string, md model.Metadata) error {
+	u, err := url.JoinPath(t.baseURL, "/api/v1/metadata", name)
+	if err != nil {
+		return fmt.Errorf("failed to join url path for sending metadata %s, %s", t.baseURL, name)
+	}
+	body, err := json.Marshal(md)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata %s: %w", name, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to init request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return t.do(req)
+}
+
+func (t *HTTPTransport) Close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
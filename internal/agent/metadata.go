@@ -0,0 +1,136 @@
+package agent
+
+import "github.com/mikeziminio/go-custom-metrics/internal/model"
+
+// Единицы измерения встроенных метрик
+const (
+	unitBytes       = "bytes"
+	unitNanoseconds = "nanoseconds"
+	unitSeconds     = "seconds"
+	unitRatio       = "ratio"
+)
+
+// Metadata - метаданные встроенных метрик агента, регистрируются на сервере при запуске.
+// Описания метрик runtime.MemStats соответствуют документации пакета runtime.
+var Metadata = map[string]model.Metadata{
+	MetricAlloc: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes of allocated heap objects.",
+	},
+	MetricBuckHashSys: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes of memory in profiling bucket hash tables.",
+	},
+	MetricFrees: {
+		Type: model.Gauge,
+		Help: "Cumulative count of heap objects freed.",
+	},
+	MetricGCCPUFraction: {
+		Type: model.Gauge, Unit: unitRatio,
+		Help: "Fraction of this program's available CPU time used by the GC since the program started.",
+	},
+	MetricGCSys: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes of memory in garbage collection metadata.",
+	},
+	MetricHeapAlloc: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes of allocated heap objects.",
+	},
+	MetricHeapIdle: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes in idle (unused) heap spans.",
+	},
+	MetricHeapInuse: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes in in-use heap spans.",
+	},
+	MetricHeapObjects: {
+		Type: model.Gauge,
+		Help: "Number of allocated heap objects.",
+	},
+	MetricHeapReleased: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes of physical memory returned to the OS.",
+	},
+	MetricHeapSys: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes of heap memory obtained from the OS.",
+	},
+	MetricLastGC: {
+		Type: model.Gauge, Unit: unitNanoseconds,
+		Help: "Time the last garbage collection finished, as nanoseconds since the Unix epoch.",
+	},
+	MetricLookups: {
+		Type: model.Gauge,
+		Help: "Number of pointer lookups performed by the runtime.",
+	},
+	MetricMCacheInuse: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes of allocated mcache structures.",
+	},
+	MetricMCacheSys: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes of memory obtained from the OS for mcache structures.",
+	},
+	MetricMSpanInuse: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes of allocated mspan structures.",
+	},
+	MetricMSpanSys: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes of memory obtained from the OS for mspan structures.",
+	},
+	MetricMallocs: {
+		Type: model.Gauge,
+		Help: "Cumulative count of heap objects allocated.",
+	},
+	MetricNextGC: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Target heap size of the next GC cycle.",
+	},
+	MetricNumForcedGC: {
+		Type: model.Gauge,
+		Help: "Number of GC cycles that were forced by the application calling the GC function.",
+	},
+	MetricNumGC: {
+		Type: model.Gauge,
+		Help: "Number of completed GC cycles.",
+	},
+	MetricOtherSys: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes of memory in miscellaneous off-heap runtime allocations.",
+	},
+	MetricPauseTotalNs: {
+		Type: model.Gauge, Unit: unitNanoseconds,
+		Help: "Cumulative time spent in GC stop-the-world pauses since the program started.",
+	},
+	MetricStackInuse: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes in stack spans.",
+	},
+	MetricStackSys: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Bytes of stack memory obtained from the OS.",
+	},
+	MetricSys: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Total bytes of memory obtained from the OS.",
+	},
+	MetricTotalAlloc: {
+		Type: model.Gauge, Unit: unitBytes,
+		Help: "Cumulative bytes allocated for heap objects.",
+	},
+	MetricPollCount: {
+		Type: model.Counter,
+		Help: "Number of times the agent collected runtime metrics.",
+	},
+	MetricRandomValue: {
+		Type: model.Gauge,
+		Help: "Random value in [0, 1), updated on every collection.",
+	},
+	MetricGCPause: {
+		Type: model.Histogram, Unit: unitSeconds,
+		Help: "Durations of GC stop-the-world pauses.",
+	},
+}
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+// TelemetryPrefix - префикс имен собственных метрик агента, отправляемых на сервер
+const TelemetryPrefix = "agent_"
+
+// Собственные метрики агента, на сервере - с префиксом TelemetryPrefix
+const (
+	metricSendsSucceeded  = "sends_succeeded_total"
+	metricSendsFailed     = "sends_failed_total"
+	metricSendsAfterFail  = "sends_after_failure_total"
+	metricQueueDepth      = "queue_depth"
+	metricLastSuccess     = "last_success_timestamp_seconds"
+	metricCollectDuration = "collect_duration_seconds"
+)
+
+// statusReadHeaderTimeout - ограничение времени чтения заголовков запроса к /status
+const statusReadHeaderTimeout = time.Second
+
+// Status - состояние агента: по времени последней успешной отправки
+// неработающий агент отличается от простаивающего
+type Status struct {
+	SendsSucceeded int64 `json:"sends_succeeded"`
+	SendsFailed    int64 `json:"sends_failed"`
+	// SendsAfterFailure - отправки метрик, выполненные после неудачной отправки.
+	// Отдельных повторов запроса у агента нет: не доставленное уходит в следующем цикле отправки.
+	SendsAfterFailure int64 `json:"sends_after_failure"`
+	// QueueDepth - собранные, но еще не доставленные на сервер наблюдения гистограмм
+	QueueDepth uint64 `json:"queue_depth"`
+	// LastSuccess - время последней успешной отправки метрик, не задано - отправок не было
+	LastSuccess         time.Time `json:"last_success,omitzero"`
+	LastCollectDuration float64   `json:"last_collect_duration_seconds"`
+	MetadataRegistered  bool      `json:"metadata_registered"`
+}
+
+// registerTelemetry - метаданные и вычисляемые метрики агента
+func (a *Agent) registerTelemetry() {
+	t := a.telemetry
+	t.Describe(metricSendsSucceeded, model.Metadata{Type: model.Counter, Help: "Successful metric reports."})
+	t.Describe(metricSendsFailed, model.Metadata{Type: model.Counter, Help: "Failed metric reports."})
+	t.Describe(metricSendsAfterFail, model.Metadata{
+		Type: model.Counter,
+		Help: "Metric reports made on the next cycle after a failed report.",
+	})
+	t.Describe(metricQueueDepth, model.Metadata{
+		Type: model.Gauge,
+		Help: "Histogram observations collected but not yet delivered to the server.",
+	})
+	t.Describe(metricLastSuccess, model.Metadata{
+		Type: model.Gauge,
+		Help: "Unix time of the last successful metric report, 0 if there was none.",
+		Unit: "seconds",
+	})
+	t.Describe(metricCollectDuration, model.Metadata{
+		Type: model.Histogram,
+		Help: "Duration of runtime metrics collection.",
+		Unit: "seconds",
+	})
+	t.CounterFunc(metricSendsSucceeded, nil, a.sendsSucceeded.Load)
+	t.CounterFunc(metricSendsFailed, nil, a.sendsFailed.Load)
+	t.CounterFunc(metricSendsAfterFail, nil, a.sendsAfterFailure.Load)
+	t.GaugeFunc(metricQueueDepth, nil, func() float64 {
+		return float64(a.queueDepth())
+	})
+	t.GaugeFunc(metricLastSuccess, nil, func() float64 {
+		if ns := a.lastSuccess.Load(); ns != 0 {
+			return float64(ns) / float64(time.Second)
+		}
+		return 0
+	})
+}
+
+// queueDepth - количество наблюдений гистограмм, ожидающих отправки
+func (a *Agent) queueDepth() uint64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	var n uint64
+	for _, h := range a.histograms {
+		n += h.Count
+	}
+	return n
+}
+
+// Status - текущее состояние агента
+func (a *Agent) Status() Status {
+	s := Status{
+		SendsSucceeded:      a.sendsSucceeded.Load(),
+		SendsFailed:         a.sendsFailed.Load(),
+		SendsAfterFailure:   a.sendsAfterFailure.Load(),
+		QueueDepth:          a.queueDepth(),
+		LastCollectDuration: time.Duration(a.lastCollectDuration.Load()).Seconds(),
+		MetadataRegistered:  a.metadataRegistered.Load(),
+	}
+	if ns := a.lastSuccess.Load(); ns != 0 {
+		s.LastSuccess = time.Unix(0, ns).UTC()
+	}
+	return s
+}
+
+// StatusHandler - GET /status, состояние агента в формате JSON
+func (a *Agent) StatusHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(res http.ResponseWriter, _ *http.Request) {
+		res.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(res).Encode(a.Status()); err != nil {
+			a.logger.Error("failed to write status", zap.Error(err))
+		}
+	})
+	return mux
+}
+
+// ServeStatus - HTTP сервер с StatusHandler на address до завершения ctx
+func (a *Agent) ServeStatus(ctx context.Context, address string) {
+	srv := &http.Server{
+		Addr:              address,
+		Handler:           a.StatusHandler(),
+		ReadHeaderTimeout: statusReadHeaderTimeout,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+	}()
+	a.logger.Info("Status server started", zap.String("address", address))
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		a.logger.Error("failed to serve status", zap.Error(err))
+	}
+}
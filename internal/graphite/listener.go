@@ -0,0 +1,170 @@
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"math"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/server"
+)
+
+// ReadTimeout - соединение закрывается, если за это время от клиента не пришла очередная строка,
+// чтобы простаивающие и медленные клиенты не удерживали соединения
+const ReadTimeout = time.Minute
+
+// Listener - TCP сервер, принимающий метрики по plaintext протоколу Graphite
+type Listener struct {
+	address         string
+	templates       *Templates
+	counterSuffixes []string
+	storage         server.Storage
+	logger          *zap.Logger
+	readTimeout     time.Duration
+	conns           map[net.Conn]struct{}
+	// closed - прием остановлен, соединения, принятые после остановки, сразу закрываются
+	closed bool
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+}
+
+func New(
+	address string,
+	templates *Templates,
+	counterSuffixes []string,
+	storage server.Storage,
+	logger *zap.Logger,
+) *Listener {
+	return &Listener{
+		address:         address,
+		templates:       templates,
+		counterSuffixes: counterSuffixes,
+		storage:         storage,
+		logger:          logger,
+		readTimeout:     ReadTimeout,
+		conns:           make(map[net.Conn]struct{}),
+	}
+}
+
+// Run - принимает соединения до отмены контекста
+func (l *Listener) Run(ctx context.Context) {
+	ln, err := (&net.ListenConfig{}).Listen(ctx, "tcp", l.address)
+	if err != nil {
+		l.logger.Fatal("failed to start graphite listener", zap.Error(err))
+	}
+	l.logger.Info("Graphite listener started", zap.String("address", ln.Addr().String()))
+
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+		l.mu.Lock()
+		l.closed = true
+		for conn := range l.conns {
+			_ = conn.Close()
+		}
+		l.mu.Unlock()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				break
+			}
+			l.logger.Error("failed to accept graphite connection", zap.Error(err))
+			continue
+		}
+		// соединение регистрируется под той же блокировкой, что и закрытие всех соединений
+		// при остановке, иначе принятое в этот момент соединение не закроется
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			_ = conn.Close()
+			break
+		}
+		l.conns[conn] = struct{}{}
+		l.wg.Add(1)
+		l.mu.Unlock()
+
+		go func() {
+			defer l.wg.Done()
+			l.serve(conn)
+		}()
+	}
+
+	l.wg.Wait()
+	l.logger.Info("Graphite listener stopped")
+}
+
+func (l *Listener) serve(conn net.Conn) {
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for {
+		// ограничение продлевается на каждую строку, как ReadTimeout http сервера на каждый запрос
+		_ = conn.SetReadDeadline(time.Now().Add(l.readTimeout))
+		if !scanner.Scan() {
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		p, err := ParseLine(line, time.Now())
+		if err != nil {
+			l.logger.Warn("skip graphite line", zap.Error(err))
+			continue
+		}
+		m := l.Metric(p)
+		if err := l.storage.Update(m); err != nil {
+			l.logger.Error("failed to update metric", zap.String("id", m.ID), zap.Error(err))
+		}
+	}
+	err := scanner.Err()
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		l.logger.Info("graphite connection closed by read timeout", zap.String("remote", conn.RemoteAddr().String()))
+	case err != nil && !errors.Is(err, net.ErrClosed):
+		l.logger.Error("failed to read graphite connection", zap.Error(err))
+	}
+}
+
+// Metric - преобразует точку Graphite в метрику. По умолчанию метрика
+// сохраняется как gauge, если имя оканчивается на один из суффиксов
+// счетчиков - как counter с приращением, равным значению точки.
+func (l *Listener) Metric(p Point) model.Metric {
+	name, labels := l.templates.Metric(p.Path)
+	for _, suffix := range l.counterSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			delta := int64(math.Round(p.Value))
+			return model.Metric{
+				ID:        name,
+				MType:     model.Counter,
+				Labels:    labels,
+				Delta:     &delta,
+				Timestamp: p.Timestamp,
+			}
+		}
+	}
+	value := p.Value
+	return model.Metric{
+		ID:        name,
+		MType:     model.Gauge,
+		Labels:    labels,
+		Value:     &value,
+		Timestamp: p.Timestamp,
+	}
+}
@@ -0,0 +1,66 @@
+package graphite
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/memstorage"
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/test/helper"
+)
+
+func TestMetric(t *testing.T) {
+	templates, err := NewTemplates([]string{"servers.* .host.measurement*"}, DefaultSeparator)
+	require.NoError(t, err)
+	ms := memstorage.New(0)
+	l := New("", templates, []string{".count"}, ms, zap.L())
+
+	assert.Equal(t, model.Metric{
+		ID:     "cpu.load",
+		MType:  model.Gauge,
+		Labels: model.NewLabels("host", "host42"),
+		Value:  helper.NewFloat64(t, 0.5),
+	}, l.Metric(Point{Path: "servers.host42.cpu.load", Value: 0.5}))
+
+	assert.Equal(t, model.Metric{
+		ID:     "requests.count",
+		MType:  model.Counter,
+		Labels: model.NewLabels("host", "host42"),
+		Delta:  helper.NewInt64(t, 12),
+	}, l.Metric(Point{Path: "servers.host42.requests.count", Value: 12}))
+}
+
+func TestServeReadTimeout(t *testing.T) {
+	templates, err := NewTemplates(nil, DefaultSeparator)
+	require.NoError(t, err)
+	ms := memstorage.New(0)
+	l := New("", templates, nil, ms, zap.L())
+	l.readTimeout = 100 * time.Millisecond
+
+	client, conn := net.Pipe()
+	defer client.Close() //nolint:errcheck // тестовое соединение
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.serve(conn)
+	}()
+
+	// ограничение продлевается после каждой строки
+	for _, line := range []string{"a 1\n", "b 2\n"} {
+		time.Sleep(60 * time.Millisecond)
+		_, err := client.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("idle connection is not closed")
+	}
+	_, err = ms.Get(model.Gauge, "b", nil)
+	require.NoError(t, err)
+}
@@ -0,0 +1,47 @@
+package graphite
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidLine = errors.New("invalid graphite line")
+
+// Point - одна точка plaintext протокола Graphite вида <path> <value> <timestamp>
+type Point struct {
+	Path      string
+	Value     float64
+	Timestamp time.Time
+}
+
+// ParseLine - разбирает строку протокола. Метка времени может отсутствовать
+// или быть равной -1, в этом случае используется now.
+func ParseLine(line string, now time.Time) (Point, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return Point{}, fmt.Errorf("%w: %q", ErrInvalidLine, line)
+	}
+
+	v, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("%w: incorrect value %q", ErrInvalidLine, fields[1])
+	}
+
+	p := Point{
+		Path:      fields[0],
+		Value:     v,
+		Timestamp: now,
+	}
+	if len(fields) == 3 && fields[2] != "-1" {
+		ts, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return Point{}, fmt.Errorf("%w: incorrect timestamp %q", ErrInvalidLine, fields[2])
+		}
+		p.Timestamp = time.Unix(0, int64(ts*float64(time.Second)))
+	}
+
+	return p, nil
+}
@@ -0,0 +1,30 @@
+package graphite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	p, err := ParseLine("servers.host42.cpu 0.75 1600000000", now)
+	require.NoError(t, err)
+	assert.Equal(t, Point{Path: "servers.host42.cpu", Value: 0.75, Timestamp: time.Unix(1600000000, 0)}, p)
+
+	p, err = ParseLine("servers.host42.cpu 0.75 -1", now)
+	require.NoError(t, err)
+	assert.Equal(t, now, p.Timestamp)
+
+	p, err = ParseLine("servers.host42.cpu 0.75", now)
+	require.NoError(t, err)
+	assert.Equal(t, now, p.Timestamp)
+
+	for _, line := range []string{"servers.host42.cpu", "servers.host42.cpu abc 1600000000", "a 1 2 3"} {
+		_, err = ParseLine(line, now)
+		require.ErrorIs(t, err, ErrInvalidLine, line)
+	}
+}
@@ -0,0 +1,138 @@
+package graphite
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+const (
+	partMeasurement     = "measurement"
+	partMeasurementRest = "measurement*"
+	partField           = "field"
+)
+
+// DefaultSeparator - разделитель частей имени метрики, собранного по шаблону
+const DefaultSeparator = "."
+
+var ErrInvalidTemplate = errors.New("invalid graphite template")
+
+// Template - шаблон преобразования пути Graphite в имя метрики, по аналогии
+// с шаблонами InfluxDB/Telegraf: "[фильтр] шаблон".
+//
+// Фильтр - путь с подстановкой *, например servers.*.cpu.
+// Шаблон - части пути через точку:
+//   - measurement - часть входит в имя метрики;
+//   - measurement* - все оставшиеся части входят в имя метрики;
+//   - field - часть добавляется в конец имени метрики;
+//   - пустая часть - пропускается;
+//   - любое другое слово - имя метки, значением которой станет часть пути.
+type Template struct {
+	filter []string
+	parts  []string
+}
+
+func ParseTemplate(s string) (*Template, error) {
+	fields := strings.Fields(s)
+	t := &Template{}
+	switch len(fields) {
+	case 1:
+		t.parts = strings.Split(fields[0], ".")
+	case 2:
+		t.filter = strings.Split(fields[0], ".")
+		t.parts = strings.Split(fields[1], ".")
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTemplate, s)
+	}
+
+	hasMeasurement := false
+	for i, p := range t.parts {
+		switch p {
+		case partMeasurement:
+			hasMeasurement = true
+		case partMeasurementRest:
+			hasMeasurement = true
+			if i != len(t.parts)-1 {
+				return nil, fmt.Errorf("%w: %s must be the last part: %q", ErrInvalidTemplate, partMeasurementRest, s)
+			}
+		}
+	}
+	if !hasMeasurement {
+		return nil, fmt.Errorf("%w: no measurement part: %q", ErrInvalidTemplate, s)
+	}
+
+	return t, nil
+}
+
+// Match - подходит ли путь под фильтр шаблона. Шаблон без фильтра подходит под любой путь.
+func (t *Template) Match(path []string) bool {
+	if len(t.filter) > len(path) {
+		return false
+	}
+	for i, f := range t.filter {
+		if f != "*" && f != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply - собирает имя и метки метрики из пути
+func (t *Template) Apply(path []string, separator string) (string, model.Labels) {
+	var measurement, fields []string
+	var labels model.Labels
+	for i, p := range t.parts {
+		if i >= len(path) {
+			break
+		}
+		switch p {
+		case partMeasurement:
+			measurement = append(measurement, path[i])
+		case partMeasurementRest:
+			measurement = append(measurement, path[i:]...)
+		case partField:
+			fields = append(fields, path[i])
+		case "":
+		default:
+			if labels == nil {
+				labels = make(model.Labels)
+			}
+			labels[p] = path[i]
+		}
+	}
+	return strings.Join(append(measurement, fields...), separator), labels
+}
+
+// Templates - набор шаблонов, применяется первый подходящий по фильтру
+type Templates struct {
+	templates []*Template
+	separator string
+}
+
+func NewTemplates(templates []string, separator string) (*Templates, error) {
+	ts := &Templates{separator: separator}
+	for _, s := range templates {
+		t, err := ParseTemplate(s)
+		if err != nil {
+			return nil, err
+		}
+		ts.templates = append(ts.templates, t)
+	}
+	return ts, nil
+}
+
+// Metric - имя и метки метрики для пути Graphite. Если ни один шаблон не подошел,
+// путь используется как имя метрики без меток.
+func (ts *Templates) Metric(path string) (string, model.Labels) {
+	parts := strings.Split(path, ".")
+	for _, t := range ts.templates {
+		if t.Match(parts) {
+			if name, labels := t.Apply(parts, ts.separator); name != "" {
+				return name, labels
+			}
+		}
+	}
+	return path, nil
+}
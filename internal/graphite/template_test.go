@@ -0,0 +1,68 @@
+package graphite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+func TestTemplatesMetric(t *testing.T) {
+	templates, err := NewTemplates([]string{
+		"servers.* .host.measurement*",
+		"apps.*.*.* ..measurement.field",
+		"stats.* .measurement*",
+	}, DefaultSeparator)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedName   string
+		expectedLabels model.Labels
+	}{
+		{
+			name:           "measurement rest",
+			path:           "servers.host42.cpu.load",
+			expectedName:   "cpu.load",
+			expectedLabels: model.NewLabels("host", "host42"),
+		},
+		{
+			name:         "measurement and field",
+			path:         "apps.billing.requests.count",
+			expectedName: "requests.count",
+		},
+		{
+			name:         "first matched template",
+			path:         "stats.timers.api.latency",
+			expectedName: "timers.api.latency",
+		},
+		{
+			name:         "no template matched",
+			path:         "other.metric",
+			expectedName: "other.metric",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, labels := templates.Metric(tc.path)
+			assert.Equal(t, tc.expectedName, name)
+			assert.Equal(t, tc.expectedLabels, labels)
+		})
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"a.b c.d e.f",
+		"host.field",
+		"measurement*.host",
+	} {
+		_, err := ParseTemplate(s)
+		require.ErrorIs(t, err, ErrInvalidTemplate, s)
+	}
+}
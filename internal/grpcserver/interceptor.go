@@ -0,0 +1,200 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/pb"
+	"github.com/mikeziminio/go-custom-metrics/internal/sign"
+)
+
+func LoggingUnaryInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		res, err := handler(ctx, req)
+		logCall(logger, info.FullMethod, start, err)
+		return res, err
+	}
+}
+
+func LoggingStreamInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(logger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(logger *zap.Logger, method string, start time.Time, err error) {
+	logger.Info("grpc call",
+		zap.String("method", method),
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("duration", time.Since(start)),
+	)
+}
+
+// RecoveryUnaryInterceptor - паника в обработчике не завершает процесс сервера,
+// а возвращается клиенту ошибкой codes.Internal
+func RecoveryUnaryInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(logger, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func RecoveryStreamInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(logger, info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recovered(logger *zap.Logger, method string, r any) error {
+	logger.Error("grpc handler panic",
+		zap.String("method", method),
+		zap.Any("panic", r),
+		zap.Stack("stack"),
+	)
+	return status.Error(codes.Internal, "internal error")
+}
+
+// TrustedSubnetUnaryInterceptor - proxies необязательны, см. checkSubnet
+func TrustedSubnetUnaryInterceptor(subnet *net.IPNet, proxies []*net.IPNet) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := checkSubnet(ctx, subnet, proxies); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func TrustedSubnetStreamInterceptor(subnet *net.IPNet, proxies []*net.IPNet) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkSubnet(ss.Context(), subnet, proxies); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// checkSubnet - IP клиента берется из адреса соединения. Метаданные x-real-ip
+// учитываются, только если соединение открыто доверенным прокси из proxies:
+// значение задает сам клиент, и иначе любой клиент мог бы обойти проверку.
+func checkSubnet(ctx context.Context, subnet *net.IPNet, proxies []*net.IPNet) error {
+	var ip net.IP
+	if p, ok := peer.FromContext(ctx); ok {
+		if ap, err := netip.ParseAddrPort(p.Addr.String()); err == nil {
+			ip = net.IP(ap.Addr().Unmap().AsSlice())
+		}
+	}
+	if ip != nil && containsIP(proxies, ip) {
+		if values := metadata.ValueFromIncomingContext(ctx, pb.RealIPMetadataKey); len(values) > 0 {
+			ip = net.ParseIP(values[0])
+		}
+	}
+	if ip == nil || !subnet.Contains(ip) {
+		return status.Errorf(codes.PermissionDenied, "ip %q is not in trusted subnet", ip.String())
+	}
+	return nil
+}
+
+func containsIP(subnets []*net.IPNet, ip net.IP) bool {
+	for _, s := range subnets {
+		if s.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+var marshalOptions = proto.MarshalOptions{Deterministic: true}
+
+// SignUnaryInterceptor - проверка подписи сообщения запроса
+func SignUnaryInterceptor(signer *sign.Signer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return nil, status.Error(codes.Internal, "request is not a proto message")
+		}
+		b, err := marshalOptions.Marshal(msg)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if !signer.Verify(signatureFromContext(ctx), b) {
+			return nil, status.Error(codes.Unauthenticated, "incorrect signature")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// SignStreamInterceptor - проверка подписи всех сообщений клиентского потока.
+// Подпись передается в метаданных при открытии потока, поэтому проверить ее можно
+// только после получения последнего сообщения - вместо io.EOF обработчик получит
+// ошибку Unauthenticated, если подпись не совпала.
+func SignStreamInterceptor(signer *sign.Signer) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &signedStream{
+			ServerStream: ss,
+			signer:       signer,
+			signature:    signatureFromContext(ss.Context()),
+		})
+	}
+}
+
+type signedStream struct {
+	grpc.ServerStream
+	signer    *sign.Signer
+	signature string
+	msgs      [][]byte
+}
+
+func (s *signedStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if errors.Is(err, io.EOF) {
+		if !s.signer.Verify(s.signature, s.msgs...) {
+			return status.Error(codes.Unauthenticated, "incorrect signature")
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return status.Error(codes.Internal, "request is not a proto message")
+	}
+	b, err := marshalOptions.Marshal(msg)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	s.msgs = append(s.msgs, b)
+	return nil
+}
+
+func signatureFromContext(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, pb.SignMetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,177 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/pb"
+	"github.com/mikeziminio/go-custom-metrics/internal/server"
+	"github.com/mikeziminio/go-custom-metrics/internal/sign"
+)
+
+// Server - gRPC API приема и чтения метрик, работает с тем же хранилищем, что и HTTP API
+type Server struct {
+	pb.UnimplementedMetricsServer
+
+	address    string
+	storage    server.Storage
+	grpcServer *grpc.Server
+	logger     *zap.Logger
+}
+
+// New - trustedSubnet и key необязательны, при пустом значении
+// соответствующая проверка в цепочке перехватчиков не выполняется.
+// trustedProxies - подсети прокси, которым разрешено передавать IP клиента в x-real-ip.
+func New(
+	address string,
+	storage server.Storage,
+	trustedSubnet *net.IPNet,
+	trustedProxies []*net.IPNet,
+	key string,
+	logger *zap.Logger,
+) *Server {
+	unary := []grpc.UnaryServerInterceptor{LoggingUnaryInterceptor(logger), RecoveryUnaryInterceptor(logger)}
+	stream := []grpc.StreamServerInterceptor{LoggingStreamInterceptor(logger), RecoveryStreamInterceptor(logger)}
+	if trustedSubnet != nil {
+		unary = append(unary, TrustedSubnetUnaryInterceptor(trustedSubnet, trustedProxies))
+		stream = append(stream, TrustedSubnetStreamInterceptor(trustedSubnet, trustedProxies))
+	}
+	if key != "" {
+		signer := sign.New(key)
+		unary = append(unary, SignUnaryInterceptor(signer))
+		stream = append(stream, SignStreamInterceptor(signer))
+	}
+
+	s := &Server{
+		address: address,
+		storage: storage,
+		grpcServer: grpc.NewServer(
+			// спаны вызовов, продолжающие трассировку клиента
+			grpc.StatsHandler(otelgrpc.NewServerHandler()),
+			grpc.ChainUnaryInterceptor(unary...),
+			grpc.ChainStreamInterceptor(stream...),
+		),
+		logger: logger,
+	}
+	pb.RegisterMetricsServer(s.grpcServer, s)
+
+	return s
+}
+
+// Run - обслуживает запросы до отмены контекста
+func (s *Server) Run(ctx context.Context) {
+	ln, err := (&net.ListenConfig{}).Listen(ctx, "tcp", s.address)
+	if err != nil {
+		s.logger.Fatal("failed to start grpc server", zap.Error(err))
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.grpcServer.GracefulStop()
+	}()
+
+	s.logger.Info("gRPC server started", zap.String("address", ln.Addr().String()))
+	if err := s.grpcServer.Serve(ln); err != nil {
+		s.logger.Fatal("failed to serve grpc", zap.Error(err))
+	}
+	s.logger.Info("gRPC server stopped")
+}
+
+func (s *Server) Update(ctx context.Context, req *pb.UpdateRequest) (*pb.UpdateResponse, error) {
+	m, err := req.GetMetric().ToModel()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := server.TracedStorage(ctx, s.storage).Update(m); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.UpdateResponse{}, nil
+}
+
+// UpdateBatch - отклоненные сообщения не прерывают применение остальных, их индексы
+// возвращаются в деталях ошибки (pb.BatchError), чтобы клиент повторил только их
+func (s *Server) UpdateBatch(stream grpc.ClientStreamingServer[pb.UpdateRequest, pb.UpdateBatchResponse]) error {
+	var reqs []*pb.UpdateRequest
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		reqs = append(reqs, req)
+	}
+
+	storage := server.TracedStorage(stream.Context(), s.storage)
+	failed := make(map[int]error)
+	for i, req := range reqs {
+		m, err := req.GetMetric().ToModel()
+		if err == nil {
+			err = storage.Update(m)
+		}
+		if err != nil {
+			failed[i] = err
+		}
+	}
+	if len(failed) > 0 {
+		return pb.BatchError(failed)
+	}
+
+	return stream.SendAndClose(&pb.UpdateBatchResponse{Updated: int64(len(reqs))})
+}
+
+func (s *Server) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
+	t, err := req.GetType().ToModel()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	m, err := server.TracedStorage(ctx, s.storage).Get(t, req.GetId(), req.GetLabels())
+	if err != nil {
+		if errors.Is(err, model.ErrMetricNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.GetResponse{Metric: pb.FromMetric(m)}, nil
+}
+
+func (s *Server) List(ctx context.Context, _ *pb.ListRequest) (*pb.ListResponse, error) {
+	metrics := server.TracedStorage(ctx, s.storage).List()
+	res := &pb.ListResponse{Metrics: make([]*pb.Metric, 0, len(metrics))}
+	for _, m := range metrics {
+		res.Metrics = append(res.Metrics, pb.FromMetric(&m))
+	}
+	return res, nil
+}
+
+func (s *Server) UpdateMetadata(ctx context.Context, req *pb.UpdateMetadataRequest) (*pb.UpdateMetadataResponse, error) {
+	md, err := req.GetMetadata().ToModel()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := server.TracedStorage(ctx, s.storage).UpdateMetadata(req.GetName(), md); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.UpdateMetadataResponse{}, nil
+}
+
+func (s *Server) GetMetadata(ctx context.Context, req *pb.GetMetadataRequest) (*pb.GetMetadataResponse, error) {
+	md, err := server.TracedStorage(ctx, s.storage).Metadata(req.GetName())
+	if err != nil {
+		if errors.Is(err, model.ErrMetadataNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.GetMetadataResponse{Metadata: pb.FromMetadata(md)}, nil
+}
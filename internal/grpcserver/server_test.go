@@ -0,0 +1,253 @@
+package grpcserver
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/memstorage"
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/pb"
+	"github.com/mikeziminio/go-custom-metrics/internal/sign"
+	"github.com/mikeziminio/go-custom-metrics/internal/test/helper"
+)
+
+const testKey = "secret"
+
+func TestUpdateAndGet(t *testing.T) {
+	client, _ := testClient(t, nil, "")
+	ctx := context.Background()
+
+	_, err := client.Update(ctx, &pb.UpdateRequest{Metric: pb.FromMetric(&model.Metric{
+		ID:    "some",
+		MType: model.Counter,
+		Delta: helper.NewInt64(t, 5),
+	})})
+	require.NoError(t, err)
+
+	res, err := client.Get(ctx, &pb.GetRequest{Type: pb.MetricType_METRIC_TYPE_COUNTER, Id: "some"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), res.GetMetric().GetDelta())
+
+	_, err = client.Get(ctx, &pb.GetRequest{Type: pb.MetricType_METRIC_TYPE_GAUGE, Id: "some"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.Update(ctx, &pb.UpdateRequest{Metric: &pb.Metric{Id: "other", Type: pb.MetricType_METRIC_TYPE_GAUGE}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	list, err := client.List(ctx, &pb.ListRequest{})
+	require.NoError(t, err)
+	assert.Len(t, list.GetMetrics(), 1)
+}
+
+func TestUpdateBatchSign(t *testing.T) {
+	client, ms := testClient(t, nil, testKey)
+
+	reqs := []*pb.UpdateRequest{
+		{Metric: pb.FromMetric(&model.Metric{ID: "a", MType: model.Counter, Delta: helper.NewInt64(t, 1)})},
+		{Metric: pb.FromMetric(&model.Metric{ID: "b", MType: model.Gauge, Value: helper.NewFloat64(t, 2.5)})},
+	}
+	msgs := make([][]byte, 0, len(reqs))
+	for _, req := range reqs {
+		b, err := marshalOptions.Marshal(req)
+		require.NoError(t, err)
+		msgs = append(msgs, b)
+	}
+
+	send := func(signature string) (*pb.UpdateBatchResponse, error) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), pb.SignMetadataKey, signature)
+		stream, err := client.UpdateBatch(ctx)
+		require.NoError(t, err)
+		for _, req := range reqs {
+			require.NoError(t, stream.Send(req))
+		}
+		return stream.CloseAndRecv()
+	}
+
+	_, err := send(sign.New("wrong").Sign(msgs...))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Empty(t, ms.List())
+
+	res, err := send(sign.New(testKey).Sign(msgs...))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), res.GetUpdated())
+	assert.Len(t, ms.List(), 2)
+}
+
+func TestUpdateBatchPartial(t *testing.T) {
+	client, ms := testClient(t, nil, "")
+
+	stream, err := client.UpdateBatch(context.Background())
+	require.NoError(t, err)
+	for _, m := range []model.Metric{
+		{ID: "a", MType: model.Counter, Delta: helper.NewInt64(t, 1)},
+		{ID: "b", MType: model.Gauge},
+		{ID: "c", MType: model.Gauge, Value: helper.NewFloat64(t, 2.5)},
+	} {
+		require.NoError(t, stream.Send(&pb.UpdateRequest{Metric: pb.FromMetric(&m)}))
+	}
+	_, err = stream.CloseAndRecv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	failed, ok := pb.BatchFailed(err)
+	require.True(t, ok)
+	assert.Equal(t, []int{1}, failed)
+	assert.Len(t, ms.List(), 2)
+}
+
+func TestTrustedSubnet(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+	client, _ := testClient(t, subnet, "")
+
+	// адрес соединения bufconn не входит в подсеть, x-real-ip от клиента не учитывается
+	ctx := metadata.AppendToOutgoingContext(context.Background(), pb.RealIPMetadataKey, "10.1.2.3")
+	_, err = client.List(ctx, &pb.ListRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestCheckSubnet(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+	_, proxy, err := net.ParseCIDR("192.168.0.1/32")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		peer     string
+		realIP   string
+		expected codes.Code
+	}{
+		{name: "peer in subnet", peer: "10.1.2.3:5000", expected: codes.OK},
+		{name: "peer in subnet ipv4 mapped", peer: "[::ffff:10.1.2.3]:5000", expected: codes.OK},
+		{name: "peer outside subnet", peer: "172.16.0.1:5000", expected: codes.PermissionDenied},
+		{name: "spoofed real ip", peer: "172.16.0.1:5000", realIP: "10.1.2.3", expected: codes.PermissionDenied},
+		{name: "real ip from proxy", peer: "192.168.0.1:5000", realIP: "10.1.2.3", expected: codes.OK},
+		{name: "outside real ip from proxy", peer: "192.168.0.1:5000", realIP: "172.16.0.1", expected: codes.PermissionDenied},
+		{name: "proxy without real ip", peer: "192.168.0.1:5000", expected: codes.PermissionDenied},
+		{name: "no peer", expected: codes.PermissionDenied},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.peer != "" {
+				addr, err := net.ResolveTCPAddr("tcp", tc.peer)
+				require.NoError(t, err)
+				ctx = peer.NewContext(ctx, &peer.Peer{Addr: addr})
+			}
+			if tc.realIP != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(pb.RealIPMetadataKey, tc.realIP))
+			}
+			err := checkSubnet(ctx, subnet, []*net.IPNet{proxy})
+			assert.Equal(t, tc.expected, status.Code(err))
+		})
+	}
+}
+
+func TestUpdateInvalidExponentialHistogram(t *testing.T) {
+	client, _ := testClient(t, nil, "")
+
+	_, err := client.Update(context.Background(), &pb.UpdateRequest{Metric: pb.FromMetric(&model.Metric{
+		ID:    "latency",
+		MType: model.ExponentialHistogram,
+		ExponentialHistogram: &model.ExponentialHistogramData{
+			Positive: model.ExponentialBuckets{Offset: math.MinInt32 + 5, Counts: []uint64{1}},
+			Count:    1,
+		},
+	})})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestRecovery(t *testing.T) {
+	unary := RecoveryUnaryInterceptor(zap.NewNop())
+	_, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test"},
+		func(context.Context, any) (any, error) { panic("boom") })
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	stream := RecoveryStreamInterceptor(zap.NewNop())
+	err = stream(nil, nil, &grpc.StreamServerInfo{FullMethod: "/test"},
+		func(any, grpc.ServerStream) error { panic("boom") })
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func testClient(t *testing.T, subnet *net.IPNet, key string) (pb.MetricsClient, *memstorage.MemStorage) {
+	t.Helper()
+
+	ms := memstorage.New(0)
+	s := New("", ms, subnet, nil, key, zap.L())
+	ln := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = s.grpcServer.Serve(ln)
+	}()
+	t.Cleanup(s.grpcServer.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return pb.NewMetricsClient(conn), ms
+}
+
+func TestMetadata(t *testing.T) {
+	client, ms := testClient(t, nil, "")
+	ctx := context.Background()
+
+	_, err := client.UpdateMetadata(ctx, &pb.UpdateMetadataRequest{
+		Name:     "Alloc",
+		Metadata: &pb.Metadata{Type: pb.MetricType_METRIC_TYPE_GAUGE, Help: "Bytes of allocated heap objects.", Unit: "bytes"},
+	})
+	require.NoError(t, err)
+	md, err := ms.Metadata("Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, model.Metadata{Type: model.Gauge, Help: "Bytes of allocated heap objects.", Unit: "bytes"}, *md)
+
+	res, err := client.GetMetadata(ctx, &pb.GetMetadataRequest{Name: "Alloc"})
+	require.NoError(t, err)
+	assert.Equal(t, "bytes", res.GetMetadata().GetUnit())
+
+	_, err = client.GetMetadata(ctx, &pb.GetMetadataRequest{Name: "none"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.UpdateMetadata(ctx, &pb.UpdateMetadataRequest{Name: "Alloc", Metadata: &pb.Metadata{Unit: "k bytes"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	client, _ := testClient(t, nil, "")
+
+	_, err := client.List(context.Background(), &pb.ListRequest{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return len(recorder.Ended()) > 1
+	}, time.Second, 10*time.Millisecond)
+	// вызов хранилища - дочерний спан спана вызова gRPC
+	storageSpan, callSpan := recorder.Ended()[0], recorder.Ended()[1]
+	assert.Equal(t, "storage.List", storageSpan.Name())
+	assert.Equal(t, "metrics.v1.Metrics/List", callSpan.Name())
+	assert.Equal(t, callSpan.SpanContext().SpanID(), storageSpan.Parent().SpanID())
+}
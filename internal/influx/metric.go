@@ -0,0 +1,42 @@
+package influx
+
+import (
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+// Metrics - преобразует поля точки в метрики с именем <measurement>_<field>.
+// Целые поля (суффиксы i и u) сохраняются как counter, вещественные и логические -
+// как gauge, строковые поля пропускаются. Теги точки становятся метками метрик.
+func (p *Point) Metrics() []model.Metric {
+	metrics := make([]model.Metric, 0, len(p.Fields))
+	for _, f := range p.Fields {
+		m := model.Metric{
+			ID:        p.Measurement + "_" + f.Key,
+			Labels:    p.Tags,
+			Timestamp: p.Timestamp,
+		}
+		switch v := f.Value.(type) {
+		case int64:
+			m.MType = model.Counter
+			m.Delta = &v
+		case uint64:
+			d := int64(v) //nolint:gosec // переполнение для значений больше MaxInt64 допустимо
+			m.MType = model.Counter
+			m.Delta = &d
+		case float64:
+			m.MType = model.Gauge
+			m.Value = &v
+		case bool:
+			var val float64
+			if v {
+				val = 1
+			}
+			m.MType = model.Gauge
+			m.Value = &val
+		default:
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
@@ -0,0 +1,27 @@
+package influx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/test/helper"
+)
+
+func TestMetrics(t *testing.T) {
+	p := Point{
+		Measurement: "cpu",
+		Fields: []Field{
+			{Key: "usage", Value: 0.5},
+			{Key: "procs", Value: int64(12)},
+			{Key: "up", Value: true},
+			{Key: "state", Value: "ok"},
+		},
+	}
+	assert.Equal(t, []model.Metric{
+		{ID: "cpu_usage", MType: model.Gauge, Value: helper.NewFloat64(t, 0.5)},
+		{ID: "cpu_procs", MType: model.Counter, Delta: helper.NewInt64(t, 12)},
+		{ID: "cpu_up", MType: model.Gauge, Value: helper.NewFloat64(t, 1)},
+	}, p.Metrics())
+}
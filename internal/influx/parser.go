@@ -0,0 +1,213 @@
+package influx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+var ErrInvalidLine = errors.New("invalid line protocol")
+
+// Point - одна точка line protocol InfluxDB вида
+// <measurement>[,<tag>=<value>...] <field>=<value>[,<field>=<value>...] [<timestamp>]
+type Point struct {
+	Measurement string
+	Tags        model.Labels
+	Fields      []Field
+	Timestamp   time.Time
+}
+
+// Field - поле точки. Value имеет один из типов float64, int64, uint64, bool, string.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Precision - множитель метки времени в наносекундах для параметра precision
+func Precision(s string) (time.Duration, error) {
+	switch s {
+	case "", "n", "ns":
+		return time.Nanosecond, nil
+	case "u", "us":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	default:
+		return 0, fmt.Errorf("unknown precision %q", s)
+	}
+}
+
+// Parse - разбирает все строки из r. Пустые строки и комментарии пропускаются,
+// при первой некорректной строке возвращается ошибка с ее номером.
+func Parse(r io.Reader, precision time.Duration, now time.Time) ([]Point, error) {
+	var points []Point
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, bufio.MaxScanTokenSize*16) //nolint:mnd // строки с большим числом полей
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := ParseLine(line, precision, now)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		points = append(points, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	return points, nil
+}
+
+func ParseLine(line string, precision time.Duration, now time.Time) (Point, error) {
+	keyEnd := indexUnescaped(line, ' ', false)
+	if keyEnd < 0 {
+		return Point{}, fmt.Errorf("%w: no fields: %q", ErrInvalidLine, line)
+	}
+	key, rest := line[:keyEnd], strings.TrimLeft(line[keyEnd:], " ")
+
+	fieldsEnd := indexUnescaped(rest, ' ', true)
+	fieldsSection, tsSection := rest, ""
+	if fieldsEnd >= 0 {
+		fieldsSection, tsSection = rest[:fieldsEnd], strings.TrimSpace(rest[fieldsEnd:])
+	}
+
+	p := Point{Timestamp: now}
+
+	keyParts := splitUnescaped(key, ',', false)
+	p.Measurement = unescape(keyParts[0])
+	if p.Measurement == "" {
+		return Point{}, fmt.Errorf("%w: empty measurement: %q", ErrInvalidLine, line)
+	}
+	for _, t := range keyParts[1:] {
+		k, v, err := splitPair(t)
+		if err != nil {
+			return Point{}, err
+		}
+		if p.Tags == nil {
+			p.Tags = make(model.Labels)
+		}
+		p.Tags[k] = v
+	}
+	if err := p.Tags.Validate(); err != nil {
+		return Point{}, fmt.Errorf("%w: %w", ErrInvalidLine, err)
+	}
+
+	for _, f := range splitUnescaped(fieldsSection, ',', true) {
+		eq := indexUnescaped(f, '=', false)
+		if eq <= 0 {
+			return Point{}, fmt.Errorf("%w: incorrect field %q", ErrInvalidLine, f)
+		}
+		v, err := parseFieldValue(f[eq+1:])
+		if err != nil {
+			return Point{}, err
+		}
+		p.Fields = append(p.Fields, Field{Key: unescape(f[:eq]), Value: v})
+	}
+
+	if tsSection != "" {
+		ts, err := strconv.ParseInt(tsSection, 10, 64)
+		if err != nil {
+			return Point{}, fmt.Errorf("%w: incorrect timestamp %q", ErrInvalidLine, tsSection)
+		}
+		p.Timestamp = time.Unix(0, ts*int64(precision))
+	}
+
+	return p, nil
+}
+
+func splitPair(s string) (string, string, error) {
+	eq := indexUnescaped(s, '=', false)
+	if eq <= 0 || eq == len(s)-1 {
+		return "", "", fmt.Errorf("%w: incorrect tag %q", ErrInvalidLine, s)
+	}
+	return unescape(s[:eq]), unescape(s[eq+1:]), nil
+}
+
+func parseFieldValue(s string) (any, error) {
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty field value", ErrInvalidLine)
+	}
+	switch s {
+	case "t", "T", "true", "True", "TRUE":
+		return true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, nil
+	}
+	last := len(s) - 1
+	switch {
+	case s[0] == '"':
+		if len(s) < 2 || s[last] != '"' {
+			return nil, fmt.Errorf("%w: unterminated string %s", ErrInvalidLine, s)
+		}
+		return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(s[1:last]), nil
+	case s[last] == 'i':
+		v, err := strconv.ParseInt(s[:last], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: incorrect integer %q", ErrInvalidLine, s)
+		}
+		return v, nil
+	case s[last] == 'u':
+		v, err := strconv.ParseUint(s[:last], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: incorrect unsigned integer %q", ErrInvalidLine, s)
+		}
+		return v, nil
+	default:
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: incorrect float %q", ErrInvalidLine, s)
+		}
+		return v, nil
+	}
+}
+
+// indexUnescaped - индекс первого sep, не экранированного \ и,
+// если quoted, не находящегося внутри строки в двойных кавычках
+func indexUnescaped(s string, sep byte, quoted bool) int {
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case quoted && s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			return i
+		}
+	}
+	return -1
+}
+
+func splitUnescaped(s string, sep byte, quoted bool) []string {
+	var parts []string
+	for {
+		i := indexUnescaped(s, sep, quoted)
+		if i < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+1:]
+	}
+}
+
+var unescaper = strings.NewReplacer(`\,`, `,`, `\ `, ` `, `\=`, `=`, `\\`, `\`)
+
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
@@ -0,0 +1,109 @@
+package influx
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+func TestParseLine(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	testCases := []struct {
+		name          string
+		line          string
+		precision     time.Duration
+		expectedPoint Point
+		expectedErr   bool
+	}{
+		{
+			name:      "fields of all types",
+			line:      `cpu,host=host42,region=eu usage=0.5,procs=12i,threads=7u,up=true,state="ok" 1600000000000000000`,
+			precision: time.Nanosecond,
+			expectedPoint: Point{
+				Measurement: "cpu",
+				Tags:        model.NewLabels("host", "host42", "region", "eu"),
+				Fields: []Field{
+					{Key: "usage", Value: 0.5},
+					{Key: "procs", Value: int64(12)},
+					{Key: "threads", Value: uint64(7)},
+					{Key: "up", Value: true},
+					{Key: "state", Value: "ok"},
+				},
+				Timestamp: time.Unix(1600000000, 0),
+			},
+		},
+		{
+			name:      "escaped characters and no timestamp",
+			line:      `disk\ io,path=/var\,log msg="a, b=\"c\"",bytes=1`,
+			precision: time.Nanosecond,
+			expectedPoint: Point{
+				Measurement: "disk io",
+				Tags:        model.NewLabels("path", "/var,log"),
+				Fields: []Field{
+					{Key: "msg", Value: `a, b="c"`},
+					{Key: "bytes", Value: 1.0},
+				},
+				Timestamp: now,
+			},
+		},
+		{
+			name:      "timestamp precision",
+			line:      "mem free=1i 1600000000",
+			precision: time.Second,
+			expectedPoint: Point{
+				Measurement: "mem",
+				Fields:      []Field{{Key: "free", Value: int64(1)}},
+				Timestamp:   time.Unix(1600000000, 0),
+			},
+		},
+		{
+			name:        "no fields",
+			line:        "cpu,host=a",
+			expectedErr: true,
+		},
+		{
+			name:        "incorrect integer",
+			line:        "cpu procs=1.5i",
+			expectedErr: true,
+		},
+		{
+			name:        "incorrect tag",
+			line:        "cpu,host usage=1",
+			expectedErr: true,
+		},
+		{
+			name:        "unterminated string",
+			line:        `cpu state="ok`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := ParseLine(tc.line, tc.precision, now)
+			if tc.expectedErr {
+				require.ErrorIs(t, err, ErrInvalidLine)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPoint, p)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	body := "# comment\ncpu usage=1\n\nmem free=2i\n"
+	points, err := Parse(strings.NewReader(body), time.Nanosecond, time.Now())
+	require.NoError(t, err)
+	assert.Len(t, points, 2)
+
+	_, err = Parse(strings.NewReader("cpu usage=1\ncpu\n"), time.Nanosecond, time.Now())
+	require.ErrorIs(t, err, ErrInvalidLine)
+	assert.Contains(t, err.Error(), "line 2")
+}
@@ -0,0 +1,91 @@
+package memstorage
+
+import (
+	"errors"
+)
+
+var errEndOfStream = errors.New("end of bit stream")
+
+// bstream - поток бит, запись и чтение начиная со старшего бита байта
+type bstream struct {
+	stream []byte
+	// count - количество свободных бит в последнем байте
+	count uint8
+}
+
+func (b *bstream) writeBit(bit bool) {
+	if b.count == 0 {
+		b.stream = append(b.stream, 0)
+		b.count = 8
+	}
+	if bit {
+		b.stream[len(b.stream)-1] |= 1 << (b.count - 1)
+	}
+	b.count--
+}
+
+// writeBits - записывает младшие nbits бит значения u
+func (b *bstream) writeBits(u uint64, nbits int) {
+	u <<= 64 - uint(nbits)
+	for nbits >= 8 {
+		b.writeByte(byte(u >> 56))
+		u <<= 8
+		nbits -= 8
+	}
+	for nbits > 0 {
+		b.writeBit(u>>63 == 1)
+		u <<= 1
+		nbits--
+	}
+}
+
+func (b *bstream) writeByte(byt byte) {
+	if b.count == 0 {
+		b.stream = append(b.stream, byt)
+		return
+	}
+	i := len(b.stream) - 1
+	b.stream[i] |= byt >> (8 - b.count)
+	b.stream = append(b.stream, byt<<b.count)
+}
+
+// bstreamReader - чтение потока бит, не изменяет исходный поток
+type bstreamReader struct {
+	stream []byte
+	// pos - номер следующего бита для чтения
+	pos int
+	// limit - количество записанных бит
+	limit int
+}
+
+func newBReader(b *bstream) bstreamReader {
+	return bstreamReader{
+		stream: b.stream,
+		limit:  len(b.stream)*8 - int(b.count),
+	}
+}
+
+func (r *bstreamReader) readBit() (bool, error) {
+	if r.pos >= r.limit {
+		return false, errEndOfStream
+	}
+	bit := r.stream[r.pos/8]&(1<<(7-r.pos%8)) != 0
+	r.pos++
+	return bit, nil
+}
+
+func (r *bstreamReader) readBits(nbits int) (uint64, error) {
+	if r.pos+nbits > r.limit {
+		return 0, errEndOfStream
+	}
+	var u uint64
+	for nbits > 0 {
+		offset := r.pos % 8
+		n := min(8-offset, nbits)
+		byt := r.stream[r.pos/8] << offset >> (8 - n)
+		u = u<<n | uint64(byt)
+		r.pos += n
+		nbits -= n
+	}
+	return u, nil
+}
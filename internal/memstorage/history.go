@@ -0,0 +1,134 @@
+package memstorage
+
+import (
+	"time"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+// chunkSize - количество значений в одном блоке истории
+const chunkSize = 120
+
+// history - история значений ряда, разбитая на сжатые блоки фиксированного размера.
+// Значения в истории упорядочены по времени, устаревшие блоки удаляются целиком.
+// Метки времени хранятся с точностью до миллисекунды.
+type history struct {
+	chunks []*xorChunk
+}
+
+// append - добавляет значение в конец истории. Значения с меткой времени
+// раньше последнего сохраненного в историю не попадают.
+func (h *history) append(s model.Sample) bool {
+	t := s.Timestamp.UnixMilli()
+	var last *xorChunk
+	if len(h.chunks) > 0 {
+		last = h.chunks[len(h.chunks)-1]
+		if t < last.t {
+			return false
+		}
+	}
+	if last == nil || last.num == chunkSize {
+		last = newXORChunk()
+		h.chunks = append(h.chunks, last)
+	}
+	last.append(t, s.Value)
+	return true
+}
+
+// truncate - удаляет блоки, все значения которых старше before
+func (h *history) truncate(before time.Time) {
+	t := before.UnixMilli()
+	n := 0
+	for n < len(h.chunks) && h.chunks[n].t < t {
+		n++
+	}
+	if n > 0 {
+		h.chunks = append(h.chunks[:0], h.chunks[n:]...)
+	}
+}
+
+// size - размер сжатой истории в байтах
+func (h *history) size() int {
+	var size int
+	for _, c := range h.chunks {
+		size += c.size()
+	}
+	return size
+}
+
+// iterator - итератор по значениям из интервала [from, to].
+// Блоки вне интервала пропускаются без декодирования.
+func (h *history) iterator(from, to time.Time) *historyIterator {
+	return &historyIterator{
+		chunks: h.chunks,
+		from:   from.UnixMilli(),
+		to:     to.UnixMilli(),
+	}
+}
+
+type historyIterator struct {
+	chunks   []*xorChunk
+	from, to int64
+	cur      *xorIterator
+	err      error
+}
+
+func (it *historyIterator) Next() bool {
+	for {
+		if it.cur == nil {
+			if !it.nextChunk() {
+				return false
+			}
+		}
+		for it.cur.Next() {
+			t, _ := it.cur.At()
+			if t > it.to {
+				it.chunks = nil
+				break
+			}
+			if t >= it.from {
+				return true
+			}
+		}
+		if err := it.cur.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		it.cur = nil
+	}
+}
+
+func (it *historyIterator) nextChunk() bool {
+	for len(it.chunks) > 0 {
+		c := it.chunks[0]
+		it.chunks = it.chunks[1:]
+		if c.minT > it.to {
+			it.chunks = nil
+			return false
+		}
+		if c.t >= it.from {
+			it.cur = c.iterator()
+			return true
+		}
+	}
+	return false
+}
+
+func (it *historyIterator) At() model.Sample {
+	t, v := it.cur.At()
+	return model.Sample{Timestamp: time.UnixMilli(t), Value: v}
+}
+
+func (it *historyIterator) Err() error {
+	return it.err
+}
+
+// rangeSamples - значения из интервала [from, to]
+func (h *history) rangeSamples(from, to time.Time) ([]model.Sample, error) {
+	var res []model.Sample
+	it := h.iterator(from, to)
+	for it.Next() {
+		res = append(res, it.At())
+	}
+	return res, it.Err()
+}
@@ -0,0 +1,183 @@
+package memstorage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/test/helper"
+)
+
+func TestRange(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	ms := New(0)
+	for i := range 5 {
+		err := ms.Update(model.Metric{
+			ID:        "some",
+			MType:     model.Counter,
+			Delta:     helper.NewInt64(t, 1),
+			Timestamp: start.Add(time.Duration(i) * 10 * time.Second),
+		})
+		require.NoError(t, err)
+	}
+
+	testCases := []struct {
+		name           string
+		from, to       time.Time
+		step           time.Duration
+		expectedValues []float64
+	}{
+		{
+			name:           "all samples",
+			from:           start,
+			to:             start.Add(time.Minute),
+			expectedValues: []float64{1, 2, 3, 4, 5},
+		},
+		{
+			name:           "interval",
+			from:           start.Add(10 * time.Second),
+			to:             start.Add(30 * time.Second),
+			expectedValues: []float64{2, 3, 4},
+		},
+		{
+			name:           "step",
+			from:           start,
+			to:             start.Add(time.Minute),
+			step:           20 * time.Second,
+			expectedValues: []float64{2, 4, 5},
+		},
+		{
+			// окна не перебираются по одному, иначе запрос выполнялся бы часами
+			name:           "nanosecond step",
+			from:           start.Add(-time.Hour),
+			to:             start.Add(time.Minute),
+			step:           time.Nanosecond,
+			expectedValues: []float64{1, 2, 3, 4, 5},
+		},
+		{
+			name: "empty interval",
+			from: start.Add(time.Hour),
+			to:   start.Add(2 * time.Hour),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			samples, err := ms.Range(model.Counter, "some", nil, tc.from, tc.to, tc.step)
+			require.NoError(t, err)
+			var values []float64
+			for _, s := range samples {
+				values = append(values, s.Value)
+			}
+			assert.Equal(t, tc.expectedValues, values)
+		})
+	}
+
+	_, err := ms.Range(model.Gauge, "some", nil, start, start, 0)
+	require.ErrorIs(t, err, model.ErrMetricNotFound)
+}
+
+func TestHistoryRetention(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ms := New(time.Hour)
+	ms.now = func() time.Time { return now }
+
+	for i := range 2 * chunkSize {
+		err := ms.Update(model.Metric{
+			ID:        "some",
+			MType:     model.Gauge,
+			Value:     helper.NewFloat64(t, float64(i)),
+			Timestamp: now.Add(-2 * time.Hour).Add(time.Duration(i) * time.Second),
+		})
+		require.NoError(t, err)
+	}
+	err := ms.Update(model.Metric{ID: "some", MType: model.Gauge, Value: helper.NewFloat64(t, 1)})
+	require.NoError(t, err)
+
+	samples, err := ms.Range(model.Gauge, "some", nil, time.Time{}, now, 0)
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, now, samples[0].Timestamp)
+}
+
+func TestHistoryOutOfOrder(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	h := &history{}
+	assert.True(t, h.append(model.Sample{Timestamp: now, Value: 1}))
+	assert.False(t, h.append(model.Sample{Timestamp: now.Add(-time.Second), Value: 2}))
+	assert.True(t, h.append(model.Sample{Timestamp: now, Value: 3}))
+	samples, err := h.rangeSamples(now, now)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Sample{
+		{Timestamp: now, Value: 1},
+		{Timestamp: now, Value: 3},
+	}, samples)
+}
+
+func TestCompactRetention(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ms := New(time.Hour)
+	ms.now = func() time.Time { return now }
+
+	for i := range 2 * chunkSize {
+		err := ms.Update(model.Metric{
+			ID:        "some",
+			MType:     model.Gauge,
+			Value:     helper.NewFloat64(t, float64(i)),
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+		})
+		require.NoError(t, err)
+	}
+
+	// ряд больше не обновляется, старая история удаляется при Compact
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, ms.Compact())
+	samples, err := ms.Range(model.Gauge, "some", nil, time.Time{}, now, 0)
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+	_, err = ms.Get(model.Gauge, "some", nil)
+	require.NoError(t, err)
+}
+
+func TestUpdateOutOfOrder(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ms := New(0)
+	ms.now = func() time.Time { return now }
+
+	update := func(m model.Metric) {
+		t.Helper()
+		require.NoError(t, ms.Update(m))
+	}
+	rangeValues := func(metricType model.MetricType) []model.Sample {
+		t.Helper()
+		samples, err := ms.Range(metricType, "some", nil, time.Time{}, now.Add(time.Hour), 0)
+		require.NoError(t, err)
+		return samples
+	}
+
+	// устаревшее значение gauge не меняет ни текущее значение, ни историю
+	update(model.Metric{ID: "some", MType: model.Gauge, Value: helper.NewFloat64(t, 1), Timestamp: now})
+	update(model.Metric{ID: "some", MType: model.Gauge, Value: helper.NewFloat64(t, 2), Timestamp: now.Add(-time.Second)})
+	m, err := ms.Get(model.Gauge, "some", nil)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, *m.Value, 1e-9)
+	assert.Equal(t, []model.Sample{{Timestamp: now, Value: 1}}, rangeValues(model.Gauge))
+
+	// опоздавшее приращение counter учитывается с временем последнего обновления
+	update(model.Metric{ID: "some", MType: model.Counter, Delta: helper.NewInt64(t, 1), Timestamp: now})
+	update(model.Metric{ID: "some", MType: model.Counter, Delta: helper.NewInt64(t, 2), Timestamp: now.Add(-time.Second)})
+	assert.Equal(t, []model.Sample{{Timestamp: now, Value: 1}, {Timestamp: now, Value: 3}}, rangeValues(model.Counter))
+
+	// метка времени из будущего не блокирует ряд
+	update(model.Metric{ID: "some", MType: model.Counter, Delta: helper.NewInt64(t, 1), Timestamp: now.Add(time.Hour)})
+	now = now.Add(time.Second)
+	update(model.Metric{ID: "some", MType: model.Counter, Delta: helper.NewInt64(t, 1), Timestamp: now})
+	m, err = ms.Get(model.Counter, "some", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), *m.Delta)
+	assert.Equal(t, now, m.Timestamp)
+	assert.Len(t, rangeValues(model.Counter), 4)
+}
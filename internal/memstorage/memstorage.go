@@ -1,59 +1,463 @@
 package memstorage
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"maps"
+	"slices"
 	"sync"
+	"time"
+
+	"go.uber.org/zap"
 
 	"github.com/mikeziminio/go-custom-metrics/internal/model"
 	"github.com/mikeziminio/go-custom-metrics/internal/server"
+	"github.com/mikeziminio/go-custom-metrics/internal/telemetry"
 )
 
+// MemStorage - хранит текущее значение каждого ряда, историю его значений и метаданные метрик.
+// История хранится не дольше retention, при retention = 0 - без ограничений.
+// Для каждого уровня прореживания из tiers история дополнительно сворачивается
+// в агрегаты, см. Compact.
 type MemStorage struct {
 	metrics map[string]model.Metric
-	mu      sync.RWMutex
+	// snapshot - копия metrics, которую возвращает List, nil - устарела после Update
+	snapshot  map[string]model.Metric
+	history   map[string]*history
+	rollups   map[string][]*rollupHistory
+	metadata  map[string]model.Metadata
+	retention time.Duration
+	tiers     []Tier
+	now       func() time.Time
+	mu        sync.RWMutex
 }
 
 var _ server.Storage = (*MemStorage)(nil)
 
-func New() *MemStorage {
+func New(retention time.Duration, tiers ...Tier) *MemStorage {
+	tiers = slices.Clone(tiers)
+	slices.SortFunc(tiers, func(a, b Tier) int {
+		return int(a.Resolution - b.Resolution)
+	})
 	return &MemStorage{
-		metrics: make(map[string]model.Metric),
+		metrics:   make(map[string]model.Metric),
+		history:   make(map[string]*history),
+		rollups:   make(map[string][]*rollupHistory),
+		metadata:  make(map[string]model.Metadata),
+		retention: retention,
+		tiers:     tiers,
+		now:       time.Now,
 	}
 }
 
+// Update - некорректная метрика отклоняется с ошибкой Metric.Validate.
+// Метрики типа Gauge перезатирают значение, метрики типа Counter
+// инкрементируют значение, наблюдения гистограмм и summary добавляются к сохраненным,
+// множества объединяются.
+// Новые значения рядов метрики добавляются в историю с временной меткой метрики
+// или, если она не задана, с текущим временем. Временная метка сохраняется
+// как время последнего обновления метрики.
+//
+// История ряда упорядочена по времени, поэтому текущее значение и история меняются
+// одинаково: метка времени из будущего заменяется текущим временем, иначе ряд не принимал бы
+// значения до наступления этого времени. Значение gauge с меткой раньше последнего обновления
+// устарело и пропускается, приращения остальных типов учитываются с временем последнего обновления.
 func (s *MemStorage) Update(m model.Metric) error {
-	// todo: next sprint
-	// в текущем спринте не дается никаких требований на хранение метрик
-	// поэтому сейчас метрики типа Gauge перезатирают значение,
-	// а метрики типа Counter инкрементируют значение.
-	// Вероятно далее необходимо будет сохранять значение с конкретной
-	// временной меткой, но в рамках 1-го спринта это избыточно.
+	// значения разыменовываются ниже, поэтому метрика проверяется и при вызове в обход сервера
+	if err := m.Validate(); err != nil {
+		return err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	current, ok := s.metrics[m.ID]
-	if ok && m.MType == model.Counter {
-		*m.Delta += *current.Delta
+	if now := s.now(); m.Timestamp.IsZero() || m.Timestamp.After(now) {
+		m.Timestamp = now
+	}
+	key := m.Key()
+	current, ok := s.metrics[key]
+	if ok && m.Timestamp.Before(current.Timestamp) {
+		if m.MType == model.Gauge {
+			return nil
+		}
+		m.Timestamp = current.Timestamp
 	}
-	s.metrics[m.ID] = m
+	switch m.MType {
+	case model.Counter:
+		if ok {
+			*m.Delta += *current.Delta
+		}
+	case model.Histogram:
+		h, err := updateHistogram(current.Histogram, m)
+		if err != nil {
+			return err
+		}
+		m.Histogram = h
+		m.Value = nil
+	case model.ExponentialHistogram:
+		h, err := updateExponentialHistogram(current.ExponentialHistogram, m)
+		if err != nil {
+			return err
+		}
+		m.ExponentialHistogram = h
+		m.Value = nil
+	case model.Summary:
+		sm, err := updateSummary(current.Summary, m)
+		if err != nil {
+			return err
+		}
+		m.Summary = sm
+		m.Value = nil
+	case model.Set:
+		st, err := updateSet(current.Set, m)
+		if err != nil {
+			return err
+		}
+		m.Set = st
+		m.Members = nil
+	}
+	s.metrics[key] = m
+	s.snapshot = nil
+	s.appendHistory(m)
 	return nil
 }
 
+// updateHistogram - гистограмма current с добавленными наблюдениями из m.
+// Новая гистограмма из одного наблюдения создается с границами model.DefaultBuckets.
+func updateHistogram(current *model.HistogramData, m model.Metric) (*model.HistogramData, error) {
+	if m.Histogram == nil {
+		var h *model.HistogramData
+		if current != nil {
+			h = current.Clone()
+		} else {
+			var err error
+			if h, err = model.NewHistogram(model.DefaultBuckets); err != nil {
+				return nil, err
+			}
+		}
+		h.Observe(*m.Value)
+		return h, nil
+	}
+	if err := m.Histogram.Validate(); err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return m.Histogram.Clone(), nil
+	}
+	return current.Merge(m.Histogram)
+}
+
+// updateExponentialHistogram - гистограмма current с добавленными наблюдениями из m.
+// Новая гистограмма из одного наблюдения создается с максимальной схемой,
+// которая уменьшается по мере расширения диапазона значений.
+func updateExponentialHistogram(
+	current *model.ExponentialHistogramData,
+	m model.Metric,
+) (*model.ExponentialHistogramData, error) {
+	if m.ExponentialHistogram == nil {
+		var h *model.ExponentialHistogramData
+		if current != nil {
+			h = current.Clone()
+		} else {
+			var err error
+			if h, err = model.NewExponentialHistogram(model.ExponentialMaxSchema); err != nil {
+				return nil, err
+			}
+		}
+		h.Observe(*m.Value)
+		return h, nil
+	}
+	if err := m.ExponentialHistogram.Validate(); err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return m.ExponentialHistogram.Clone(), nil
+	}
+	return current.Merge(m.ExponentialHistogram), nil
+}
+
+// updateSummary - скетч current с добавленными наблюдениями из m.
+// Новый скетч из одного наблюдения создается с погрешностью model.DefaultRelativeAccuracy.
+func updateSummary(current *model.SummaryData, m model.Metric) (*model.SummaryData, error) {
+	if m.Summary == nil {
+		var s *model.SummaryData
+		if current != nil {
+			s = current.Clone()
+		} else {
+			var err error
+			if s, err = model.NewSummary(model.DefaultRelativeAccuracy); err != nil {
+				return nil, err
+			}
+		}
+		s.Observe(*m.Value)
+		return s, nil
+	}
+	if err := m.Summary.Validate(); err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return m.Summary.Clone(), nil
+	}
+	return current.Merge(m.Summary)
+}
+
+// updateSet - множество current с добавленными элементами и объединенное с множеством из m.
+// Новое множество создается с точностью model.DefaultSetPrecision.
+func updateSet(current *model.SetData, m model.Metric) (*model.SetData, error) {
+	var res *model.SetData
+	switch {
+	case current != nil && m.Set != nil:
+		if err := m.Set.Validate(); err != nil {
+			return nil, err
+		}
+		var err error
+		if res, err = current.Merge(m.Set); err != nil {
+			return nil, err
+		}
+	case current != nil:
+		res = current.Clone()
+	case m.Set != nil:
+		if err := m.Set.Validate(); err != nil {
+			return nil, err
+		}
+		res = m.Set.Clone()
+	default:
+		var err error
+		if res, err = model.NewSet(model.DefaultSetPrecision); err != nil {
+			return nil, err
+		}
+	}
+	for _, member := range m.Members {
+		res.Add(member)
+	}
+	return res, nil
+}
+
+// appendHistory - история хранится отдельно для каждого ряда метрики, см. model.Metric.Series
+func (s *MemStorage) appendHistory(m model.Metric) {
+	now := s.now()
+	for _, sv := range m.Series() {
+		key := model.SeriesKey(m.MType, sv.Name, sv.Labels)
+		h, ok := s.history[key]
+		if !ok {
+			h = &history{}
+			s.history[key] = h
+			rollups := make([]*rollupHistory, len(s.tiers))
+			for i, t := range s.tiers {
+				rollups[i] = &rollupHistory{Tier: t}
+			}
+			s.rollups[key] = rollups
+		}
+		h.append(model.Sample{Timestamp: m.Timestamp, Value: sv.Value})
+		s.truncateHistory(key, now)
+	}
+}
+
+// truncateHistory - удаляет из истории ряда значения старше retention,
+// значения, еще не свернутые в агрегаты, не удаляются
+func (s *MemStorage) truncateHistory(key string, now time.Time) {
+	if s.retention == 0 {
+		return
+	}
+	cutoff := now.Add(-s.retention)
+	for _, r := range s.rollups[key] {
+		if r.compacted.Before(cutoff) {
+			cutoff = r.compacted
+		}
+	}
+	s.history[key].truncate(cutoff)
+}
+
+// List - копия metrics, общая для всех вызовов до следующего Update.
+// Копируется только после изменений, поэтому частые запросы списка
+// не копируют все ряды на каждый вызов.
 func (s *MemStorage) List() map[string]model.Metric {
-	// todo: next sprints
-	// Возвращает копию мапы с метриками - не самый оптимальный вариант,
-	// Но т.к. требования к структуре хранения метрик вероятно будет
-	// обновлено в следующих спринтах - для упрощения пока сделано так.
 	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return maps.Clone(s.metrics)
+	snapshot := s.snapshot
+	s.mu.RUnlock()
+	if snapshot != nil {
+		return snapshot
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshot == nil {
+		s.snapshot = maps.Clone(s.metrics)
+	}
+	return s.snapshot
 }
 
-func (s *MemStorage) Get(metricType model.MetricType, metricName string) (*model.Metric, error) {
+func (s *MemStorage) Get(metricType model.MetricType, metricName string, labels model.Labels) (*model.Metric, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	m, ok := s.metrics[metricName]
-	if !ok || m.MType != metricType {
+	m, ok := s.metrics[model.SeriesKey(metricType, metricName, labels)]
+	if !ok {
 		return nil, model.ErrMetricNotFound
 	}
 	return &m, nil
 }
+
+// UpdateMetadata - метаданные, как и метрики, хранятся только в памяти и теряются при перезапуске.
+// todo: next sprints
+// сохранение метаданных вместе с метриками при появлении постоянного хранилища
+func (s *MemStorage) UpdateMetadata(metricName string, md model.Metadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metadata[metricName] = md
+	return nil
+}
+
+func (s *MemStorage) Metadata(metricName string) (*model.Metadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	md, ok := s.metadata[metricName]
+	if !ok {
+		return nil, model.ErrMetadataNotFound
+	}
+	return &md, nil
+}
+
+func (s *MemStorage) ListMetadata() map[string]model.Metadata {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.metadata)
+}
+
+// Range - история значений ряда в интервале [from, to], прореженная с шагом step.
+// Для гистограммы metricName и labels задают один из рядов model.HistogramData.Series.
+// Уровень прореживания выбирается rangeTier, агрегаты еще не свернутой части истории
+// вычисляются при запросе.
+func (s *MemStorage) Range(
+	metricType model.MetricType,
+	metricName string,
+	labels model.Labels,
+	from, to time.Time,
+	step time.Duration,
+) ([]model.Sample, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key := model.SeriesKey(metricType, metricName, labels)
+	h, ok := s.history[key]
+	if !ok {
+		return nil, model.ErrMetricNotFound
+	}
+
+	tier := s.rangeTier(s.rollups[key], from, step)
+	if tier == nil {
+		samples, err := h.rangeSamples(from, to)
+		if err != nil {
+			return nil, err
+		}
+		return downsample(samples, from, step), nil
+	}
+
+	start := from.Truncate(tier.Resolution)
+	samples, err := tier.rangeSamples(start, to)
+	if err != nil {
+		return nil, err
+	}
+	if !to.Before(tier.compacted) {
+		tail, err := rollup(h.iterator(latest(start, tier.compacted), to), tier.Resolution)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, tail...)
+	}
+	return downsample(samples, from, step), nil
+}
+
+// rangeTier - уровень прореживания для запроса с from и step, nil - история без прореживания.
+// Из уровней, время хранения которых покрывает from, выбирается самый грубый с интервалом
+// не больше step, если таких нет - самый детальный. Если from не покрывает ни один уровень,
+// выбирается уровень с самым долгим хранением: более детальные данные уже удалены.
+func (s *MemStorage) rangeTier(rollups []*rollupHistory, from time.Time, step time.Duration) *rollupHistory {
+	now := s.now()
+	covers := func(retention time.Duration) bool {
+		return retention == 0 || !from.Before(now.Add(-retention))
+	}
+	// самый детальный уровень - история без прореживания
+	var (
+		tier      *rollupHistory
+		covered   = covers(s.retention)
+		retention = s.retention
+	)
+	for _, r := range rollups {
+		switch {
+		case covers(r.Retention) && (!covered || step > 0 && r.Resolution <= step):
+			tier, covered, retention = r, true, r.Retention
+		case !covered && r.Retention > retention:
+			tier, retention = r, r.Retention
+		}
+	}
+	return tier
+}
+
+// Compact - сворачивает в агрегаты интервалы истории всех рядов, завершенные к текущему моменту,
+// и удаляет значения старше retention, в том числе у рядов, которые больше не обновляются.
+// Блокировка берется отдельно для каждого ряда, чтобы не задерживать запись.
+func (s *MemStorage) Compact() error {
+	if len(s.tiers) == 0 && s.retention == 0 {
+		return nil
+	}
+	s.mu.RLock()
+	keys := slices.Collect(maps.Keys(s.history))
+	s.mu.RUnlock()
+
+	var errs []error
+	for _, key := range keys {
+		s.mu.Lock()
+		now := s.now()
+		for _, r := range s.rollups[key] {
+			if err := r.compact(s.history[key], now); err != nil {
+				errs = append(errs, fmt.Errorf("failed to compact %s: %w", key, err))
+			}
+		}
+		s.truncateHistory(key, now)
+		s.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// MetricCompactionDuration - собственная метрика длительности Compact
+const MetricCompactionDuration = "storage_compaction_duration_seconds"
+
+// retentionInterval - частота Compact без уровней прореживания, только для удаления старой истории
+const retentionInterval = time.Minute
+
+// RunCompaction - периодически сворачивает историю с частотой самого детального
+// уровня прореживания (без уровней - с частотой retentionInterval) до завершения ctx,
+// длительность сворачивания учитывается в reg
+func (s *MemStorage) RunCompaction(ctx context.Context, reg *telemetry.Registry, logger *zap.Logger) {
+	if len(s.tiers) == 0 && s.retention == 0 {
+		return
+	}
+	interval := retentionInterval
+	if len(s.tiers) > 0 {
+		interval = s.tiers[0].Resolution
+	}
+	reg.Describe(MetricCompactionDuration, model.Metadata{
+		Type: model.Histogram,
+		Help: "Duration of storage history compaction into rollup tiers.",
+		Unit: "seconds",
+	})
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			start := time.Now()
+			if err := s.Compact(); err != nil {
+				logger.Error("failed to compact history", zap.Error(err))
+			}
+			reg.Observe(MetricCompactionDuration, nil, time.Since(start).Seconds())
+		}
+	}
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -1,7 +1,10 @@
 package memstorage
 
 import (
+	"reflect"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -27,7 +30,7 @@ func TestUpdate(t *testing.T) {
 				Value: nil,
 			},
 			expectedMetrics: map[string]model.Metric{
-				"some": {
+				"counter:some": {
 					ID:    "some",
 					MType: model.Counter,
 					Delta: helper.NewInt64(t, 5),
@@ -38,7 +41,7 @@ func TestUpdate(t *testing.T) {
 		{
 			name: "add counter metric",
 			metrics: map[string]model.Metric{
-				"some": {
+				"counter:some": {
 					ID:    "some",
 					MType: model.Counter,
 					Delta: helper.NewInt64(t, 5),
@@ -52,13 +55,13 @@ func TestUpdate(t *testing.T) {
 				Value: nil,
 			},
 			expectedMetrics: map[string]model.Metric{
-				"some": {
+				"counter:some": {
 					ID:    "some",
 					MType: model.Counter,
 					Delta: helper.NewInt64(t, 5),
 					Value: nil,
 				},
-				"other": {
+				"counter:other": {
 					ID:    "other",
 					MType: model.Counter,
 					Delta: helper.NewInt64(t, 8),
@@ -76,7 +79,7 @@ func TestUpdate(t *testing.T) {
 				Value: helper.NewFloat64(t, 5),
 			},
 			expectedMetrics: map[string]model.Metric{
-				"some": {
+				"gauge:some": {
 					ID:    "some",
 					MType: model.Gauge,
 					Delta: nil,
@@ -87,7 +90,7 @@ func TestUpdate(t *testing.T) {
 		{
 			name: "add gauge metric",
 			metrics: map[string]model.Metric{
-				"some": {
+				"gauge:some": {
 					ID:    "some",
 					MType: model.Gauge,
 					Delta: nil,
@@ -101,13 +104,13 @@ func TestUpdate(t *testing.T) {
 				Value: helper.NewFloat64(t, 8),
 			},
 			expectedMetrics: map[string]model.Metric{
-				"some": {
+				"gauge:some": {
 					ID:    "some",
 					MType: model.Gauge,
 					Delta: nil,
 					Value: helper.NewFloat64(t, 5),
 				},
-				"other": {
+				"gauge:other": {
 					ID:    "other",
 					MType: model.Gauge,
 					Delta: nil,
@@ -118,7 +121,7 @@ func TestUpdate(t *testing.T) {
 		{
 			name: "update gauge metric",
 			metrics: map[string]model.Metric{
-				"some": {
+				"gauge:some": {
 					ID:    "some",
 					MType: model.Gauge,
 					Delta: nil,
@@ -132,7 +135,7 @@ func TestUpdate(t *testing.T) {
 				Value: helper.NewFloat64(t, 8),
 			},
 			expectedMetrics: map[string]model.Metric{
-				"some": {
+				"gauge:some": {
 					ID:    "some",
 					MType: model.Gauge,
 					Delta: nil,
@@ -143,7 +146,7 @@ func TestUpdate(t *testing.T) {
 		{
 			name: "update counter metric",
 			metrics: map[string]model.Metric{
-				"some": {
+				"counter:some": {
 					ID:    "some",
 					MType: model.Counter,
 					Delta: helper.NewInt64(t, 5),
@@ -157,7 +160,7 @@ func TestUpdate(t *testing.T) {
 				Value: nil,
 			},
 			expectedMetrics: map[string]model.Metric{
-				"some": {
+				"counter:some": {
 					ID:    "some",
 					MType: model.Counter,
 					Delta: helper.NewInt64(t, 13),
@@ -167,19 +170,28 @@ func TestUpdate(t *testing.T) {
 		},
 	}
 
+	now := time.Unix(1700000000, 0)
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			ms := New()
+			ms := New(0)
+			ms.now = func() time.Time { return now }
 			ms.metrics = tc.metrics
 			err := ms.Update(tc.updatedModel)
 			require.NoError(t, err)
+			// время обновления метрики без временной метки - текущее время хранилища
+			key := tc.updatedModel.Key()
+			updated := tc.expectedMetrics[key]
+			updated.Timestamp = now
+			tc.expectedMetrics[key] = updated
 			assert.Equal(t, tc.expectedMetrics, ms.metrics)
 		})
 	}
 }
 
 func TestGetCounter(t *testing.T) {
-	ms := New()
+	ms := New(0)
+	now := time.Unix(1700000000, 0)
+	ms.now = func() time.Time { return now }
 	err := ms.Update(model.Metric{
 		ID:    "some",
 		MType: model.Counter,
@@ -201,26 +213,30 @@ func TestGetCounter(t *testing.T) {
 		Value: nil,
 	})
 	require.NoError(t, err)
-	m, err := ms.Get(model.Counter, "some")
+	m, err := ms.Get(model.Counter, "some", nil)
 	require.NoError(t, err)
 	assert.Equal(t, &model.Metric{
-		ID:    "some",
-		MType: model.Counter,
-		Delta: helper.NewInt64(t, 4),
-		Value: nil,
+		ID:        "some",
+		MType:     model.Counter,
+		Delta:     helper.NewInt64(t, 4),
+		Value:     nil,
+		Timestamp: now,
 	}, m)
-	m, err = ms.Get(model.Counter, "other")
+	m, err = ms.Get(model.Counter, "other", nil)
 	require.NoError(t, err)
 	assert.Equal(t, &model.Metric{
-		ID:    "other",
-		MType: model.Counter,
-		Delta: helper.NewInt64(t, 2),
-		Value: nil,
+		ID:        "other",
+		MType:     model.Counter,
+		Delta:     helper.NewInt64(t, 2),
+		Value:     nil,
+		Timestamp: now,
 	}, m)
 }
 
 func TestGetGauge(t *testing.T) {
-	ms := New()
+	ms := New(0)
+	now := time.Unix(1700000000, 0)
+	ms.now = func() time.Time { return now }
 	err := ms.Update(model.Metric{
 		ID:    "some",
 		MType: model.Gauge,
@@ -242,26 +258,30 @@ func TestGetGauge(t *testing.T) {
 		Value: helper.NewFloat64(t, 3),
 	})
 	require.NoError(t, err)
-	m, err := ms.Get(model.Gauge, "some")
+	m, err := ms.Get(model.Gauge, "some", nil)
 	require.NoError(t, err)
 	assert.Equal(t, &model.Metric{
-		ID:    "some",
-		MType: model.Gauge,
-		Delta: nil,
-		Value: helper.NewFloat64(t, 3),
+		ID:        "some",
+		MType:     model.Gauge,
+		Delta:     nil,
+		Value:     helper.NewFloat64(t, 3),
+		Timestamp: now,
 	}, m)
-	m, err = ms.Get(model.Gauge, "other")
+	m, err = ms.Get(model.Gauge, "other", nil)
 	require.NoError(t, err)
 	assert.Equal(t, &model.Metric{
-		ID:    "other",
-		MType: model.Gauge,
-		Delta: nil,
-		Value: helper.NewFloat64(t, 2),
+		ID:        "other",
+		MType:     model.Gauge,
+		Delta:     nil,
+		Value:     helper.NewFloat64(t, 2),
+		Timestamp: now,
 	}, m)
 }
 
 func TestList(t *testing.T) {
-	ms := New()
+	ms := New(0)
+	now := time.Unix(1700000000, 0)
+	ms.now = func() time.Time { return now }
 	err := ms.Update(model.Metric{
 		ID:    "some",
 		MType: model.Counter,
@@ -279,17 +299,264 @@ func TestList(t *testing.T) {
 	m := ms.List()
 	require.NoError(t, err)
 	assert.Equal(t, map[string]model.Metric{
-		"some": {
-			ID:    "some",
-			MType: model.Counter,
-			Delta: helper.NewInt64(t, 1),
-			Value: nil,
+		"counter:some": {
+			ID:        "some",
+			MType:     model.Counter,
+			Delta:     helper.NewInt64(t, 1),
+			Value:     nil,
+			Timestamp: now,
 		},
-		"other": {
-			ID:    "other",
-			MType: model.Gauge,
-			Delta: nil,
-			Value: helper.NewFloat64(t, 88),
+		"gauge:other": {
+			ID:        "other",
+			MType:     model.Gauge,
+			Delta:     nil,
+			Value:     helper.NewFloat64(t, 88),
+			Timestamp: now,
 		},
 	}, m)
+
+	// без обновлений возвращается тот же снимок, после обновления - новый,
+	// полученный ранее снимок не меняется
+	assert.Equal(t, reflect.ValueOf(m).Pointer(), reflect.ValueOf(ms.List()).Pointer())
+	require.NoError(t, ms.Update(model.Metric{ID: "new", MType: model.Gauge, Value: helper.NewFloat64(t, 1)}))
+	assert.Len(t, ms.List(), 3)
+	assert.Len(t, m, 2)
+}
+
+func TestLabels(t *testing.T) {
+	ms := New(0)
+	for _, host := range []string{"a", "b", "a"} {
+		err := ms.Update(model.Metric{
+			ID:     "some",
+			MType:  model.Counter,
+			Labels: model.NewLabels("host", host),
+			Delta:  helper.NewInt64(t, 1),
+		})
+		require.NoError(t, err)
+	}
+
+	m, err := ms.Get(model.Counter, "some", model.NewLabels("host", "a"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), *m.Delta)
+	m, err = ms.Get(model.Counter, "some", model.NewLabels("host", "b"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), *m.Delta)
+	_, err = ms.Get(model.Counter, "some", nil)
+	require.ErrorIs(t, err, model.ErrMetricNotFound)
+}
+
+func TestUpdateInvalid(t *testing.T) {
+	ms := New(0)
+	for _, m := range []model.Metric{
+		{ID: "some", MType: model.Histogram},
+		{ID: "some", MType: model.ExponentialHistogram},
+		{ID: "some", MType: model.Summary},
+		{ID: "some", MType: model.Counter},
+		{ID: "some", MType: model.Gauge},
+	} {
+		require.ErrorIs(t, ms.Update(m), model.ErrInvalidMetric, m.MType)
+	}
+	assert.Empty(t, ms.List())
+}
+
+func TestHistogram(t *testing.T) {
+	ms := New(0)
+	ts := time.Unix(1700000000, 0)
+
+	err := ms.Update(model.Metric{ID: "latency", MType: model.Histogram, Value: helper.NewFloat64(t, 0.3), Timestamp: ts})
+	require.NoError(t, err)
+	m, err := ms.Get(model.Histogram, "latency", nil)
+	require.NoError(t, err)
+	assert.Equal(t, model.DefaultBuckets, m.Histogram.Bounds)
+	assert.Nil(t, m.Value)
+
+	err = ms.Update(model.Metric{
+		ID:    "latency",
+		MType: model.Histogram,
+		Histogram: &model.HistogramData{
+			Bounds: model.DefaultBuckets,
+			Counts: []uint64{2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+			Sum:    20.002,
+			Count:  3,
+		},
+		Timestamp: ts.Add(time.Second),
+	})
+	require.NoError(t, err)
+	m, err = ms.Get(model.Histogram, "latency", nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), m.Histogram.Count)
+	assert.InDelta(t, 20.302, m.Histogram.Sum, 1e-9)
+
+	err = ms.Update(model.Metric{
+		ID:        "latency",
+		MType:     model.Histogram,
+		Histogram: &model.HistogramData{Bounds: []float64{1}, Counts: []uint64{1, 0}, Count: 1},
+	})
+	require.ErrorIs(t, err, model.ErrInvalidHistogram)
+
+	samples, err := ms.Range(
+		model.Histogram, "latency_bucket", model.NewLabels(model.LabelLe, "0.5"),
+		ts, ts.Add(time.Minute), 0,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Sample{
+		{Timestamp: ts, Value: 1},
+		{Timestamp: ts.Add(time.Second), Value: 3},
+	}, samples)
+}
+
+func TestExponentialHistogram(t *testing.T) {
+	ms := New(0)
+	// гистограммы от двух агентов с разными схемами
+	a, _ := model.NewExponentialHistogram(model.ExponentialMaxSchema)
+	b, _ := model.NewExponentialHistogram(0)
+	for v := 1; v <= 100; v++ {
+		a.Observe(float64(v))
+		b.Observe(float64(v) * 100)
+	}
+	for _, h := range []*model.ExponentialHistogramData{a, b} {
+		err := ms.Update(model.Metric{ID: "latency", MType: model.ExponentialHistogram, ExponentialHistogram: h})
+		require.NoError(t, err)
+	}
+	err := ms.Update(model.Metric{ID: "latency", MType: model.ExponentialHistogram, Value: helper.NewFloat64(t, 0)})
+	require.NoError(t, err)
+
+	m, err := ms.Get(model.ExponentialHistogram, "latency", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), m.ExponentialHistogram.Schema)
+	assert.Equal(t, uint64(201), m.ExponentialHistogram.Count)
+	assert.Equal(t, uint64(1), m.ExponentialHistogram.ZeroCount)
+	assert.InEpsilon(t, 100, m.ExponentialHistogram.Quantile(0.5), 1)
+	assert.Equal(t, uint64(100), a.Count)
+}
+
+func TestSummary(t *testing.T) {
+	ms := New(0)
+	// скетчи от нескольких экземпляров сервиса
+	for i := range 3 {
+		s, _ := model.NewSummary(model.DefaultRelativeAccuracy)
+		for v := 1; v <= 100; v++ {
+			s.Observe(float64(i*100 + v))
+		}
+		err := ms.Update(model.Metric{ID: "latency", MType: model.Summary, Summary: s})
+		require.NoError(t, err)
+	}
+	err := ms.Update(model.Metric{ID: "latency", MType: model.Summary, Value: helper.NewFloat64(t, 301)})
+	require.NoError(t, err)
+
+	m, err := ms.Get(model.Summary, "latency", nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(301), m.Summary.Count)
+	assert.InEpsilon(t, 298, m.Summary.Quantile(0.99), model.DefaultRelativeAccuracy)
+
+	other, _ := model.NewSummary(0.05)
+	err = ms.Update(model.Metric{ID: "latency", MType: model.Summary, Summary: other})
+	require.ErrorIs(t, err, model.ErrInvalidSummary)
+}
+
+func TestSet(t *testing.T) {
+	ms := New(0)
+	for _, member := range []string{"alice", "bob", "alice"} {
+		err := ms.Update(model.Metric{ID: "users", MType: model.Set, Members: []string{member}})
+		require.NoError(t, err)
+	}
+	// множество от другого агента
+	other, _ := model.NewSet(model.DefaultSetPrecision)
+	other.Add("bob")
+	other.Add("carol")
+	err := ms.Update(model.Metric{ID: "users", MType: model.Set, Set: other})
+	require.NoError(t, err)
+
+	m, err := ms.Get(model.Set, "users", nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), m.Set.Estimate())
+	assert.Nil(t, m.Members)
+	assert.Equal(t, uint64(2), other.Estimate())
+
+	err = ms.Update(model.Metric{ID: "users", MType: model.Set, Set: &model.SetData{Precision: 4}})
+	require.ErrorIs(t, err, model.ErrInvalidSet)
+}
+
+func TestMetadata(t *testing.T) {
+	s := New(0)
+	_, err := s.Metadata("Alloc")
+	assert.ErrorIs(t, err, model.ErrMetadataNotFound)
+
+	md := model.Metadata{Type: model.Gauge, Help: "Bytes of allocated heap objects.", Unit: "bytes"}
+	require.NoError(t, s.UpdateMetadata("Alloc", md))
+	// метаданные заменяются целиком
+	require.NoError(t, s.UpdateMetadata("Alloc", model.Metadata{Help: "Allocated."}))
+
+	got, err := s.Metadata("Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, model.Metadata{Help: "Allocated."}, *got)
+	assert.Equal(t, map[string]model.Metadata{"Alloc": {Help: "Allocated."}}, s.ListMetadata())
+}
+
+// benchSeriesCounts - количество рядов в хранилище: текущий объем и рост в 10 раз
+var benchSeriesCounts = []int{1000, 10000}
+
+// benchStorage - хранилище с n рядами gauge
+func benchStorage(b *testing.B, n int) *MemStorage {
+	b.Helper()
+	s := New(time.Minute)
+	for i := range n {
+		v := float64(i)
+		require.NoError(b, s.Update(model.Metric{ID: "gauge", MType: model.Gauge, Labels: benchLabels(i), Value: &v}))
+	}
+	return s
+}
+
+func benchLabels(i int) model.Labels {
+	return model.NewLabels("instance", strconv.Itoa(i))
+}
+
+func BenchmarkUpdate(b *testing.B) {
+	for _, n := range benchSeriesCounts {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			s := benchStorage(b, n)
+			labels := make([]model.Labels, n)
+			for i := range labels {
+				labels[i] = benchLabels(i)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				v := float64(i)
+				if err := s.Update(model.Metric{ID: "gauge", MType: model.Gauge, Labels: labels[i%n], Value: &v}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkList - List без обновлений между вызовами (read) и с обновлением одного ряда
+// перед каждым вызовом (update)
+func BenchmarkList(b *testing.B) {
+	for _, n := range benchSeriesCounts {
+		for _, update := range []bool{false, true} {
+			name := strconv.Itoa(n) + "/read"
+			if update {
+				name = strconv.Itoa(n) + "/update"
+			}
+			b.Run(name, func(b *testing.B) {
+				s := benchStorage(b, n)
+				labels := benchLabels(0)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := range b.N {
+					if update {
+						v := float64(i)
+						if err := s.Update(model.Metric{ID: "gauge", MType: model.Gauge, Labels: labels, Value: &v}); err != nil {
+							b.Fatal(err)
+						}
+					}
+					if len(s.List()) != n {
+						b.Fatal("unexpected series count")
+					}
+				}
+			})
+		}
+	}
 }
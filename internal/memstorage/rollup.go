@@ -0,0 +1,184 @@
+package memstorage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+// Tier - уровень прореживания истории: агрегаты значений
+// за интервалы Resolution хранятся не дольше Retention
+type Tier struct {
+	Resolution time.Duration
+	Retention  time.Duration
+}
+
+// DefaultTiers - уровни прореживания по умолчанию,
+// часовые агрегаты позволяют сравнивать данные год к году
+var DefaultTiers = []Tier{
+	{Resolution: time.Minute, Retention: 7 * 24 * time.Hour},
+	{Resolution: 5 * time.Minute, Retention: 30 * 24 * time.Hour},
+	{Resolution: time.Hour, Retention: 400 * 24 * time.Hour},
+}
+
+var ErrInvalidTier = errors.New("invalid rollup tier")
+
+// ParseTier - уровень прореживания в формате <resolution>=<retention>, например 5m=720h
+func ParseTier(s string) (Tier, error) {
+	resolution, retention, ok := strings.Cut(s, "=")
+	if !ok {
+		return Tier{}, fmt.Errorf("%w: %q, expected <resolution>=<retention>", ErrInvalidTier, s)
+	}
+	var t Tier
+	var err error
+	if t.Resolution, err = time.ParseDuration(resolution); err != nil || t.Resolution < time.Second {
+		return Tier{}, fmt.Errorf("%w: incorrect resolution %q", ErrInvalidTier, resolution)
+	}
+	if t.Retention, err = time.ParseDuration(retention); err != nil || t.Retention < 0 {
+		return Tier{}, fmt.Errorf("%w: incorrect retention %q", ErrInvalidTier, retention)
+	}
+	return t, nil
+}
+
+// rollupHistory - агрегаты значений ряда одного уровня прореживания.
+// Каждый агрегат хранится в отдельной сжатой истории с общими метками времени -
+// началом интервала.
+type rollupHistory struct {
+	Tier
+	// compacted - конец последнего свернутого интервала
+	compacted time.Time
+
+	min, max, sum, count, last history
+}
+
+func (r *rollupHistory) columns() []*history {
+	return []*history{&r.min, &r.max, &r.sum, &r.count, &r.last}
+}
+
+func (r *rollupHistory) append(s model.Sample) {
+	values := []float64{s.Rollup.Min, s.Rollup.Max, s.Rollup.Sum, float64(s.Rollup.Count), s.Value}
+	for i, h := range r.columns() {
+		h.append(model.Sample{Timestamp: s.Timestamp, Value: values[i]})
+	}
+}
+
+func (r *rollupHistory) truncate(before time.Time) {
+	for _, h := range r.columns() {
+		h.truncate(before)
+	}
+}
+
+// rangeSamples - агрегаты интервалов, начинающихся в [from, to]
+func (r *rollupHistory) rangeSamples(from, to time.Time) ([]model.Sample, error) {
+	columns := r.columns()
+	its := make([]*historyIterator, len(columns))
+	for i, h := range columns {
+		its[i] = h.iterator(from, to)
+	}
+
+	var res []model.Sample
+	for its[0].Next() {
+		values := make([]float64, len(its))
+		values[0] = its[0].At().Value
+		for i := 1; i < len(its); i++ {
+			if !its[i].Next() {
+				return nil, fmt.Errorf("rollup columns length mismatch: %w", its[i].Err())
+			}
+			values[i] = its[i].At().Value
+		}
+		res = append(res, model.Sample{
+			Timestamp: its[0].At().Timestamp,
+			Value:     values[4],
+			Rollup: &model.Rollup{
+				Min:   values[0],
+				Max:   values[1],
+				Sum:   values[2],
+				Count: uint64(values[3]),
+			},
+		})
+	}
+	for _, it := range its {
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// compact - сворачивает интервалы истории h, завершенные к моменту now.
+// Значения, пришедшие позже в уже свернутые интервалы, в агрегаты не попадают.
+func (r *rollupHistory) compact(h *history, now time.Time) error {
+	end := now.Truncate(r.Resolution)
+	start := r.compacted
+	if start.IsZero() {
+		if len(h.chunks) == 0 {
+			return nil
+		}
+		start = time.UnixMilli(h.chunks[0].minT).Truncate(r.Resolution)
+	}
+	if !start.Before(end) {
+		return nil
+	}
+
+	samples, err := rollup(h.iterator(start, end.Add(-time.Millisecond)), r.Resolution)
+	if err != nil {
+		return err
+	}
+	for _, s := range samples {
+		r.append(s)
+	}
+	r.compacted = end
+	if r.Retention > 0 {
+		r.truncate(now.Add(-r.Retention))
+	}
+	return nil
+}
+
+// rollup - агрегаты значений итератора по интервалам resolution
+func rollup(it *historyIterator, resolution time.Duration) ([]model.Sample, error) {
+	var res []model.Sample
+	for it.Next() {
+		s := it.At()
+		start := s.Timestamp.Truncate(resolution)
+		r := model.Rollup{Min: s.Value, Max: s.Value, Sum: s.Value, Count: 1}
+		if n := len(res); n > 0 && res[n-1].Timestamp.Equal(start) {
+			r = res[n-1].Rollup.Merge(r)
+			res[n-1].Rollup = &r
+			res[n-1].Value = s.Value
+			continue
+		}
+		res = append(res, model.Sample{Timestamp: start, Value: s.Value, Rollup: &r})
+	}
+	return res, it.Err()
+}
+
+// downsample - оставляет не больше одного значения на каждое окно
+// [from + k*step, from + (k+1)*step): последнее значение окна,
+// агрегаты прореженной истории при этом объединяются.
+func downsample(samples []model.Sample, from time.Time, step time.Duration) []model.Sample {
+	if step <= 0 {
+		return samples
+	}
+	res := samples[:0]
+	windowEnd := from.Add(step)
+	for _, s := range samples {
+		if len(res) > 0 && s.Timestamp.Before(windowEnd) {
+			last := &res[len(res)-1]
+			if last.Rollup != nil && s.Rollup != nil {
+				r := last.Rollup.Merge(*s.Rollup)
+				s.Rollup = &r
+				s.Timestamp = last.Timestamp
+			}
+			*last = s
+			continue
+		}
+		// конец окна значения вычисляется сразу: перебор окон при малом шаге занял бы
+		// время, пропорциональное интервалу, под блокировкой хранилища
+		windowEnd = from.Add((s.Timestamp.Sub(from)/step + 1) * step)
+		res = append(res, s)
+	}
+	return res
+}
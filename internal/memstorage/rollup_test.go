@@ -0,0 +1,153 @@
+package memstorage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/test/helper"
+)
+
+func TestParseTier(t *testing.T) {
+	tier, err := ParseTier("5m=720h")
+	require.NoError(t, err)
+	assert.Equal(t, Tier{Resolution: 5 * time.Minute, Retention: 720 * time.Hour}, tier)
+
+	for _, s := range []string{"", "5m", "5m=", "x=1h", "1ms=1h", "1m=-1h"} {
+		_, err := ParseTier(s)
+		require.ErrorIs(t, err, ErrInvalidTier, s)
+	}
+}
+
+func TestRollupRange(t *testing.T) {
+	start := time.Unix(1700000000, 0).Truncate(time.Hour)
+	now := start
+	ms := New(time.Hour, Tier{Resolution: 5 * time.Minute}, Tier{Resolution: time.Minute})
+	ms.now = func() time.Time { return now }
+
+	// значение каждые 30 секунд в течение 10 минут: 0, 1, ..., 19
+	for i := range 20 {
+		now = start.Add(time.Duration(i) * 30 * time.Second)
+		err := ms.Update(model.Metric{ID: "some", MType: model.Gauge, Value: helper.NewFloat64(t, float64(i))})
+		require.NoError(t, err)
+		if i == 12 {
+			// свернуты первые 6 минут, остальное вычисляется при запросе
+			require.NoError(t, ms.Compact())
+		}
+	}
+
+	rollup := func(minute int, vMin, vMax, sum float64, count uint64) model.Sample {
+		return model.Sample{
+			Timestamp: start.Add(time.Duration(minute) * time.Minute),
+			Value:     vMax,
+			Rollup:    &model.Rollup{Min: vMin, Max: vMax, Sum: sum, Count: count},
+		}
+	}
+
+	testCases := []struct {
+		name            string
+		step            time.Duration
+		expectedSamples []model.Sample
+	}{
+		{
+			name: "minute tier",
+			step: 2 * time.Minute,
+			expectedSamples: []model.Sample{
+				rollup(0, 0, 3, 6, 4),
+				rollup(2, 4, 7, 22, 4),
+				rollup(4, 8, 11, 38, 4),
+				rollup(6, 12, 15, 54, 4),
+				rollup(8, 16, 19, 70, 4),
+			},
+		},
+		{
+			name: "five minutes tier",
+			step: 5 * time.Minute,
+			expectedSamples: []model.Sample{
+				rollup(0, 0, 9, 45, 10),
+				rollup(5, 10, 19, 145, 10),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			samples, err := ms.Range(model.Gauge, "some", nil, start, now, tc.step)
+			require.NoError(t, err)
+			require.Len(t, samples, len(tc.expectedSamples))
+			for i, s := range samples {
+				assert.True(t, tc.expectedSamples[i].Timestamp.Equal(s.Timestamp), i)
+				assert.Equal(t, tc.expectedSamples[i].Value, s.Value, i)
+				assert.Equal(t, tc.expectedSamples[i].Rollup, s.Rollup, i)
+			}
+		})
+	}
+
+	samples, err := ms.Range(model.Gauge, "some", nil, start, now, 30*time.Second)
+	require.NoError(t, err)
+	assert.Len(t, samples, 20)
+	assert.Nil(t, samples[0].Rollup)
+}
+
+func TestRollupRetention(t *testing.T) {
+	start := time.Unix(1700000000, 0).Truncate(time.Hour)
+	now := start
+	ms := New(time.Minute, Tier{Resolution: time.Minute, Retention: 10 * time.Minute})
+	ms.now = func() time.Time { return now }
+
+	for i := range 300 {
+		now = start.Add(time.Duration(i) * time.Minute)
+		err := ms.Update(model.Metric{ID: "some", MType: model.Counter, Delta: helper.NewInt64(t, 1)})
+		require.NoError(t, err)
+		require.NoError(t, ms.Compact())
+	}
+
+	samples, err := ms.Range(model.Counter, "some", nil, start, now, time.Minute)
+	require.NoError(t, err)
+	// устаревшие агрегаты удаляются целыми блоками: остается последний блок
+	// с 240-й минуты и последняя минута, еще не свернутая
+	require.Len(t, samples, 60)
+	assert.Equal(t, 241.0, samples[0].Value)
+	assert.Equal(t, 300.0, samples[59].Value)
+}
+
+func TestRangeTier(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ms := New(time.Minute,
+		Tier{Resolution: time.Minute, Retention: 10 * time.Minute},
+		Tier{Resolution: 5 * time.Minute, Retention: time.Hour},
+	)
+	ms.now = func() time.Time { return now }
+	require.NoError(t, ms.Update(model.Metric{ID: "some", MType: model.Counter, Delta: helper.NewInt64(t, 1)}))
+	rollups := ms.rollups[model.SeriesKey(model.Counter, "some", nil)]
+
+	testCases := []struct {
+		name               string
+		from               time.Duration
+		step               time.Duration
+		expectedResolution time.Duration
+	}{
+		{name: "raw history", from: 30 * time.Second},
+		{name: "coarsest tier not greater than step", from: 5 * time.Minute, step: 10 * time.Minute, expectedResolution: 5 * time.Minute},
+		{name: "minute tier", from: 5 * time.Minute, step: 2 * time.Minute, expectedResolution: time.Minute},
+		// минутные агрегаты за последние 30 минут уже удалены
+		{name: "finest tier covering from", from: 30 * time.Minute, step: time.Minute, expectedResolution: 5 * time.Minute},
+		{name: "finest tier covering from without step", from: 5 * time.Minute, expectedResolution: time.Minute},
+		{name: "longest retention", from: 2 * time.Hour, step: time.Minute, expectedResolution: 5 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tier := ms.rangeTier(rollups, now.Add(-tc.from), tc.step)
+			if tc.expectedResolution == 0 {
+				assert.Nil(t, tier)
+				return
+			}
+			require.NotNil(t, tier)
+			assert.Equal(t, tc.expectedResolution, tier.Resolution)
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package memstorage
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+// xorChunk - блок значений ряда, сжатый по схеме Facebook Gorilla:
+// метки времени (в миллисекундах) кодируются разностью разностей,
+// значения - XOR с предыдущим значением.
+type xorChunk struct {
+	b   bstream
+	num uint16
+
+	// состояние последнего записанного значения
+	minT     int64
+	t        int64
+	tDelta   int64
+	v        float64
+	leading  uint8
+	trailing uint8
+}
+
+func newXORChunk() *xorChunk {
+	return &xorChunk{
+		b:       bstream{stream: make([]byte, 0, chunkBytesHint)},
+		leading: math.MaxUint8,
+	}
+}
+
+// chunkBytesHint - начальный размер буфера блока,
+// для регулярного ряда 120 значений обычно занимают меньше
+const chunkBytesHint = 128
+
+func (c *xorChunk) append(t int64, v float64) {
+	switch c.num {
+	case 0:
+		c.minT = t
+		c.b.writeBits(uint64(t), 64) //nolint:gosec // сохраняется битовое представление
+		c.b.writeBits(math.Float64bits(v), 64)
+	case 1:
+		c.tDelta = t - c.t
+		c.writeUvarint(uint64(c.tDelta)) //nolint:gosec // значения упорядочены по времени
+		c.writeValue(v)
+	default:
+		tDelta := t - c.t
+		c.writeDoD(tDelta - c.tDelta)
+		c.tDelta = tDelta
+		c.writeValue(v)
+	}
+	c.t = t
+	c.v = v
+	c.num++
+}
+
+// size - размер сжатых данных в байтах
+func (c *xorChunk) size() int {
+	return len(c.b.stream)
+}
+
+func (c *xorChunk) writeUvarint(u uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	for _, byt := range buf[:binary.PutUvarint(buf[:], u)] {
+		c.b.writeByte(byt)
+	}
+}
+
+// writeDoD - разность разностей меток времени кодируется префиксом
+// переменной длины, для регулярных рядов обычно достаточно одного бита
+func (c *xorChunk) writeDoD(dod int64) {
+	u := uint64(dod) //nolint:gosec // сохраняется битовое представление
+	switch {
+	case dod == 0:
+		c.b.writeBit(false)
+	case fitsBits(dod, 14):
+		c.b.writeBits(0b10, 2)
+		c.b.writeBits(u, 14)
+	case fitsBits(dod, 17):
+		c.b.writeBits(0b110, 3)
+		c.b.writeBits(u, 17)
+	case fitsBits(dod, 20):
+		c.b.writeBits(0b1110, 4)
+		c.b.writeBits(u, 20)
+	default:
+		c.b.writeBits(0b1111, 4)
+		c.b.writeBits(u, 64)
+	}
+}
+
+// writeValue - XOR с предыдущим значением. Если значащие биты помещаются
+// в окно предыдущего значения - пишутся только они, иначе новое окно
+// (5 бит ведущих нулей и 6 бит длины) и значащие биты.
+func (c *xorChunk) writeValue(v float64) {
+	delta := math.Float64bits(v) ^ math.Float64bits(c.v)
+	if delta == 0 {
+		c.b.writeBit(false)
+		return
+	}
+	c.b.writeBit(true)
+
+	leading := uint8(min(bits.LeadingZeros64(delta), 31)) //nolint:gosec // не больше 31
+	trailing := uint8(bits.TrailingZeros64(delta))        //nolint:gosec // не больше 63
+	if c.leading != math.MaxUint8 && leading >= c.leading && trailing >= c.trailing {
+		c.b.writeBit(false)
+		c.b.writeBits(delta>>c.trailing, 64-int(c.leading)-int(c.trailing))
+		return
+	}
+
+	c.leading, c.trailing = leading, trailing
+	sigbits := 64 - int(leading) - int(trailing)
+	c.b.writeBit(true)
+	c.b.writeBits(uint64(leading), 5)
+	// длина 64 не помещается в 6 бит и записывается как 0
+	c.b.writeBits(uint64(sigbits), 6) //nolint:gosec // от 1 до 64
+	c.b.writeBits(delta>>trailing, sigbits)
+}
+
+// fitsBits - помещается ли x в nbits бит в дополнительном коде
+func fitsBits(x int64, nbits uint) bool {
+	return -(1<<(nbits-1)) <= x && x <= 1<<(nbits-1)-1
+}
+
+// iterator - итератор, декодирующий значения блока по мере чтения
+func (c *xorChunk) iterator() *xorIterator {
+	return &xorIterator{
+		br:  newBReader(&c.b),
+		num: c.num,
+	}
+}
+
+type xorIterator struct {
+	br   bstreamReader
+	num  uint16
+	read uint16
+
+	t        int64
+	tDelta   int64
+	v        float64
+	leading  uint8
+	trailing uint8
+	err      error
+}
+
+func (it *xorIterator) Next() bool {
+	if it.err != nil || it.read == it.num {
+		return false
+	}
+	switch it.read {
+	case 0:
+		t, err := it.br.readBits(64)
+		if err != nil {
+			return it.fail(err)
+		}
+		v, err := it.br.readBits(64)
+		if err != nil {
+			return it.fail(err)
+		}
+		it.t = int64(t) //nolint:gosec // восстанавливается битовое представление
+		it.v = math.Float64frombits(v)
+	case 1:
+		tDelta, err := it.readUvarint()
+		if err != nil {
+			return it.fail(err)
+		}
+		it.tDelta = int64(tDelta) //nolint:gosec // восстанавливается битовое представление
+		it.t += it.tDelta
+		if err := it.readValue(); err != nil {
+			return it.fail(err)
+		}
+	default:
+		dod, err := it.readDoD()
+		if err != nil {
+			return it.fail(err)
+		}
+		it.tDelta += dod
+		it.t += it.tDelta
+		if err := it.readValue(); err != nil {
+			return it.fail(err)
+		}
+	}
+	it.read++
+	return true
+}
+
+// At - метка времени в миллисекундах и значение текущего элемента
+func (it *xorIterator) At() (int64, float64) {
+	return it.t, it.v
+}
+
+func (it *xorIterator) Err() error {
+	return it.err
+}
+
+func (it *xorIterator) fail(err error) bool {
+	it.err = err
+	return false
+}
+
+func (it *xorIterator) readUvarint() (uint64, error) {
+	var buf [binary.MaxVarintLen64]byte
+	for i := range buf {
+		byt, err := it.br.readBits(8)
+		if err != nil {
+			return 0, err
+		}
+		buf[i] = byte(byt)
+		if byt < 0x80 {
+			u, _ := binary.Uvarint(buf[:i+1])
+			return u, nil
+		}
+	}
+	return 0, errEndOfStream
+}
+
+func (it *xorIterator) readDoD() (int64, error) {
+	// количество единиц в префиксе определяет длину значения
+	var nbits int
+	for _, n := range []int{0, 14, 17, 20} {
+		bit, err := it.br.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if !bit {
+			nbits = n
+			break
+		}
+		nbits = 64
+	}
+	if nbits == 0 {
+		return 0, nil
+	}
+	u, err := it.br.readBits(nbits)
+	if err != nil {
+		return 0, err
+	}
+	// восстановление знака
+	shift := uint(64 - nbits)            //nolint:gosec // от 0 до 50
+	return int64(u<<shift) >> shift, nil //nolint:gosec // восстанавливается битовое представление
+}
+
+func (it *xorIterator) readValue() error {
+	changed, err := it.br.readBit()
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	newWindow, err := it.br.readBit()
+	if err != nil {
+		return err
+	}
+	if newWindow {
+		leading, err := it.br.readBits(5)
+		if err != nil {
+			return err
+		}
+		sigbits, err := it.br.readBits(6)
+		if err != nil {
+			return err
+		}
+		if sigbits == 0 {
+			sigbits = 64
+		}
+		it.leading = uint8(leading)                 //nolint:gosec // не больше 31
+		it.trailing = uint8(64 - leading - sigbits) //nolint:gosec // не больше 63
+	}
+	sigbits := 64 - int(it.leading) - int(it.trailing)
+	delta, err := it.br.readBits(sigbits)
+	if err != nil {
+		return err
+	}
+	it.v = math.Float64frombits(math.Float64bits(it.v) ^ delta<<it.trailing)
+	return nil
+}
@@ -0,0 +1,151 @@
+package memstorage
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+type testSample struct {
+	t int64
+	v float64
+}
+
+func TestXORChunk(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2)) //nolint:gosec // детерминированные данные для теста
+	specials := []float64{0, math.Copysign(0, -1), math.Inf(1), math.Inf(-1), math.NaN(), math.MaxFloat64}
+
+	var samples []testSample
+	ts := int64(1700000000000)
+	v := 0.0
+	for i := range chunkSize {
+		// регулярный шаг, небольшой и очень большой разброс меток времени
+		switch i % 4 {
+		case 0:
+			ts += 10000
+		case 1:
+			ts += 10000 + r.Int64N(100)
+		case 2:
+			ts += r.Int64N(1 << 30)
+		case 3:
+			ts += 1
+		}
+		switch i % 3 {
+		case 0:
+			v = float64(r.IntN(1000))
+		case 1:
+			v += r.NormFloat64()
+		case 2:
+			v = specials[r.IntN(len(specials))]
+		}
+		samples = append(samples, testSample{t: ts, v: v})
+	}
+
+	c := newXORChunk()
+	for _, s := range samples {
+		c.append(s.t, s.v)
+	}
+
+	it := c.iterator()
+	var got []testSample
+	for it.Next() {
+		tt, vv := it.At()
+		got = append(got, testSample{t: tt, v: vv})
+	}
+	require.NoError(t, it.Err())
+	require.Len(t, got, len(samples))
+	for i := range samples {
+		assert.Equal(t, samples[i].t, got[i].t, i)
+		assert.Equal(t, math.Float64bits(samples[i].v), math.Float64bits(got[i].v), i)
+	}
+}
+
+func TestXORChunkRegularSize(t *testing.T) {
+	c := newXORChunk()
+	for i := range chunkSize {
+		c.append(1700000000000+int64(i)*10000, 42)
+	}
+	// 16 байт первого значения, далее по 2 бита на значение
+	assert.Less(t, c.size(), 16+chunkSize/2)
+}
+
+// benchSeries - ряды для бенчмарков с отправкой раз в 10 секунд
+var benchSeries = []struct {
+	name     string
+	generate func(n int) []testSample
+}{
+	{
+		// счетчик с целыми приращениями, метки времени без разброса
+		name: "counter",
+		generate: func(n int) []testSample {
+			r := rand.New(rand.NewPCG(1, 2)) //nolint:gosec // детерминированные данные для бенчмарка
+			samples := make([]testSample, n)
+			v := 0.0
+			for i := range samples {
+				v += float64(r.IntN(100))
+				samples[i] = testSample{t: 1700000000000 + int64(i)*10000, v: v}
+			}
+			return samples
+		},
+	},
+	{
+		// gauge с точностью до сотых, разброс меток времени до 2 мс
+		name: "gauge",
+		generate: func(n int) []testSample {
+			r := rand.New(rand.NewPCG(1, 2)) //nolint:gosec // детерминированные данные для бенчмарка
+			samples := make([]testSample, n)
+			ts := int64(1700000000000)
+			v := 50.0
+			for i := range samples {
+				ts += 10000 + r.Int64N(3)
+				v = math.Round((v+r.NormFloat64())*100) / 100
+				samples[i] = testSample{t: ts, v: v}
+			}
+			return samples
+		},
+	},
+}
+
+func BenchmarkHistoryAppend(b *testing.B) {
+	for _, bs := range benchSeries {
+		b.Run(bs.name, func(b *testing.B) {
+			samples := bs.generate(b.N)
+			h := &history{}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				h.append(model.Sample{Timestamp: time.UnixMilli(samples[i].t), Value: samples[i].v})
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(h.size())/float64(b.N), "bytes/sample")
+			// несжатое значение - 8 байт времени и 8 байт значения
+			b.ReportMetric(16*float64(b.N)/float64(h.size()), "ratio")
+		})
+	}
+}
+
+func BenchmarkHistoryIterate(b *testing.B) {
+	samples := benchSeries[1].generate(100 * chunkSize)
+	h := &history{}
+	for _, s := range samples {
+		h.append(model.Sample{Timestamp: time.UnixMilli(s.t), Value: s.v})
+	}
+	from := time.UnixMilli(samples[0].t)
+	to := time.UnixMilli(samples[len(samples)-1].t)
+	b.ReportAllocs()
+	b.ResetTimer()
+	var n int
+	for range b.N {
+		it := h.iterator(from, to)
+		for it.Next() {
+			n++
+		}
+	}
+	b.ReportMetric(float64(n)/b.Elapsed().Seconds(), "samples/s")
+}
@@ -0,0 +1,323 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// Допустимые схемы экспоненциальной гистограммы, как у нативных гистограмм Prometheus
+const (
+	ExponentialMinSchema = -4
+	ExponentialMaxSchema = 8
+	// ExponentialMaxBuckets - максимальное количество корзин отдельно для положительных
+	// и для отрицательных значений, при превышении схема гистограммы уменьшается
+	ExponentialMaxBuckets = 160
+)
+
+// LabelQuantile - метка рядов с оценками квантилей
+const LabelQuantile = "quantile"
+
+// DefaultQuantiles - квантили, сохраняемые в истории и выводимые в текстовом формате
+var DefaultQuantiles = []float64{0.5, 0.9, 0.99}
+
+// ExponentialBuckets - непрерывный диапазон корзин, начинающийся с индекса Offset
+type ExponentialBuckets struct {
+	Offset int32    `json:"offset"`
+	Counts []uint64 `json:"counts,omitempty"`
+}
+
+// ExponentialHistogramData - разреженная экспоненциальная гистограмма (аналог экспоненциальных
+// гистограмм OpenTelemetry и нативных гистограмм Prometheus).
+// Основание base = 2^(2^-Schema), корзина с индексом i содержит значения из (base^i, base^(i+1)].
+// Отрицательные значения учитываются по модулю в Negative, значения, не превышающие
+// по модулю ZeroThreshold, - в ZeroCount.
+type ExponentialHistogramData struct {
+	Schema        int32              `json:"schema"`
+	ZeroThreshold float64            `json:"zero_threshold"`
+	ZeroCount     uint64             `json:"zero_count"`
+	Positive      ExponentialBuckets `json:"positive"`
+	Negative      ExponentialBuckets `json:"negative"`
+	Sum           float64            `json:"sum"`
+	Count         uint64             `json:"count"`
+}
+
+// NewExponentialHistogram - пустая гистограмма со схемой schema
+func NewExponentialHistogram(schema int32) (*ExponentialHistogramData, error) {
+	h := &ExponentialHistogramData{Schema: schema}
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Validate - схема в допустимых пределах, количество корзин не превышает ExponentialMaxBuckets,
+// индексы корзин соответствуют значениям float64, Sum конечна, Count равен сумме всех корзин
+func (h *ExponentialHistogramData) Validate() error {
+	if h.Schema < ExponentialMinSchema || h.Schema > ExponentialMaxSchema {
+		return fmt.Errorf("%w: schema %d out of range [%d, %d]",
+			ErrInvalidHistogram, h.Schema, ExponentialMinSchema, ExponentialMaxSchema)
+	}
+	if math.IsNaN(h.ZeroThreshold) || math.IsInf(h.ZeroThreshold, 0) || h.ZeroThreshold < 0 {
+		return fmt.Errorf("%w: incorrect zero threshold %v", ErrInvalidHistogram, h.ZeroThreshold)
+	}
+	if len(h.Positive.Counts) > ExponentialMaxBuckets || len(h.Negative.Counts) > ExponentialMaxBuckets {
+		return fmt.Errorf("%w: more than %d buckets", ErrInvalidHistogram, ExponentialMaxBuckets)
+	}
+	if lo, hi := exponentialIndexRange(h.Schema); !h.Positive.inRange(lo, hi) || !h.Negative.inRange(lo, hi) {
+		return fmt.Errorf("%w: bucket indexes out of range [%d, %d]", ErrInvalidHistogram, lo, hi)
+	}
+	if math.IsNaN(h.Sum) || math.IsInf(h.Sum, 0) {
+		return fmt.Errorf("%w: incorrect sum %v", ErrInvalidHistogram, h.Sum)
+	}
+	count := h.ZeroCount + h.Positive.count() + h.Negative.count()
+	if count != h.Count {
+		return fmt.Errorf("%w: count %d does not match bucket counts %d", ErrInvalidHistogram, h.Count, count)
+	}
+	return nil
+}
+
+// Observe - добавляет наблюдение, бесконечности и NaN не учитываются
+func (h *ExponentialHistogramData) Observe(v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return
+	}
+	h.Sum += v
+	h.Count++
+	switch {
+	case math.Abs(v) <= h.ZeroThreshold:
+		h.ZeroCount++
+	case v > 0:
+		h.Positive.add(exponentialIndex(v, h.Schema), 1)
+	default:
+		h.Negative.add(exponentialIndex(-v, h.Schema), 1)
+	}
+	h.limit()
+}
+
+// Merge - новая гистограмма с наблюдениями обеих гистограмм. Результат имеет меньшую из двух схем
+// и больший из двух порогов нуля, при необходимости схема уменьшается до ExponentialMaxBuckets корзин.
+func (h *ExponentialHistogramData) Merge(other *ExponentialHistogramData) *ExponentialHistogramData {
+	res, o := h.Clone(), other.Clone()
+	schema := min(res.Schema, o.Schema)
+	res.Downscale(schema)
+	o.Downscale(schema)
+	threshold := max(res.ZeroThreshold, o.ZeroThreshold)
+	res.widenZero(threshold)
+	o.widenZero(threshold)
+
+	res.ZeroCount += o.ZeroCount
+	res.Positive.merge(o.Positive)
+	res.Negative.merge(o.Negative)
+	res.Sum += o.Sum
+	res.Count += o.Count
+	res.limit()
+	return res
+}
+
+// Downscale - уменьшает схему до schema, объединяя соседние корзины
+func (h *ExponentialHistogramData) Downscale(schema int32) {
+	if schema >= h.Schema {
+		return
+	}
+	by := h.Schema - schema
+	h.Positive = h.Positive.downscale(by)
+	h.Negative = h.Negative.downscale(by)
+	h.Schema = schema
+}
+
+// Quantile - оценка квантиля q из [0, 1] по корзинам, внутри корзины значение
+// интерполируется экспоненциально, внутри нулевой корзины - линейно.
+// Для пустой гистограммы - NaN.
+func (h *ExponentialHistogramData) Quantile(q float64) float64 {
+	if h.Count == 0 || math.IsNaN(q) {
+		return math.NaN()
+	}
+	rank := min(max(q, 0), 1) * float64(h.Count)
+	var seen float64
+	// отрицательные значения - от больших по модулю к меньшим
+	for i := len(h.Negative.Counts) - 1; i >= 0; i-- {
+		c := float64(h.Negative.Counts[i])
+		if c > 0 && seen+c >= rank {
+			lower, upper := h.bucketBounds(h.Negative.Offset + int32(i)) //nolint:gosec // количество корзин ограничено
+			return -upper * math.Pow(lower/upper, (rank-seen)/c)
+		}
+		seen += c
+	}
+	if c := float64(h.ZeroCount); c > 0 && seen+c >= rank {
+		return h.ZeroThreshold * (2*(rank-seen)/c - 1)
+	}
+	seen += float64(h.ZeroCount)
+	for i, cnt := range h.Positive.Counts {
+		c := float64(cnt)
+		if c > 0 && seen+c >= rank {
+			lower, upper := h.bucketBounds(h.Positive.Offset + int32(i)) //nolint:gosec // количество корзин ограничено
+			return lower * math.Pow(upper/lower, (rank-seen)/c)
+		}
+		seen += c
+	}
+	// ранг за пределами корзин возможен только из-за погрешности вычислений
+	if n := len(h.Positive.Counts); n > 0 {
+		_, upper := h.bucketBounds(h.Positive.Offset + int32(n) - 1) //nolint:gosec // количество корзин ограничено
+		return upper
+	}
+	return h.ZeroThreshold
+}
+
+func (h *ExponentialHistogramData) Clone() *ExponentialHistogramData {
+	res := *h
+	res.Positive.Counts = slices.Clone(h.Positive.Counts)
+	res.Negative.Counts = slices.Clone(h.Negative.Counts)
+	return &res
+}
+
+// Series - ряды гистограммы: оценки квантилей DefaultQuantiles с меткой quantile,
+// <name>_sum и <name>_count
+func (h *ExponentialHistogramData) Series(name string, labels Labels) []SeriesValue {
+	return quantileSeries(name, labels, h.Quantile, h.Sum, h.Count)
+}
+
+// quantileSeries - ряды в формате summary Prometheus
+func quantileSeries(name string, labels Labels, quantile func(float64) float64, sum float64, count uint64) []SeriesValue {
+	res := make([]SeriesValue, 0, len(DefaultQuantiles)+2)
+	for _, q := range DefaultQuantiles {
+		res = append(res, SeriesValue{
+			Name:   name,
+			Labels: labels.Merge(NewLabels(LabelQuantile, strconv.FormatFloat(q, 'f', -1, 64))),
+			Value:  quantile(q),
+		})
+	}
+	return append(res,
+		SeriesValue{Name: name + HistogramSumSuffix, Labels: labels, Value: sum},
+		SeriesValue{Name: name + HistogramCountSuffix, Labels: labels, Value: float64(count)},
+	)
+}
+
+// bucketBounds - границы значений (по модулю) корзины с индексом idx
+func (h *ExponentialHistogramData) bucketBounds(idx int32) (float64, float64) {
+	return exponentialBound(idx, h.Schema), exponentialBound(idx+1, h.Schema)
+}
+
+// limit - уменьшает схему, пока количество корзин превышает ExponentialMaxBuckets
+func (h *ExponentialHistogramData) limit() {
+	by := max(h.Positive.scaleReduction(), h.Negative.scaleReduction())
+	h.Downscale(max(h.Schema-by, ExponentialMinSchema))
+}
+
+// widenZero - увеличивает порог нуля, корзины, целиком лежащие ниже порога, переносятся в ZeroCount
+func (h *ExponentialHistogramData) widenZero(threshold float64) {
+	if threshold <= h.ZeroThreshold {
+		return
+	}
+	h.ZeroThreshold = threshold
+	h.ZeroCount += h.Positive.removeBelow(threshold, h.Schema) + h.Negative.removeBelow(threshold, h.Schema)
+}
+
+// exponentialIndex - индекс корзины для положительного значения v
+func exponentialIndex(v float64, schema int32) int32 {
+	if schema > 0 {
+		return int32(math.Ceil(math.Log2(v)*math.Ldexp(1, int(schema)))) - 1
+	}
+	// для схем <= 0 индекс вычисляется точно по двоичной экспоненте:
+	// v = frac * 2^exp, frac из [0.5, 1)
+	frac, exp := math.Frexp(v)
+	idx := int32(exp) - 1 //nolint:gosec // экспонента float64 помещается в int32
+	if frac == 0.5 {
+		idx--
+	}
+	return idx >> -schema
+}
+
+// Двоичные логарифмы наименьшего (субнормального) и превышающего наибольшее значений float64
+const (
+	float64MinLog2 = -1074
+	float64MaxLog2 = 1024
+)
+
+// exponentialIndexRange - индексы корзин, в которые попадают конечные ненулевые значения float64.
+// Ограничение индексов не позволяет корзинам с далекими друг от друга индексами
+// занять при объединении гистограмм неограниченную память.
+func exponentialIndexRange(schema int32) (int32, int32) {
+	if schema > 0 {
+		return float64MinLog2<<schema - 1, float64MaxLog2<<schema - 1
+	}
+	return (float64MinLog2 - 1) >> -schema, (float64MaxLog2 - 1) >> -schema
+}
+
+// exponentialBound - нижняя граница корзины с индексом idx
+func exponentialBound(idx int32, schema int32) float64 {
+	return math.Exp2(math.Ldexp(float64(idx), -int(schema)))
+}
+
+func (b *ExponentialBuckets) add(idx int32, n uint64) {
+	if len(b.Counts) == 0 {
+		b.Offset = idx
+		b.Counts = []uint64{n}
+		return
+	}
+	if idx < b.Offset {
+		b.Counts = append(make([]uint64, b.Offset-idx), b.Counts...)
+		b.Offset = idx
+	}
+	if end := b.Offset + int32(len(b.Counts)); idx >= end { //nolint:gosec // количество корзин ограничено
+		b.Counts = append(b.Counts, make([]uint64, idx-end+1)...)
+	}
+	b.Counts[idx-b.Offset] += n
+}
+
+func (b *ExponentialBuckets) merge(other ExponentialBuckets) {
+	for i, c := range other.Counts {
+		if c > 0 {
+			b.add(other.Offset+int32(i), c) //nolint:gosec // количество корзин ограничено
+		}
+	}
+}
+
+func (b ExponentialBuckets) downscale(by int32) ExponentialBuckets {
+	var res ExponentialBuckets
+	for i, c := range b.Counts {
+		if c > 0 {
+			res.add((b.Offset+int32(i))>>by, c) //nolint:gosec // количество корзин ограничено
+		}
+	}
+	return res
+}
+
+// scaleReduction - на сколько нужно уменьшить схему, чтобы корзин стало не больше ExponentialMaxBuckets
+func (b ExponentialBuckets) scaleReduction() int32 {
+	if len(b.Counts) == 0 {
+		return 0
+	}
+	lo, hi := b.Offset, b.Offset+int32(len(b.Counts))-1 //nolint:gosec // количество корзин ограничено
+	var by int32
+	for (hi>>by)-(lo>>by)+1 > ExponentialMaxBuckets {
+		by++
+	}
+	return by
+}
+
+// removeBelow - удаляет корзины, верхняя граница которых не превышает threshold,
+// и возвращает количество наблюдений в них
+func (b *ExponentialBuckets) removeBelow(threshold float64, schema int32) uint64 {
+	var n uint64
+	for len(b.Counts) > 0 && exponentialBound(b.Offset+1, schema) <= threshold {
+		n += b.Counts[0]
+		b.Counts = b.Counts[1:]
+		b.Offset++
+	}
+	return n
+}
+
+// inRange - индексы всех корзин из [lo, hi]
+func (b ExponentialBuckets) inRange(lo, hi int32) bool {
+	return len(b.Counts) == 0 || b.Offset >= lo && int64(b.Offset)+int64(len(b.Counts))-1 <= int64(hi)
+}
+
+func (b ExponentialBuckets) count() uint64 {
+	var n uint64
+	for _, c := range b.Counts {
+		n += c
+	}
+	return n
+}
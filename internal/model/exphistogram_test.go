@@ -0,0 +1,154 @@
+package model
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExponentialIndex(t *testing.T) {
+	testCases := []struct {
+		v        float64
+		schema   int32
+		expected int32
+	}{
+		{v: 1, schema: 0, expected: -1},
+		{v: 2, schema: 0, expected: 0},
+		{v: 3, schema: 0, expected: 1},
+		{v: 4, schema: 0, expected: 1},
+		{v: 2, schema: 1, expected: 1},
+		{v: 1.5, schema: 1, expected: 1},
+		{v: 1.4, schema: 1, expected: 0},
+		{v: 4, schema: -1, expected: 0},
+		{v: 5, schema: -1, expected: 1},
+		{v: 0.25, schema: -1, expected: -2},
+	}
+	for _, tc := range testCases {
+		idx := exponentialIndex(tc.v, tc.schema)
+		assert.Equal(t, tc.expected, idx, "v=%v schema=%d", tc.v, tc.schema)
+		assert.Less(t, exponentialBound(idx, tc.schema), tc.v)
+		assert.LessOrEqual(t, tc.v, exponentialBound(idx+1, tc.schema))
+	}
+}
+
+func TestExponentialHistogramObserve(t *testing.T) {
+	h, err := NewExponentialHistogram(ExponentialMaxSchema)
+	require.NoError(t, err)
+	for v := 1; v <= 10000; v++ {
+		h.Observe(float64(v))
+	}
+	h.Observe(0)
+	h.Observe(-3)
+	h.Observe(math.NaN())
+
+	require.NoError(t, h.Validate())
+	assert.Less(t, h.Schema, int32(ExponentialMaxSchema))
+	assert.LessOrEqual(t, len(h.Positive.Counts), ExponentialMaxBuckets)
+	assert.Equal(t, uint64(10002), h.Count)
+	assert.Equal(t, uint64(1), h.ZeroCount)
+	assert.Equal(t, uint64(1), h.Negative.count())
+	assert.InDelta(t, 50005000-3, h.Sum, 1e-6)
+}
+
+func TestExponentialHistogramMerge(t *testing.T) {
+	a, _ := NewExponentialHistogram(3)
+	b, _ := NewExponentialHistogram(0)
+	expected, _ := NewExponentialHistogram(0)
+	for _, v := range []float64{0.1, 1, 1.5, 7} {
+		a.Observe(v)
+		expected.Observe(v)
+	}
+	for _, v := range []float64{-2, 3, 100} {
+		b.Observe(v)
+		expected.Observe(v)
+	}
+
+	res := a.Merge(b)
+	assert.Equal(t, expected, res)
+	assert.Equal(t, int32(3), a.Schema)
+	assert.Equal(t, uint64(4), a.Count)
+
+	// наблюдения ниже большего порога нуля переносятся в нулевую корзину
+	c := &ExponentialHistogramData{ZeroThreshold: 1}
+	c.Observe(0.5)
+	res = res.Merge(c)
+	require.NoError(t, res.Validate())
+	assert.InDelta(t, 1, res.ZeroThreshold, 0)
+	assert.Equal(t, uint64(3), res.ZeroCount)
+	assert.Equal(t, uint64(8), res.Count)
+}
+
+func TestExponentialHistogramQuantile(t *testing.T) {
+	h, _ := NewExponentialHistogram(ExponentialMaxSchema)
+	assert.True(t, math.IsNaN(h.Quantile(0.5)))
+
+	for v := 1; v <= 10000; v++ {
+		h.Observe(float64(v))
+	}
+	// относительная погрешность ограничена шириной корзины base - 1
+	relErr := math.Exp2(math.Ldexp(1, -int(h.Schema))) - 1
+	for _, q := range []float64{0.01, 0.5, 0.9, 0.99} {
+		assert.InEpsilon(t, q*10000, h.Quantile(q), relErr, "q=%v", q)
+	}
+
+	n, _ := NewExponentialHistogram(ExponentialMaxSchema)
+	for _, v := range []float64{-10, -1, 1, 10} {
+		n.Observe(v)
+	}
+	relErr = math.Exp2(math.Ldexp(1, -int(n.Schema))) - 1
+	assert.InEpsilon(t, -10, n.Quantile(0), relErr)
+	assert.InEpsilon(t, -1, n.Quantile(0.3), relErr)
+	assert.InEpsilon(t, 10, n.Quantile(1), relErr)
+}
+
+func TestExponentialHistogramValidate(t *testing.T) {
+	_, err := NewExponentialHistogram(ExponentialMaxSchema + 1)
+	require.ErrorIs(t, err, ErrInvalidHistogram)
+	h := &ExponentialHistogramData{Positive: ExponentialBuckets{Counts: []uint64{1}}, Count: 2}
+	require.ErrorIs(t, h.Validate(), ErrInvalidHistogram)
+}
+
+func TestExponentialIndexRange(t *testing.T) {
+	for schema := int32(ExponentialMinSchema); schema <= ExponentialMaxSchema; schema++ {
+		lo, hi := exponentialIndexRange(schema)
+		assert.Equal(t, lo, exponentialIndex(math.SmallestNonzeroFloat64, schema), "schema=%d", schema)
+		assert.Equal(t, hi, exponentialIndex(math.MaxFloat64, schema), "schema=%d", schema)
+	}
+}
+
+func TestExponentialHistogramValidateBuckets(t *testing.T) {
+	testCases := []struct {
+		name string
+		h    ExponentialHistogramData
+	}{
+		{
+			name: "positive offset below range",
+			h:    ExponentialHistogramData{Positive: ExponentialBuckets{Offset: math.MinInt32 + 5, Counts: []uint64{1}}, Count: 1},
+		},
+		{
+			name: "negative offset above range",
+			h:    ExponentialHistogramData{Negative: ExponentialBuckets{Offset: math.MaxInt32, Counts: []uint64{1, 1}}, Count: 2},
+		},
+		{
+			name: "last bucket above range",
+			h:    ExponentialHistogramData{Schema: 8, Positive: ExponentialBuckets{Offset: 1024<<8 - 1, Counts: []uint64{1, 1}}, Count: 2},
+		},
+		{
+			name: "infinite sum",
+			h:    ExponentialHistogramData{Positive: ExponentialBuckets{Counts: []uint64{1}}, Count: 1, Sum: math.Inf(1)},
+		},
+		{
+			name: "nan sum",
+			h:    ExponentialHistogramData{Positive: ExponentialBuckets{Counts: []uint64{1}}, Count: 1, Sum: math.NaN()},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.ErrorIs(t, tc.h.Validate(), ErrInvalidHistogram)
+		})
+	}
+	h := ExponentialHistogramData{Schema: 8, Positive: ExponentialBuckets{Offset: 1024<<8 - 2, Counts: []uint64{1, 1}}, Count: 2}
+	require.NoError(t, h.Validate())
+}
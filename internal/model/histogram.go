@@ -0,0 +1,135 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// DefaultBuckets - границы корзин гистограммы по умолчанию (секунды), как в клиенте Prometheus
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Суффиксы и метка рядов гистограммы в формате Prometheus
+const (
+	HistogramBucketSuffix = "_bucket"
+	HistogramSumSuffix    = "_sum"
+	HistogramCountSuffix  = "_count"
+	LabelLe               = "le"
+)
+
+var ErrInvalidHistogram = errors.New("invalid histogram")
+
+// HistogramData - гистограмма с фиксированными корзинами.
+// Bounds - возрастающие верхние границы корзин (включительно),
+// Counts - количество наблюдений в каждой корзине, последняя корзина - значения больше всех границ.
+type HistogramData struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Sum    float64   `json:"sum"`
+	Count  uint64    `json:"count"`
+}
+
+// NewHistogram - пустая гистограмма с границами bounds
+func NewHistogram(bounds []float64) (*HistogramData, error) {
+	h := &HistogramData{
+		Bounds: slices.Clone(bounds),
+		Counts: make([]uint64, len(bounds)+1),
+	}
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Validate - границы конечные и строго возрастают, количество корзин на одну больше
+// количества границ, Count равен сумме Counts
+func (h *HistogramData) Validate() error {
+	for i, b := range h.Bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) {
+			return fmt.Errorf("%w: bound %v must be finite", ErrInvalidHistogram, b)
+		}
+		if i > 0 && b <= h.Bounds[i-1] {
+			return fmt.Errorf("%w: bounds must be strictly increasing", ErrInvalidHistogram)
+		}
+	}
+	if len(h.Counts) != len(h.Bounds)+1 {
+		return fmt.Errorf("%w: expected %d counts, got %d", ErrInvalidHistogram, len(h.Bounds)+1, len(h.Counts))
+	}
+	var count uint64
+	for _, c := range h.Counts {
+		count += c
+	}
+	if count != h.Count {
+		return fmt.Errorf("%w: count %d does not match bucket counts %d", ErrInvalidHistogram, h.Count, count)
+	}
+	if math.IsNaN(h.Sum) || math.IsInf(h.Sum, 0) {
+		return fmt.Errorf("%w: incorrect sum %v", ErrInvalidHistogram, h.Sum)
+	}
+	return nil
+}
+
+// Observe - добавляет наблюдение, бесконечности и NaN не учитываются
+func (h *HistogramData) Observe(v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return
+	}
+	i, _ := slices.BinarySearch(h.Bounds, v)
+	h.Counts[i]++
+	h.Sum += v
+	h.Count++
+}
+
+// Merge - новая гистограмма с наблюдениями обеих гистограмм, границы должны совпадать
+func (h *HistogramData) Merge(other *HistogramData) (*HistogramData, error) {
+	if !slices.Equal(h.Bounds, other.Bounds) {
+		return nil, fmt.Errorf("%w: bounds mismatch", ErrInvalidHistogram)
+	}
+	res := h.Clone()
+	for i, c := range other.Counts {
+		res.Counts[i] += c
+	}
+	res.Sum += other.Sum
+	res.Count += other.Count
+	return res, nil
+}
+
+func (h *HistogramData) Clone() *HistogramData {
+	return &HistogramData{
+		Bounds: slices.Clone(h.Bounds),
+		Counts: slices.Clone(h.Counts),
+		Sum:    h.Sum,
+		Count:  h.Count,
+	}
+}
+
+// SeriesValue - значение одного из рядов, на которые раскладывается метрика
+type SeriesValue struct {
+	Name   string
+	Labels Labels
+	Value  float64
+}
+
+// Series - ряды гистограммы в формате Prometheus: <name>_bucket с накопленным
+// количеством наблюдений и меткой le для каждой границы и +Inf, <name>_sum и <name>_count
+func (h *HistogramData) Series(name string, labels Labels) []SeriesValue {
+	res := make([]SeriesValue, 0, len(h.Counts)+2)
+	var cumulative uint64
+	for i, c := range h.Counts {
+		cumulative += c
+		le := "+Inf"
+		if i < len(h.Bounds) {
+			le = strconv.FormatFloat(h.Bounds[i], 'f', -1, 64)
+		}
+		res = append(res, SeriesValue{
+			Name:   name + HistogramBucketSuffix,
+			Labels: labels.Merge(NewLabels(LabelLe, le)),
+			Value:  float64(cumulative),
+		})
+	}
+	return append(res,
+		SeriesValue{Name: name + HistogramSumSuffix, Labels: labels, Value: h.Sum},
+		SeriesValue{Name: name + HistogramCountSuffix, Labels: labels, Value: float64(h.Count)},
+	)
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramObserve(t *testing.T) {
+	h, err := NewHistogram([]float64{1, 5})
+	require.NoError(t, err)
+	for _, v := range []float64{0.5, 1, 3, 10, math.NaN(), math.Inf(1)} {
+		h.Observe(v)
+	}
+	assert.Equal(t, []uint64{2, 1, 1}, h.Counts)
+	assert.Equal(t, uint64(4), h.Count)
+	assert.InDelta(t, 14.5, h.Sum, 1e-9)
+	require.NoError(t, h.Validate())
+}
+
+func TestHistogramValidate(t *testing.T) {
+	_, err := NewHistogram([]float64{1, 1})
+	require.ErrorIs(t, err, ErrInvalidHistogram)
+	require.ErrorIs(t, (&HistogramData{Bounds: []float64{1}, Counts: []uint64{1}, Count: 1}).Validate(), ErrInvalidHistogram)
+	require.ErrorIs(t, (&HistogramData{Bounds: []float64{1}, Counts: []uint64{1, 1}, Count: 1}).Validate(), ErrInvalidHistogram)
+	require.ErrorIs(t, (&HistogramData{Counts: []uint64{1}, Sum: math.NaN(), Count: 1}).Validate(), ErrInvalidHistogram)
+
+	nan := math.NaN()
+	for _, mt := range []MetricType{Histogram, ExponentialHistogram, Summary} {
+		require.ErrorIs(t, (&Metric{ID: "latency", MType: mt, Value: &nan}).Validate(), ErrInvalidMetric)
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	a := &HistogramData{Bounds: []float64{1}, Counts: []uint64{1, 2}, Sum: 5, Count: 3}
+	b := &HistogramData{Bounds: []float64{1}, Counts: []uint64{3, 0}, Sum: 1, Count: 3}
+
+	res, err := a.Merge(b)
+	require.NoError(t, err)
+	assert.Equal(t, &HistogramData{Bounds: []float64{1}, Counts: []uint64{4, 2}, Sum: 6, Count: 6}, res)
+	assert.Equal(t, []uint64{1, 2}, a.Counts)
+
+	_, err = a.Merge(&HistogramData{Bounds: []float64{2}, Counts: []uint64{0, 0}})
+	require.ErrorIs(t, err, ErrInvalidHistogram)
+}
+
+func TestHistogramSeries(t *testing.T) {
+	h := &HistogramData{Bounds: []float64{0.5, 1}, Counts: []uint64{1, 2, 3}, Sum: 7, Count: 6}
+	assert.Equal(t, []SeriesValue{
+		{Name: "x_bucket", Labels: NewLabels("host", "a", "le", "0.5"), Value: 1},
+		{Name: "x_bucket", Labels: NewLabels("host", "a", "le", "1"), Value: 3},
+		{Name: "x_bucket", Labels: NewLabels("host", "a", "le", "+Inf"), Value: 6},
+		{Name: "x_sum", Labels: NewLabels("host", "a"), Value: 7},
+		{Name: "x_count", Labels: NewLabels("host", "a"), Value: 6},
+	}, h.Series("x", NewLabels("host", "a")))
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Labels - метки метрики (имя - значение)
+type Labels map[string]string
+
+var ErrInvalidLabel = errors.New("invalid label")
+
+// LabelParamPrefix - префикс параметра запроса HTTP API с меткой метрики: label.host=a - метка host
+const LabelParamPrefix = "label."
+
+// NewLabels - метки из пар имя, значение
+func NewLabels(pairs ...string) Labels {
+	if len(pairs)%2 != 0 {
+		panic("odd number of label pairs")
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+	l := make(Labels, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		l[pairs[i]] = pairs[i+1]
+	}
+	return l
+}
+
+// Validate - имя метки не должно быть пустым и содержать символы,
+// используемые при форматировании меток
+func (l Labels) Validate() error {
+	for name := range l {
+		if name == "" || strings.ContainsAny(name, `{}=,"`) {
+			return fmt.Errorf("%w: incorrect name %q", ErrInvalidLabel, name)
+		}
+	}
+	return nil
+}
+
+// Names - отсортированные имена меток
+func (l Labels) Names() []string {
+	return l.appendNames(make([]string, 0, len(l)))
+}
+
+func (l Labels) appendNames(names []string) []string {
+	for name := range l {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// maxStackLabels - количество меток, имена которых String сортирует без выделения памяти
+const maxStackLabels = 8
+
+// String - метки в формате {a="1",b="2"}, отсортированные по имени,
+// пустая строка, если меток нет
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+	return string(l.Append(nil))
+}
+
+// Append - метки в формате String, дописанные к b
+func (l Labels) Append(b []byte) []byte {
+	if len(l) == 0 {
+		return b
+	}
+	var buf [maxStackLabels]string
+	b = append(b, '{')
+	for i, name := range l.appendNames(buf[:0]) {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, name...)
+		b = append(b, '=')
+		b = strconv.AppendQuote(b, l[name])
+	}
+	return append(b, '}')
+}
+
+// Merge - новый набор меток, значения из other перекрывают значения из l
+func (l Labels) Merge(other Labels) Labels {
+	if len(l) == 0 && len(other) == 0 {
+		return nil
+	}
+	res := make(Labels, len(l)+len(other))
+	maps.Copy(res, l)
+	maps.Copy(res, other)
+	return res
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelsString(t *testing.T) {
+	assert.Empty(t, Labels(nil).String())
+	assert.Equal(t, `{a="1",b="x \"y\""}`, NewLabels("b", `x "y"`, "a", "1").String())
+	assert.Equal(t, `some{a="1"}`, string(NewLabels("a", "1").Append([]byte("some"))))
+}
+
+func TestSeriesKey(t *testing.T) {
+	assert.Equal(t,
+		SeriesKey(Gauge, "some", NewLabels("a", "1", "b", "2")),
+		SeriesKey(Gauge, "some", NewLabels("b", "2", "a", "1")),
+	)
+	assert.NotEqual(t, SeriesKey(Gauge, "some", nil), SeriesKey(Counter, "some", nil))
+	assert.NotEqual(t, SeriesKey(Gauge, "some", nil), SeriesKey(Gauge, "some", NewLabels("a", "1")))
+}
+
+func TestValidateName(t *testing.T) {
+	require.NoError(t, ValidateName("servers.host-1.cpu_usage"))
+	require.ErrorIs(t, ValidateName(""), ErrInvalidMetric)
+	// имя с метками совпало бы с ключом ряда some{a="1"}
+	require.ErrorIs(t, ValidateName(`some{a="1"}`), ErrInvalidMetric)
+	require.ErrorIs(t, ValidateName(`some"`), ErrInvalidMetric)
+	require.ErrorIs(t, ValidateName("some\nother"), ErrInvalidMetric)
+}
+
+func TestLabelsValidate(t *testing.T) {
+	require.NoError(t, NewLabels("host", "a{b}").Validate())
+	require.ErrorIs(t, NewLabels("", "a").Validate(), ErrInvalidLabel)
+	require.ErrorIs(t, NewLabels("a=b", "a").Validate(), ErrInvalidLabel)
+}
+
+func TestLabelsMerge(t *testing.T) {
+	assert.Nil(t, Labels(nil).Merge(nil))
+	assert.Equal(t, NewLabels("a", "1", "b", "3"), NewLabels("a", "1", "b", "2").Merge(NewLabels("b", "3")))
+}
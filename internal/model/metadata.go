@@ -0,0 +1,37 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Metadata - описание метрики, общее для всех ее рядов независимо от меток
+type Metadata struct {
+	// Type - тип метрики, необязательный
+	Type MetricType `json:"type,omitempty"`
+	// Help - описание метрики
+	Help string `json:"help,omitempty"`
+	// Unit - единица измерения, например bytes или seconds
+	Unit string `json:"unit,omitempty"`
+	// Owner - команда или сервис, ответственные за метрику
+	Owner string `json:"owner,omitempty"`
+}
+
+var ErrMetadataNotFound = errors.New("metadata not found")
+
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
+// Validate - тип, если задан, должен быть известен, единица измерения не содержит пробелов
+func (md *Metadata) Validate() error {
+	if md.Type != "" {
+		if _, err := NewMetricTypeFromString(string(md.Type)); err != nil {
+			return fmt.Errorf("%w: incorrect type %q", ErrInvalidMetadata, md.Type)
+		}
+	}
+	if strings.ContainsFunc(md.Unit, unicode.IsSpace) {
+		return fmt.Errorf("%w: incorrect unit %q", ErrInvalidMetadata, md.Unit)
+	}
+	return nil
+}
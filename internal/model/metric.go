@@ -3,29 +3,184 @@ package model
 import (
 	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
 )
 
 type MetricType string
 
 const (
-	Counter MetricType = "counter"
-	Gauge   MetricType = "gauge"
+	Counter   MetricType = "counter"
+	Gauge     MetricType = "gauge"
+	Histogram MetricType = "histogram"
+	// ExponentialHistogram - гистограмма с экспоненциальными корзинами, см. ExponentialHistogramData
+	ExponentialHistogram MetricType = "exponential_histogram"
+	// Summary - скетч для оценки квантилей, см. SummaryData
+	Summary MetricType = "summary"
+	// Set - оценка количества уникальных элементов, см. SetData
+	Set MetricType = "set"
 )
 
 func NewMetricTypeFromString(s string) (MetricType, error) {
 	switch MetricType(s) {
-	case Counter, Gauge:
+	case Counter, Gauge, Histogram, ExponentialHistogram, Summary, Set:
 		return MetricType(s), nil
 	default:
 		return MetricType(""), fmt.Errorf("incorrect metric type")
 	}
 }
 
+// Metric - метрика однозначно определяется именем, типом и набором меток.
+// Для гистограмм и summary при обновлении передается либо Histogram (ExponentialHistogram, Summary)
+// с наблюдениями, которые добавляются к сохраненным, либо Value - одно наблюдение.
+// Для множества передаются добавляемые элементы Members и/или множество Set для объединения.
 type Metric struct {
-	ID    string     `json:"id"`
-	MType MetricType `json:"type"`
-	Delta *int64     `json:"delta,omitempty"`
-	Value *float64   `json:"value,omitempty"`
+	ID     string     `json:"id"`
+	MType  MetricType `json:"type"`
+	Labels Labels     `json:"labels,omitempty"`
+	Delta  *int64     `json:"delta,omitempty"`
+	Value  *float64   `json:"value,omitempty"`
+	// Histogram - задается только для гистограммы
+	Histogram *HistogramData `json:"histogram,omitempty"`
+	// ExponentialHistogram - задается только для экспоненциальной гистограммы
+	ExponentialHistogram *ExponentialHistogramData `json:"exponential_histogram,omitempty"`
+	// Summary - задается только для summary
+	Summary *SummaryData `json:"summary,omitempty"`
+	// Members, Set - задаются только для множества
+	Members []string `json:"members,omitempty"`
+	Set     *SetData `json:"set,omitempty"`
+	// Timestamp - время значения, если не задано - используется время записи в хранилище
+	Timestamp time.Time `json:"-"`
+}
+
+// Key - идентификатор ряда метрики
+func (m *Metric) Key() string {
+	return SeriesKey(m.MType, m.ID, m.Labels)
+}
+
+// SeriesKey - идентификатор ряда по типу, имени и меткам,
+// не зависит от порядка добавления меток
+func SeriesKey(metricType MetricType, name string, labels Labels) string {
+	return string(metricType) + ":" + name + labels.String()
 }
 
 var ErrMetricNotFound = errors.New("metric not found")
+
+var ErrInvalidMetric = errors.New("invalid metric")
+
+// ValidateName - имя метрики не должно быть пустым и содержать символы, используемые
+// при форматировании меток в SeriesKey, и управляющие символы: иначе имя с "{" совпало бы
+// с ключом ряда другой метрики с метками
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: empty id", ErrInvalidMetric)
+	}
+	if strings.ContainsAny(name, `{}=,"`) || strings.ContainsFunc(name, unicode.IsControl) {
+		return fmt.Errorf("%w: incorrect name %q", ErrInvalidMetric, name)
+	}
+	return nil
+}
+
+// Validate - корректное имя и задано значение, соответствующее типу метрики
+func (m *Metric) Validate() error {
+	if err := ValidateName(m.ID); err != nil {
+		return err
+	}
+	switch m.MType {
+	case Counter:
+		if m.Delta == nil {
+			return fmt.Errorf("%w: no delta for counter %s", ErrInvalidMetric, m.ID)
+		}
+	case Gauge:
+		if m.Value == nil {
+			return fmt.Errorf("%w: no value for gauge %s", ErrInvalidMetric, m.ID)
+		}
+	case Histogram:
+		if m.Histogram != nil {
+			if err := m.Histogram.Validate(); err != nil {
+				return err
+			}
+		} else if m.Value == nil {
+			return fmt.Errorf("%w: no histogram or value for histogram %s", ErrInvalidMetric, m.ID)
+		}
+	case ExponentialHistogram:
+		if m.ExponentialHistogram != nil {
+			if err := m.ExponentialHistogram.Validate(); err != nil {
+				return err
+			}
+		} else if m.Value == nil {
+			return fmt.Errorf("%w: no histogram or value for histogram %s", ErrInvalidMetric, m.ID)
+		}
+	case Summary:
+		if m.Summary != nil {
+			if err := m.Summary.Validate(); err != nil {
+				return err
+			}
+		} else if m.Value == nil {
+			return fmt.Errorf("%w: no summary or value for summary %s", ErrInvalidMetric, m.ID)
+		}
+	case Set:
+		if m.Set != nil {
+			if err := m.Set.Validate(); err != nil {
+				return err
+			}
+		} else if len(m.Members) == 0 {
+			return fmt.Errorf("%w: no set or members for set %s", ErrInvalidMetric, m.ID)
+		}
+	default:
+		return fmt.Errorf("%w: incorrect type %q", ErrInvalidMetric, m.MType)
+	}
+	if m.observation() && (math.IsNaN(*m.Value) || math.IsInf(*m.Value, 0)) {
+		return fmt.Errorf("%w: observation %v for %s must be finite", ErrInvalidMetric, *m.Value, m.ID)
+	}
+	return m.Labels.Validate()
+}
+
+// observation - метрика передает одно наблюдение в Value для гистограммы или summary
+func (m *Metric) observation() bool {
+	switch m.MType {
+	case Histogram:
+		return m.Histogram == nil && m.Value != nil
+	case ExponentialHistogram:
+		return m.ExponentialHistogram == nil && m.Value != nil
+	case Summary:
+		return m.Summary == nil && m.Value != nil
+	default:
+		return false
+	}
+}
+
+// Quantile - оценка квантиля q для экспоненциальной гистограммы и summary
+func (m *Metric) Quantile(q float64) (float64, error) {
+	switch {
+	case m.MType == ExponentialHistogram && m.ExponentialHistogram != nil:
+		return m.ExponentialHistogram.Quantile(q), nil
+	case m.MType == Summary && m.Summary != nil:
+		return m.Summary.Quantile(q), nil
+	}
+	return 0, fmt.Errorf("%w: quantiles are not supported for %s", ErrInvalidMetric, m.MType)
+}
+
+// Series - ряды, на которые раскладывается значение метрики:
+// для counter, gauge и set - единственный ряд с именем метрики, для гистограмм и summary - см. HistogramData.Series,
+// ExponentialHistogramData.Series и SummaryData.Series
+func (m *Metric) Series() []SeriesValue {
+	sv := SeriesValue{Name: m.ID, Labels: m.Labels}
+	switch {
+	case m.MType == Histogram && m.Histogram != nil:
+		return m.Histogram.Series(m.ID, m.Labels)
+	case m.MType == ExponentialHistogram && m.ExponentialHistogram != nil:
+		return m.ExponentialHistogram.Series(m.ID, m.Labels)
+	case m.MType == Summary && m.Summary != nil:
+		return m.Summary.Series(m.ID, m.Labels)
+	case m.MType == Set && m.Set != nil:
+		sv.Value = float64(m.Set.Estimate())
+	case m.Delta != nil:
+		sv.Value = float64(*m.Delta)
+	case m.Value != nil:
+		sv.Value = *m.Value
+	}
+	return []SeriesValue{sv}
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+)
+
+// Sample - значение ряда метрики в момент времени.
+// Для counter хранится накопленное значение счетчика.
+type Sample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+	// Rollup - агрегаты значений за интервал, начинающийся в Timestamp,
+	// задан только для прореженной истории. Value при этом - последнее значение интервала.
+	Rollup *Rollup `json:"rollup,omitempty"`
+}
+
+// Rollup - агрегаты значений ряда за интервал
+type Rollup struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Sum   float64 `json:"sum"`
+	Count uint64  `json:"count"`
+}
+
+// Merge - объединяет агрегаты двух интервалов
+func (r Rollup) Merge(other Rollup) Rollup {
+	return Rollup{
+		Min:   min(r.Min, other.Min),
+		Max:   max(r.Max, other.Max),
+		Sum:   r.Sum + other.Sum,
+		Count: r.Count + other.Count,
+	}
+}
+
+// Series - ряд метрики с историей значений
+type Series struct {
+	ID      string     `json:"id"`
+	MType   MetricType `json:"type"`
+	Labels  Labels     `json:"labels,omitempty"`
+	Samples []Sample   `json:"samples"`
+}
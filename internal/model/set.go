@@ -0,0 +1,121 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// Точность HyperLogLog - количество бит хеша, определяющих номер регистра.
+// Стандартная ошибка оценки 1.04 / sqrt(2^precision), для точности по умолчанию - около 0.8%.
+const (
+	DefaultSetPrecision = 14
+	MinSetPrecision     = 4
+	MaxSetPrecision     = 18
+)
+
+var ErrInvalidSet = errors.New("invalid set")
+
+// SetData - HyperLogLog для оценки количества уникальных элементов множества.
+// Занимает 2^Precision байт независимо от количества элементов,
+// множества с одинаковой точностью объединяются без потери точности.
+type SetData struct {
+	Precision uint8  `json:"precision"`
+	Registers []byte `json:"registers"`
+}
+
+// NewSet - пустое множество с точностью precision
+func NewSet(precision uint8) (*SetData, error) {
+	if err := validateSetPrecision(precision); err != nil {
+		return nil, err
+	}
+	return &SetData{
+		Precision: precision,
+		Registers: make([]byte, 1<<precision),
+	}, nil
+}
+
+// Validate - точность в допустимых пределах, количество регистров соответствует точности
+func (s *SetData) Validate() error {
+	if err := validateSetPrecision(s.Precision); err != nil {
+		return err
+	}
+	if len(s.Registers) != 1<<s.Precision {
+		return fmt.Errorf("%w: expected %d registers, got %d", ErrInvalidSet, 1<<s.Precision, len(s.Registers))
+	}
+	return nil
+}
+
+func validateSetPrecision(precision uint8) error {
+	if precision < MinSetPrecision || precision > MaxSetPrecision {
+		return fmt.Errorf("%w: precision %d out of range [%d, %d]",
+			ErrInvalidSet, precision, MinSetPrecision, MaxSetPrecision)
+	}
+	return nil
+}
+
+// Add - добавляет элемент
+func (s *SetData) Add(member string) {
+	h := setHash(member)
+	idx := h >> (64 - s.Precision)
+	// к оставшимся битам добавляется единица, чтобы количество ведущих нулей было ограничено
+	rho := byte(bits.LeadingZeros64(h<<s.Precision|1<<(s.Precision-1)) + 1) //nolint:gosec // не больше 64
+	s.Registers[idx] = max(s.Registers[idx], rho)
+}
+
+// Merge - новое множество, объединяющее элементы обоих множеств, точности должны совпадать
+func (s *SetData) Merge(other *SetData) (*SetData, error) {
+	if s.Precision != other.Precision {
+		return nil, fmt.Errorf("%w: precision mismatch", ErrInvalidSet)
+	}
+	res := s.Clone()
+	for i, r := range other.Registers {
+		res.Registers[i] = max(res.Registers[i], r)
+	}
+	return res, nil
+}
+
+// Estimate - оценка количества уникальных элементов, для малых множеств
+// используется линейный подсчет по количеству пустых регистров
+func (s *SetData) Estimate() uint64 {
+	m := float64(len(s.Registers))
+	var sum float64
+	var zeros int
+	for _, r := range s.Registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(math.Round(estimate))
+}
+
+func (s *SetData) Clone() *SetData {
+	return &SetData{
+		Precision: s.Precision,
+		Registers: slices.Clone(s.Registers),
+	}
+}
+
+// setHash - хеш элемента, одинаковый во всех процессах, чтобы множества
+// от разных агентов можно было объединять
+func setHash(member string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(member))
+	// FNV плохо перемешивает старшие биты, поэтому дополнительно применяется финализатор splitmix64
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
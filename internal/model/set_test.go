@@ -0,0 +1,49 @@
+package model
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetEstimate(t *testing.T) {
+	s, err := NewSet(DefaultSetPrecision)
+	require.NoError(t, err)
+	assert.Zero(t, s.Estimate())
+
+	for _, n := range []int{10, 1000, 100000} {
+		s, _ := NewSet(DefaultSetPrecision)
+		for i := range n {
+			s.Add("user" + strconv.Itoa(i))
+			// повторные элементы не меняют оценку
+			s.Add("user" + strconv.Itoa(i))
+		}
+		assert.InEpsilon(t, n, s.Estimate(), 0.03, "n=%d", n)
+	}
+}
+
+func TestSetMerge(t *testing.T) {
+	a, _ := NewSet(DefaultSetPrecision)
+	b, _ := NewSet(DefaultSetPrecision)
+	for i := range 10000 {
+		a.Add("user" + strconv.Itoa(i))
+		b.Add("user" + strconv.Itoa(i+5000))
+	}
+
+	res, err := a.Merge(b)
+	require.NoError(t, err)
+	assert.InEpsilon(t, 15000, res.Estimate(), 0.03)
+	assert.InEpsilon(t, 10000, a.Estimate(), 0.03)
+
+	other, _ := NewSet(DefaultSetPrecision - 1)
+	_, err = a.Merge(other)
+	require.ErrorIs(t, err, ErrInvalidSet)
+}
+
+func TestSetValidate(t *testing.T) {
+	_, err := NewSet(MaxSetPrecision + 1)
+	require.ErrorIs(t, err, ErrInvalidSet)
+	require.ErrorIs(t, (&SetData{Precision: 4, Registers: make([]byte, 8)}).Validate(), ErrInvalidSet)
+}
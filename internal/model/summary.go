@@ -0,0 +1,182 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+const (
+	// DefaultRelativeAccuracy - относительная погрешность квантилей summary по умолчанию
+	DefaultRelativeAccuracy = 0.01
+	// SummaryMinRelativeAccuracy - наименьшая допустимая погрешность: при меньшей
+	// индексы корзин значений float64 не помещаются в int32
+	SummaryMinRelativeAccuracy = 1e-4
+	// SummaryMaxBuckets - максимальное количество корзин отдельно для положительных
+	// и для отрицательных значений, при превышении объединяются корзины наименьших по модулю значений
+	SummaryMaxBuckets = 2048
+	// summaryMinValue - значения, меньшие по модулю, учитываются как нулевые
+	summaryMinValue = 1e-9
+)
+
+var ErrInvalidSummary = errors.New("invalid summary")
+
+// SummaryData - скетч DDSketch для оценки квантилей с относительной погрешностью RelativeAccuracy.
+// Корзина с индексом i содержит значения из (gamma^(i-1), gamma^i], где
+// gamma = (1 + RelativeAccuracy) / (1 - RelativeAccuracy). Отрицательные значения
+// учитываются по модулю в Negative, близкие к нулю - в ZeroCount.
+// Скетчи объединяются без потери точности, если у них одинаковая RelativeAccuracy.
+type SummaryData struct {
+	RelativeAccuracy float64            `json:"relative_accuracy"`
+	ZeroCount        uint64             `json:"zero_count"`
+	Positive         ExponentialBuckets `json:"positive"`
+	Negative         ExponentialBuckets `json:"negative"`
+	Sum              float64            `json:"sum"`
+	Count            uint64             `json:"count"`
+}
+
+// NewSummary - пустой скетч с относительной погрешностью relativeAccuracy
+func NewSummary(relativeAccuracy float64) (*SummaryData, error) {
+	s := &SummaryData{RelativeAccuracy: relativeAccuracy}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate - погрешность из [SummaryMinRelativeAccuracy, 1), количество корзин не превышает SummaryMaxBuckets,
+// индексы корзин соответствуют значениям float64, Sum конечна, Count равен сумме всех корзин
+func (s *SummaryData) Validate() error {
+	if !(s.RelativeAccuracy >= SummaryMinRelativeAccuracy && s.RelativeAccuracy < 1) {
+		return fmt.Errorf("%w: relative accuracy %v must be in [%v, 1)",
+			ErrInvalidSummary, s.RelativeAccuracy, SummaryMinRelativeAccuracy)
+	}
+	if len(s.Positive.Counts) > SummaryMaxBuckets || len(s.Negative.Counts) > SummaryMaxBuckets {
+		return fmt.Errorf("%w: more than %d buckets", ErrInvalidSummary, SummaryMaxBuckets)
+	}
+	if lo, hi := s.indexRange(); !s.Positive.inRange(lo, hi) || !s.Negative.inRange(lo, hi) {
+		return fmt.Errorf("%w: bucket indexes out of range [%d, %d]", ErrInvalidSummary, lo, hi)
+	}
+	if math.IsNaN(s.Sum) || math.IsInf(s.Sum, 0) {
+		return fmt.Errorf("%w: incorrect sum %v", ErrInvalidSummary, s.Sum)
+	}
+	count := s.ZeroCount + s.Positive.count() + s.Negative.count()
+	if count != s.Count {
+		return fmt.Errorf("%w: count %d does not match bucket counts %d", ErrInvalidSummary, s.Count, count)
+	}
+	return nil
+}
+
+// Observe - добавляет наблюдение, бесконечности и NaN не учитываются
+func (s *SummaryData) Observe(v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return
+	}
+	s.Sum += v
+	s.Count++
+	switch {
+	case math.Abs(v) < summaryMinValue:
+		s.ZeroCount++
+	case v > 0:
+		s.Positive.add(s.index(v), 1)
+		s.Positive.collapse(SummaryMaxBuckets)
+	default:
+		s.Negative.add(s.index(-v), 1)
+		s.Negative.collapse(SummaryMaxBuckets)
+	}
+}
+
+// Merge - новый скетч с наблюдениями обоих скетчей, погрешности должны совпадать
+func (s *SummaryData) Merge(other *SummaryData) (*SummaryData, error) {
+	if s.RelativeAccuracy != other.RelativeAccuracy {
+		return nil, fmt.Errorf("%w: relative accuracy mismatch", ErrInvalidSummary)
+	}
+	res := s.Clone()
+	res.ZeroCount += other.ZeroCount
+	res.Positive.merge(other.Positive)
+	res.Positive.collapse(SummaryMaxBuckets)
+	res.Negative.merge(other.Negative)
+	res.Negative.collapse(SummaryMaxBuckets)
+	res.Sum += other.Sum
+	res.Count += other.Count
+	return res, nil
+}
+
+// Quantile - оценка квантиля q из [0, 1] с относительной погрешностью RelativeAccuracy
+// (кроме значений из объединенных корзин). Для пустого скетча - NaN.
+func (s *SummaryData) Quantile(q float64) float64 {
+	if s.Count == 0 || math.IsNaN(q) {
+		return math.NaN()
+	}
+	rank := min(max(q, 0), 1) * float64(s.Count-1)
+	var seen float64
+	// отрицательные значения - от больших по модулю к меньшим
+	for i := len(s.Negative.Counts) - 1; i >= 0; i-- {
+		seen += float64(s.Negative.Counts[i])
+		if seen > rank {
+			return -s.value(s.Negative.Offset + int32(i)) //nolint:gosec // количество корзин ограничено
+		}
+	}
+	seen += float64(s.ZeroCount)
+	if seen > rank {
+		return 0
+	}
+	for i, c := range s.Positive.Counts {
+		seen += float64(c)
+		if seen > rank {
+			return s.value(s.Positive.Offset + int32(i)) //nolint:gosec // количество корзин ограничено
+		}
+	}
+	return s.value(s.Positive.Offset + int32(len(s.Positive.Counts)) - 1) //nolint:gosec // количество корзин ограничено
+}
+
+func (s *SummaryData) Clone() *SummaryData {
+	res := *s
+	res.Positive.Counts = slices.Clone(s.Positive.Counts)
+	res.Negative.Counts = slices.Clone(s.Negative.Counts)
+	return &res
+}
+
+// Series - ряды в формате summary Prometheus: оценки квантилей DefaultQuantiles с меткой quantile,
+// <name>_sum и <name>_count
+func (s *SummaryData) Series(name string, labels Labels) []SeriesValue {
+	return quantileSeries(name, labels, s.Quantile, s.Sum, s.Count)
+}
+
+func (s *SummaryData) gamma() float64 {
+	return (1 + s.RelativeAccuracy) / (1 - s.RelativeAccuracy)
+}
+
+// index - индекс корзины для положительного значения v
+func (s *SummaryData) index(v float64) int32 {
+	return int32(math.Ceil(math.Log(v) / math.Log(s.gamma())))
+}
+
+// indexRange - индексы корзин, в которые попадают значения от summaryMinValue до math.MaxFloat64.
+// Корзины с индексами вне диапазона при объединении скетчей заняли бы неограниченную память.
+func (s *SummaryData) indexRange() (int32, int32) {
+	return s.index(summaryMinValue), s.index(math.MaxFloat64)
+}
+
+// value - оценка значений корзины с индексом idx, относительная погрешность
+// которой не превышает RelativeAccuracy для любого значения корзины
+func (s *SummaryData) value(idx int32) float64 {
+	g := s.gamma()
+	return 2 * math.Pow(g, float64(idx)) / (g + 1)
+}
+
+// collapse - объединяет корзины с наименьшими индексами, чтобы их осталось не больше limit
+func (b *ExponentialBuckets) collapse(limit int) {
+	n := len(b.Counts) - limit
+	if n <= 0 {
+		return
+	}
+	var merged uint64
+	for _, c := range b.Counts[:n] {
+		merged += c
+	}
+	b.Counts = b.Counts[n:]
+	b.Counts[0] += merged
+	b.Offset += int32(n) //nolint:gosec // количество корзин ограничено
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummaryQuantile(t *testing.T) {
+	s, err := NewSummary(DefaultRelativeAccuracy)
+	require.NoError(t, err)
+	assert.True(t, math.IsNaN(s.Quantile(0.5)))
+
+	r := rand.New(rand.NewPCG(1, 2)) //nolint:gosec // детерминированные данные для теста
+	values := make([]float64, 10000)
+	for i := range values {
+		values[i] = r.ExpFloat64() * 100
+		s.Observe(values[i])
+	}
+	require.NoError(t, s.Validate())
+	slices.Sort(values)
+	for _, q := range []float64{0, 0.5, 0.9, 0.99, 1} {
+		expected := values[int(q*float64(len(values)-1))]
+		assert.InEpsilon(t, expected, s.Quantile(q), DefaultRelativeAccuracy, "q=%v", q)
+	}
+}
+
+func TestSummaryNegativeAndZero(t *testing.T) {
+	s, _ := NewSummary(DefaultRelativeAccuracy)
+	for _, v := range []float64{-100, -1, 0, 1, 100} {
+		s.Observe(v)
+	}
+	assert.InEpsilon(t, -100, s.Quantile(0), DefaultRelativeAccuracy)
+	assert.InEpsilon(t, -1, s.Quantile(0.25), DefaultRelativeAccuracy)
+	assert.Zero(t, s.Quantile(0.5))
+	assert.InEpsilon(t, 100, s.Quantile(1), DefaultRelativeAccuracy)
+}
+
+func TestSummaryMerge(t *testing.T) {
+	a, _ := NewSummary(DefaultRelativeAccuracy)
+	b, _ := NewSummary(DefaultRelativeAccuracy)
+	expected, _ := NewSummary(DefaultRelativeAccuracy)
+	for v := 1; v <= 1000; v++ {
+		if v%2 == 0 {
+			a.Observe(float64(v))
+		} else {
+			b.Observe(float64(v))
+		}
+		expected.Observe(float64(v))
+	}
+
+	res, err := a.Merge(b)
+	require.NoError(t, err)
+	assert.Equal(t, expected.Positive, res.Positive)
+	assert.Equal(t, expected.Count, res.Count)
+	assert.InEpsilon(t, 990, res.Quantile(0.99), DefaultRelativeAccuracy)
+	assert.Equal(t, uint64(500), a.Count)
+
+	other, _ := NewSummary(0.05)
+	_, err = a.Merge(other)
+	require.ErrorIs(t, err, ErrInvalidSummary)
+}
+
+func TestSummaryCollapse(t *testing.T) {
+	s, _ := NewSummary(DefaultRelativeAccuracy)
+	for _, v := range []float64{1e-8, 1, 1e9, 1e12} {
+		s.Observe(v)
+	}
+	require.NoError(t, s.Validate())
+	assert.Len(t, s.Positive.Counts, SummaryMaxBuckets)
+	assert.Equal(t, uint64(4), s.Count)
+	// наибольшие значения сохраняют точность
+	assert.InEpsilon(t, 1e12, s.Quantile(1), DefaultRelativeAccuracy)
+
+	_, err := NewSummary(1)
+	require.ErrorIs(t, err, ErrInvalidSummary)
+}
+
+func TestSummaryValidate(t *testing.T) {
+	_, err := NewSummary(1e-12)
+	require.ErrorIs(t, err, ErrInvalidSummary)
+	s, err := NewSummary(SummaryMinRelativeAccuracy)
+	require.NoError(t, err)
+	s.Observe(math.MaxFloat64)
+	s.Observe(summaryMinValue)
+	require.NoError(t, s.Validate())
+
+	testCases := []struct {
+		name string
+		s    SummaryData
+	}{
+		{
+			name: "positive offset below range",
+			s: SummaryData{
+				RelativeAccuracy: DefaultRelativeAccuracy,
+				Positive:         ExponentialBuckets{Offset: math.MinInt32 + 5, Counts: []uint64{1}},
+				Count:            1,
+			},
+		},
+		{
+			name: "negative offset above range",
+			s: SummaryData{
+				RelativeAccuracy: DefaultRelativeAccuracy,
+				Negative:         ExponentialBuckets{Offset: math.MaxInt32, Counts: []uint64{1, 1}},
+				Count:            2,
+			},
+		},
+		{
+			name: "infinite sum",
+			s:    SummaryData{RelativeAccuracy: DefaultRelativeAccuracy, ZeroCount: 1, Count: 1, Sum: math.Inf(-1)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.ErrorIs(t, tc.s.Validate(), ErrInvalidSummary)
+		})
+	}
+}
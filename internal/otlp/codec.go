@@ -0,0 +1,51 @@
+package otlp
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeJSON     = "application/json"
+)
+
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// MediaType - тип содержимого без параметров, поддерживаются только protobuf и JSON кодировки OTLP
+func MediaType(contentType string) (string, error) {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+	}
+	if mt != ContentTypeProtobuf && mt != ContentTypeJSON {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedContentType, mt)
+	}
+	return mt, nil
+}
+
+func DecodeRequest(body []byte, mediaType string) (*colmetricspb.ExportMetricsServiceRequest, error) {
+	req := &colmetricspb.ExportMetricsServiceRequest{}
+	var err error
+	if mediaType == ContentTypeJSON {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, req)
+	} else {
+		err = proto.Unmarshal(body, req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode export request: %w", err)
+	}
+	return req, nil
+}
+
+func EncodeResponse(resp *colmetricspb.ExportMetricsServiceResponse, mediaType string) ([]byte, error) {
+	if mediaType == ContentTypeJSON {
+		return protojson.Marshal(resp)
+	}
+	return proto.Marshal(resp)
+}
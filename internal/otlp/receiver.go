@@ -0,0 +1,501 @@
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+// Storage - методы хранилища, необходимые для приема метрик.
+// ctx - контекст запроса, например для трассировки вызовов хранилища.
+type Storage interface {
+	Update(ctx context.Context, m model.Metric) error
+	Get(ctx context.Context, metricType model.MetricType, metricName string, labels model.Labels) (*model.Metric, error)
+}
+
+// Receiver - преобразует метрики OTLP в метрики хранилища.
+//
+// Sum с монотонным ростом сохраняются как counter: delta-точки добавляются как есть,
+// для cumulative-точек приращение вычисляется относительно предыдущего значения ряда.
+// Первая cumulative-точка ряда только запоминается как начальное значение: накопленное
+// до нее уже могло быть сохранено до перезапуска сервера. Ряды без точек дольше
+// staleAfter забываются.
+// Немонотонные Sum и Gauge сохраняются как gauge.
+// Гистограммы с явными границами сохраняются как histogram, экспоненциальные - как
+// exponential_histogram, для cumulative-точек так же вычисляется приращение.
+// Атрибуты ресурса и точки становятся метками метрики.
+//
+// todo: next sprints
+// точки Summary содержат готовые значения квантилей, которые нельзя объединить
+// со скетчами model.Summary, поэтому пока отклоняются.
+type Receiver struct {
+	storage                        Storage
+	cumulative                     map[string]cumulativePoint
+	cumulativeHistogram            map[string]cumulativeHistogramPoint
+	cumulativeExponentialHistogram map[string]cumulativeExponentialHistogramPoint
+	now                            func() time.Time
+	evicted                        time.Time
+	mu                             sync.Mutex
+}
+
+// staleAfter - время без точек, после которого начальное значение cumulative-ряда удаляется
+const staleAfter = time.Hour
+
+type cumulativePoint struct {
+	start uint64
+	value float64
+	seen  time.Time
+}
+
+type cumulativeHistogramPoint struct {
+	start uint64
+	value *model.HistogramData
+	seen  time.Time
+}
+
+type cumulativeExponentialHistogramPoint struct {
+	start uint64
+	value *model.ExponentialHistogramData
+	seen  time.Time
+}
+
+func NewReceiver(storage Storage) *Receiver {
+	return &Receiver{
+		storage:                        storage,
+		cumulative:                     make(map[string]cumulativePoint),
+		cumulativeHistogram:            make(map[string]cumulativeHistogramPoint),
+		cumulativeExponentialHistogram: make(map[string]cumulativeExponentialHistogramPoint),
+		now:                            time.Now,
+	}
+}
+
+// Export - сохраняет метрики из запроса и возвращает количество отклоненных точек
+// с описанием причины для partial_success ответа. Точки, отклоненные хранилищем,
+// не прерывают обработку: остальные точки запроса сохраняются.
+func (r *Receiver) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (int64, string) {
+	var rejected int64
+	var reasons []string
+
+	r.evictStale()
+	for _, rm := range req.GetResourceMetrics() {
+		resource := labels(rm.GetResource().GetAttributes())
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				if err := model.ValidateName(m.GetName()); err != nil {
+					rejected += dataPointsCount(m)
+					reasons = append(reasons, err.Error())
+					continue
+				}
+				var errs []error
+				switch data := m.GetData().(type) {
+				case *metricspb.Metric_Sum:
+					errs = r.sum(ctx, m.GetName(), resource, data.Sum)
+				case *metricspb.Metric_Gauge:
+					errs = r.gauge(ctx, m.GetName(), resource, data.Gauge.GetDataPoints())
+				case *metricspb.Metric_Histogram:
+					errs = r.histogram(ctx, m.GetName(), resource, data.Histogram)
+				case *metricspb.Metric_ExponentialHistogram:
+					errs = r.exponentialHistogram(ctx, m.GetName(), resource, data.ExponentialHistogram)
+				default:
+					n := dataPointsCount(m)
+					rejected += n
+					reasons = append(reasons, fmt.Sprintf("%s: unsupported metric data type %T", m.GetName(), data))
+					continue
+				}
+				if len(errs) > 0 {
+					// в причине - только первая ошибка метрики, точек с ошибками может быть много
+					rejected += int64(len(errs))
+					reasons = append(reasons, fmt.Sprintf("%s: %v", m.GetName(), errs[0]))
+				}
+			}
+		}
+	}
+
+	return rejected, strings.Join(reasons, "; ")
+}
+
+// evictStale - удаляет начальные значения рядов без точек дольше staleAfter,
+// проверка выполняется не чаще раза в staleAfter
+func (r *Receiver) evictStale() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if now.Sub(r.evicted) < staleAfter {
+		return
+	}
+	r.evicted = now
+	deadline := now.Add(-staleAfter)
+	maps.DeleteFunc(r.cumulative, func(_ string, p cumulativePoint) bool {
+		return p.seen.Before(deadline)
+	})
+	maps.DeleteFunc(r.cumulativeHistogram, func(_ string, p cumulativeHistogramPoint) bool {
+		return p.seen.Before(deadline)
+	})
+	maps.DeleteFunc(r.cumulativeExponentialHistogram, func(_ string, p cumulativeExponentialHistogramPoint) bool {
+		return p.seen.Before(deadline)
+	})
+}
+
+func (r *Receiver) sum(ctx context.Context, name string, resource model.Labels, sum *metricspb.Sum) []error {
+	cumulative := sum.GetAggregationTemporality() == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+
+	if !sum.GetIsMonotonic() {
+		if cumulative {
+			return r.gauge(ctx, name, resource, sum.GetDataPoints())
+		}
+		return r.gaugeDelta(ctx, name, resource, sum.GetDataPoints())
+	}
+
+	var errs []error
+	for _, dp := range sum.GetDataPoints() {
+		l, err := pointLabels(resource, dp.GetAttributes())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		m := model.Metric{
+			ID:        name,
+			MType:     model.Counter,
+			Labels:    l,
+			Timestamp: pointTime(dp),
+		}
+		delta := math.Round(pointValue(dp))
+		if cumulative {
+			var ok bool
+			if delta, ok = r.cumulativeDelta(m.Key(), dp); !ok {
+				continue
+			}
+		}
+		d := int64(delta)
+		m.Delta = &d
+		if err := r.storage.Update(ctx, m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// cumulativeDelta - приращение cumulative-точки относительно предыдущей точки ряда.
+// Если изменилось время старта или значение уменьшилось (перезапуск источника) -
+// приращением считается все значение точки. false - ряд встречается впервые,
+// точка запомнена как начальное значение.
+func (r *Receiver) cumulativeDelta(key string, dp *metricspb.NumberDataPoint) (float64, bool) {
+	value := pointValue(dp)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prev, ok := r.cumulative[key]
+	r.cumulative[key] = cumulativePoint{start: dp.GetStartTimeUnixNano(), value: value, seen: r.now()}
+	if !ok {
+		return 0, false
+	}
+	if prev.start != dp.GetStartTimeUnixNano() || value < prev.value {
+		return math.Round(value), true
+	}
+	return math.Round(value) - math.Round(prev.value), true
+}
+
+func (r *Receiver) gauge(ctx context.Context, name string, resource model.Labels, points []*metricspb.NumberDataPoint) []error {
+	var errs []error
+	for _, dp := range points {
+		l, err := pointLabels(resource, dp.GetAttributes())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		v := pointValue(dp)
+		err = r.storage.Update(ctx, model.Metric{
+			ID:        name,
+			MType:     model.Gauge,
+			Labels:    l,
+			Value:     &v,
+			Timestamp: pointTime(dp),
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// gaugeDelta - немонотонная delta-сумма (UpDownCounter) изменяет текущее значение gauge
+func (r *Receiver) gaugeDelta(ctx context.Context, name string, resource model.Labels, points []*metricspb.NumberDataPoint) []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var errs []error
+	for _, dp := range points {
+		l, err := pointLabels(resource, dp.GetAttributes())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		v := pointValue(dp)
+		current, err := r.storage.Get(ctx, model.Gauge, name, l)
+		if err == nil && current.Value != nil {
+			v += *current.Value
+		}
+		err = r.storage.Update(ctx, model.Metric{
+			ID:        name,
+			MType:     model.Gauge,
+			Labels:    l,
+			Value:     &v,
+			Timestamp: pointTime(dp),
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (r *Receiver) histogram(ctx context.Context, name string, resource model.Labels, hist *metricspb.Histogram) []error {
+	cumulative := hist.GetAggregationTemporality() == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+
+	var errs []error
+	for _, dp := range hist.GetDataPoints() {
+		h := &model.HistogramData{
+			Bounds: dp.GetExplicitBounds(),
+			Counts: dp.GetBucketCounts(),
+			Sum:    dp.GetSum(),
+			Count:  dp.GetCount(),
+		}
+		if len(h.Bounds) == 0 && len(h.Counts) == 0 {
+			h.Counts = []uint64{h.Count}
+		}
+		if err := h.Validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		l, err := pointLabels(resource, dp.GetAttributes())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		m := model.Metric{
+			ID:        name,
+			MType:     model.Histogram,
+			Labels:    l,
+			Timestamp: pointTime(dp),
+		}
+		if cumulative {
+			var ok bool
+			if h, ok = r.cumulativeHistogramDelta(m.Key(), dp.GetStartTimeUnixNano(), h); !ok {
+				continue
+			}
+		}
+		m.Histogram = h
+		if err := r.storage.Update(ctx, m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// cumulativeHistogramDelta - приращение cumulative-гистограммы относительно предыдущей точки ряда,
+// правила перезапуска источника те же, что и для cumulativeDelta
+func (r *Receiver) cumulativeHistogramDelta(
+	key string,
+	start uint64,
+	h *model.HistogramData,
+) (*model.HistogramData, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prev, ok := r.cumulativeHistogram[key]
+	r.cumulativeHistogram[key] = cumulativeHistogramPoint{start: start, value: h, seen: r.now()}
+	if !ok {
+		return nil, false
+	}
+	if prev.start != start || !slices.Equal(prev.value.Bounds, h.Bounds) || h.Count < prev.value.Count {
+		return h, true
+	}
+	delta := h.Clone()
+	for i, c := range prev.value.Counts {
+		if delta.Counts[i] < c {
+			return h, true
+		}
+		delta.Counts[i] -= c
+	}
+	delta.Sum -= prev.value.Sum
+	delta.Count -= prev.value.Count
+	return delta, true
+}
+
+// exponentialHistogram - схемы больше model.ExponentialMaxSchema уменьшаются при приеме
+func (r *Receiver) exponentialHistogram(
+	ctx context.Context,
+	name string,
+	resource model.Labels,
+	hist *metricspb.ExponentialHistogram,
+) []error {
+	cumulative := hist.GetAggregationTemporality() == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+
+	var errs []error
+	for _, dp := range hist.GetDataPoints() {
+		h := &model.ExponentialHistogramData{
+			Schema:        dp.GetScale(),
+			ZeroThreshold: dp.GetZeroThreshold(),
+			ZeroCount:     dp.GetZeroCount(),
+			Positive:      model.ExponentialBuckets{Offset: dp.GetPositive().GetOffset(), Counts: dp.GetPositive().GetBucketCounts()},
+			Negative:      model.ExponentialBuckets{Offset: dp.GetNegative().GetOffset(), Counts: dp.GetNegative().GetBucketCounts()},
+			Sum:           dp.GetSum(),
+			Count:         dp.GetCount(),
+		}
+		h.Downscale(model.ExponentialMaxSchema)
+		if err := h.Validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		l, err := pointLabels(resource, dp.GetAttributes())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		m := model.Metric{
+			ID:        name,
+			MType:     model.ExponentialHistogram,
+			Labels:    l,
+			Timestamp: pointTime(dp),
+		}
+		if cumulative {
+			var ok bool
+			if h, ok = r.cumulativeExponentialHistogramDelta(m.Key(), dp.GetStartTimeUnixNano(), h); !ok {
+				continue
+			}
+		}
+		m.ExponentialHistogram = h
+		if err := r.storage.Update(ctx, m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// cumulativeExponentialHistogramDelta - приращение cumulative-гистограммы относительно
+// предыдущей точки ряда, обе гистограммы приводятся к меньшей из двух схем
+func (r *Receiver) cumulativeExponentialHistogramDelta(
+	key string,
+	start uint64,
+	h *model.ExponentialHistogramData,
+) (*model.ExponentialHistogramData, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prev, ok := r.cumulativeExponentialHistogram[key]
+	r.cumulativeExponentialHistogram[key] = cumulativeExponentialHistogramPoint{start: start, value: h, seen: r.now()}
+	if !ok {
+		return nil, false
+	}
+	if prev.start != start || prev.value.ZeroThreshold != h.ZeroThreshold ||
+		h.Count < prev.value.Count || h.ZeroCount < prev.value.ZeroCount {
+		return h, true
+	}
+	delta, p := h.Clone(), prev.value.Clone()
+	schema := min(delta.Schema, p.Schema)
+	delta.Downscale(schema)
+	p.Downscale(schema)
+	if !subtractBuckets(&delta.Positive, p.Positive) || !subtractBuckets(&delta.Negative, p.Negative) {
+		return h, true
+	}
+	delta.ZeroCount -= p.ZeroCount
+	delta.Sum -= p.Sum
+	delta.Count -= p.Count
+	return delta, true
+}
+
+// subtractBuckets - вычитает из b корзины prev, false - если в prev есть наблюдения,
+// отсутствующие в b (источник перезапущен)
+func subtractBuckets(b *model.ExponentialBuckets, prev model.ExponentialBuckets) bool {
+	for i, c := range prev.Counts {
+		if c == 0 {
+			continue
+		}
+		idx := int(prev.Offset) + i - int(b.Offset)
+		if idx < 0 || idx >= len(b.Counts) || b.Counts[idx] < c {
+			return false
+		}
+		b.Counts[idx] -= c
+	}
+	return true
+}
+
+// pointTime - время точки, нулевое значение если время не передано
+func pointTime(dp interface{ GetTimeUnixNano() uint64 }) time.Time {
+	if dp.GetTimeUnixNano() == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(dp.GetTimeUnixNano())) //nolint:gosec // время в наносекундах помещается в int64
+}
+
+func pointValue(dp *metricspb.NumberDataPoint) float64 {
+	if v, ok := dp.GetValue().(*metricspb.NumberDataPoint_AsInt); ok {
+		return float64(v.AsInt)
+	}
+	return dp.GetAsDouble()
+}
+
+func dataPointsCount(m *metricspb.Metric) int64 {
+	switch data := m.GetData().(type) {
+	case *metricspb.Metric_Sum:
+		return int64(len(data.Sum.GetDataPoints()))
+	case *metricspb.Metric_Gauge:
+		return int64(len(data.Gauge.GetDataPoints()))
+	case *metricspb.Metric_Histogram:
+		return int64(len(data.Histogram.GetDataPoints()))
+	case *metricspb.Metric_ExponentialHistogram:
+		return int64(len(data.ExponentialHistogram.GetDataPoints()))
+	case *metricspb.Metric_Summary:
+		return int64(len(data.Summary.GetDataPoints()))
+	default:
+		return 0
+	}
+}
+
+// pointLabels - метки ресурса и атрибуты точки, имена меток проверяются до
+// вычисления приращений, чтобы не хранить состояние рядов с некорректными метками
+func pointLabels(resource model.Labels, attrs []*commonpb.KeyValue) (model.Labels, error) {
+	l := resource.Merge(labels(attrs))
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// labels - атрибуты OTLP в виде меток метрики
+func labels(attrs []*commonpb.KeyValue) model.Labels {
+	if len(attrs) == 0 {
+		return nil
+	}
+	l := make(model.Labels, len(attrs))
+	for _, kv := range attrs {
+		l[kv.GetKey()] = anyValueString(kv.GetValue())
+	}
+	return l
+}
+
+// anyValueString - строковое представление значения атрибута
+func anyValueString(v *commonpb.AnyValue) string {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return val.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return fmt.Sprintf("%t", val.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return fmt.Sprintf("%d", val.IntValue)
+	case *commonpb.AnyValue_DoubleValue:
+		return fmt.Sprintf("%g", val.DoubleValue)
+	case *commonpb.AnyValue_BytesValue:
+		return fmt.Sprintf("%x", val.BytesValue)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,67 @@
+package otlp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+// counterStorage - хранилище, запоминающее сумму приращений counter по ключу ряда
+type counterStorage map[string]int64
+
+func (s counterStorage) Update(_ context.Context, m model.Metric) error {
+	s[m.Key()] += *m.Delta
+	return nil
+}
+
+func (s counterStorage) Get(context.Context, model.MetricType, string, model.Labels) (*model.Metric, error) {
+	return nil, model.ErrMetricNotFound
+}
+
+func TestEvictStale(t *testing.T) {
+	s := counterStorage{}
+	r := NewReceiver(s)
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	export := func(name string, v int64) {
+		t.Helper()
+		rejected, _ := r.Export(context.Background(), &colmetricspb.ExportMetricsServiceRequest{
+			ResourceMetrics: []*metricspb.ResourceMetrics{{
+				ScopeMetrics: []*metricspb.ScopeMetrics{{Metrics: []*metricspb.Metric{{
+					Name: name,
+					Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+						AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+						IsMonotonic:            true,
+						DataPoints: []*metricspb.NumberDataPoint{{
+							StartTimeUnixNano: 1,
+							Value:             &metricspb.NumberDataPoint_AsInt{AsInt: v},
+						}},
+					}},
+				}}}},
+			}},
+		})
+		require.Zero(t, rejected)
+	}
+
+	export("stale", 10)
+	export("active", 10)
+	now = now.Add(staleAfter / 2)
+	export("active", 20)
+	now = now.Add(staleAfter)
+	export("active", 30)
+
+	assert.NotContains(t, r.cumulative, model.SeriesKey(model.Counter, "stale", nil))
+	assert.Contains(t, r.cumulative, model.SeriesKey(model.Counter, "active", nil))
+
+	// после удаления точка снова считается начальным значением
+	export("stale", 15)
+	assert.Equal(t, counterStorage{model.SeriesKey(model.Counter, "active", nil): 20}, s)
+}
@@ -0,0 +1,253 @@
+package otlp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/memstorage"
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/otlp"
+)
+
+// contextStorage - хранилище в памяти с методами otlp.Storage
+type contextStorage struct {
+	*memstorage.MemStorage
+}
+
+func (s contextStorage) Update(_ context.Context, m model.Metric) error {
+	return s.MemStorage.Update(m)
+}
+
+func (s contextStorage) Get(
+	_ context.Context,
+	metricType model.MetricType,
+	metricName string,
+	labels model.Labels,
+) (*model.Metric, error) {
+	return s.MemStorage.Get(metricType, metricName, labels)
+}
+
+func TestExport(t *testing.T) {
+	ms := memstorage.New(0)
+	r := otlp.NewReceiver(contextStorage{ms})
+
+	export := func(metrics ...*metricspb.Metric) (int64, string) {
+		t.Helper()
+		return r.Export(context.Background(), exportRequest(metrics...))
+	}
+
+	rejected, _ := export(
+		sumMetric("requests", true, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, intPoint(1, 5)),
+		sumMetric("bytes", true, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, intPoint(1, 100)),
+		sumMetric("queue", false, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, intPoint(1, 3)),
+		gaugeMetric("temperature", doublePoint(36.6)),
+	)
+	assert.Zero(t, rejected)
+
+	rejected, reason := export(
+		sumMetric("requests", true, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, intPoint(1, 2)),
+		sumMetric("bytes", true, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, intPoint(1, 150)),
+		sumMetric("queue", false, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, intPoint(1, -1)),
+		&metricspb.Metric{
+			Name: "latency",
+			Data: &metricspb.Metric_Summary{Summary: &metricspb.Summary{
+				DataPoints: []*metricspb.SummaryDataPoint{{}, {}},
+			}},
+		},
+	)
+	assert.Equal(t, int64(2), rejected)
+	assert.Contains(t, reason, "latency")
+
+	// перезапуск источника - новое время старта
+	export(sumMetric("bytes", true, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, intPoint(2, 30)))
+
+	labels := model.NewLabels("service.name", "api", "host", "a")
+	assertCounter(t, ms, "requests", labels, 7)
+	// первая cumulative-точка - только начальное значение
+	assertCounter(t, ms, "bytes", labels, 80)
+	assertGauge(t, ms, "queue", labels, 2)
+	assertGauge(t, ms, "temperature", model.NewLabels("service.name", "api"), 36.6)
+}
+
+func TestExportInvalidNames(t *testing.T) {
+	ms := memstorage.New(0)
+	r := otlp.NewReceiver(contextStorage{ms})
+
+	rejected, reason := r.Export(context.Background(), exportRequest(
+		gaugeMetric(`load{host="b"}`, doublePoint(1), doublePoint(2)),
+	))
+	assert.Equal(t, int64(2), rejected)
+	assert.Contains(t, reason, "incorrect name")
+
+	point := doublePoint(1)
+	point.Attributes = []*commonpb.KeyValue{stringAttribute(`host="b"`, "a")}
+	// точка с некорректной меткой отклоняется, остальные точки запроса сохраняются
+	rejected, reason = r.Export(context.Background(), exportRequest(
+		gaugeMetric("load", point, doublePoint(3)),
+	))
+	assert.Equal(t, int64(1), rejected)
+	assert.Contains(t, reason, model.ErrInvalidLabel.Error())
+	assertGauge(t, ms, "load", model.NewLabels("service.name", "api"), 3)
+	assert.Len(t, ms.List(), 1)
+}
+
+func TestExportHistogram(t *testing.T) {
+	ms := memstorage.New(0)
+	r := otlp.NewReceiver(contextStorage{ms})
+
+	histogram := func(start uint64, counts []uint64, sum float64) *metricspb.Metric {
+		var count uint64
+		for _, c := range counts {
+			count += c
+		}
+		return &metricspb.Metric{
+			Name: "duration",
+			Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				DataPoints: []*metricspb.HistogramDataPoint{{
+					StartTimeUnixNano: start,
+					ExplicitBounds:    []float64{0.1, 1},
+					BucketCounts:      counts,
+					Sum:               &sum,
+					Count:             count,
+				}},
+			}},
+		}
+	}
+
+	for _, m := range []*metricspb.Metric{
+		histogram(1, []uint64{1, 0, 0}, 0.05),
+		histogram(1, []uint64{2, 1, 0}, 0.65),
+		// перезапуск источника - новое время старта
+		histogram(2, []uint64{0, 0, 1}, 3),
+	} {
+		rejected, _ := r.Export(context.Background(), exportRequest(m))
+		assert.Zero(t, rejected)
+	}
+
+	m, err := ms.Get(model.Histogram, "duration", model.NewLabels("service.name", "api"))
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 1, 1}, m.Histogram.Counts)
+	assert.Equal(t, uint64(3), m.Histogram.Count)
+	assert.InDelta(t, 3.6, m.Histogram.Sum, 1e-9)
+}
+
+func TestExportExponentialHistogram(t *testing.T) {
+	ms := memstorage.New(0)
+	r := otlp.NewReceiver(contextStorage{ms})
+
+	histogram := func(scale int32, offset int32, counts []uint64) *metricspb.Metric {
+		var count uint64
+		for _, c := range counts {
+			count += c
+		}
+		return &metricspb.Metric{
+			Name: "duration",
+			Data: &metricspb.Metric_ExponentialHistogram{ExponentialHistogram: &metricspb.ExponentialHistogram{
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				DataPoints: []*metricspb.ExponentialHistogramDataPoint{{
+					StartTimeUnixNano: 1,
+					Scale:             scale,
+					Positive: &metricspb.ExponentialHistogramDataPoint_Buckets{
+						Offset:       offset,
+						BucketCounts: counts,
+					},
+					Count: count,
+				}},
+			}},
+		}
+	}
+
+	for _, m := range []*metricspb.Metric{
+		// схема больше допустимой уменьшается при приеме
+		histogram(10, 1024, []uint64{1}),
+		// следующая точка после уменьшения схемы источником
+		histogram(0, 0, []uint64{1, 2}),
+	} {
+		rejected, _ := r.Export(context.Background(), exportRequest(m))
+		assert.Zero(t, rejected)
+	}
+
+	m, err := ms.Get(model.ExponentialHistogram, "duration", model.NewLabels("service.name", "api"))
+	require.NoError(t, err)
+	h := m.ExponentialHistogram
+	assert.Equal(t, int32(0), h.Schema)
+	assert.Equal(t, uint64(2), h.Count)
+	assert.Equal(t, model.ExponentialBuckets{Offset: 0, Counts: []uint64{1, 1}}, h.Positive)
+}
+
+func assertCounter(t *testing.T, ms *memstorage.MemStorage, name string, labels model.Labels, expected int64) {
+	t.Helper()
+	m, err := ms.Get(model.Counter, name, labels)
+	require.NoError(t, err)
+	assert.Equal(t, expected, *m.Delta)
+}
+
+func assertGauge(t *testing.T, ms *memstorage.MemStorage, name string, labels model.Labels, expected float64) {
+	t.Helper()
+	m, err := ms.Get(model.Gauge, name, labels)
+	require.NoError(t, err)
+	assert.InDelta(t, expected, *m.Value, 1e-9)
+}
+
+func exportRequest(metrics ...*metricspb.Metric) *colmetricspb.ExportMetricsServiceRequest {
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				stringAttribute("service.name", "api"),
+			}},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{Metrics: metrics}},
+		}},
+	}
+}
+
+func sumMetric(
+	name string,
+	monotonic bool,
+	temporality metricspb.AggregationTemporality,
+	points ...*metricspb.NumberDataPoint,
+) *metricspb.Metric {
+	return &metricspb.Metric{
+		Name: name,
+		Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			DataPoints:             points,
+			AggregationTemporality: temporality,
+			IsMonotonic:            monotonic,
+		}},
+	}
+}
+
+func gaugeMetric(name string, points ...*metricspb.NumberDataPoint) *metricspb.Metric {
+	return &metricspb.Metric{
+		Name: name,
+		Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: points}},
+	}
+}
+
+func intPoint(start uint64, v int64) *metricspb.NumberDataPoint {
+	return &metricspb.NumberDataPoint{
+		StartTimeUnixNano: start,
+		Attributes:        []*commonpb.KeyValue{stringAttribute("host", "a")},
+		Value:             &metricspb.NumberDataPoint_AsInt{AsInt: v},
+	}
+}
+
+func doublePoint(v float64) *metricspb.NumberDataPoint {
+	return &metricspb.NumberDataPoint{
+		Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: v},
+	}
+}
+
+func stringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
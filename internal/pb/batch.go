@@ -0,0 +1,56 @@
+package pb
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// batchField - поле нарушения в деталях ошибки UpdateBatch, индекс - номер сообщения в потоке
+const batchField = "requests[%d]"
+
+// BatchError - ошибка UpdateBatch, в деталях которой перечислены индексы отклоненных сообщений,
+// остальные сообщения потока применены. failed - ошибки по индексам сообщений.
+func BatchError(failed map[int]error) error {
+	indexes := slices.Sorted(maps.Keys(failed))
+	details := &errdetails.BadRequest{}
+	for _, i := range indexes {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       fmt.Sprintf(batchField, i),
+			Description: failed[i].Error(),
+		})
+	}
+
+	st := status.Newf(codes.InvalidArgument, "%d of the batch metrics rejected: %v", len(indexes), failed[indexes[0]])
+	if withDetails, err := st.WithDetails(details); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// BatchFailed - индексы отклоненных сообщений из ошибки BatchError,
+// false - ошибка не содержит индексов (пакет не применен)
+func BatchFailed(err error) ([]int, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.InvalidArgument {
+		return nil, false
+	}
+	var failed []int
+	for _, d := range st.Details() {
+		br, ok := d.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+		for _, v := range br.GetFieldViolations() {
+			var i int
+			if _, err := fmt.Sscanf(v.GetField(), batchField, &i); err == nil {
+				failed = append(failed, i)
+			}
+		}
+	}
+	return failed, len(failed) > 0
+}
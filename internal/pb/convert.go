@@ -0,0 +1,222 @@
+package pb
+
+import (
+	"fmt"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+func FromMetricType(t model.MetricType) MetricType {
+	switch t {
+	case model.Counter:
+		return MetricType_METRIC_TYPE_COUNTER
+	case model.Gauge:
+		return MetricType_METRIC_TYPE_GAUGE
+	case model.Histogram:
+		return MetricType_METRIC_TYPE_HISTOGRAM
+	case model.ExponentialHistogram:
+		return MetricType_METRIC_TYPE_EXPONENTIAL_HISTOGRAM
+	case model.Summary:
+		return MetricType_METRIC_TYPE_SUMMARY
+	case model.Set:
+		return MetricType_METRIC_TYPE_SET
+	default:
+		return MetricType_METRIC_TYPE_UNSPECIFIED
+	}
+}
+
+func (t MetricType) ToModel() (model.MetricType, error) {
+	switch t {
+	case MetricType_METRIC_TYPE_COUNTER:
+		return model.Counter, nil
+	case MetricType_METRIC_TYPE_GAUGE:
+		return model.Gauge, nil
+	case MetricType_METRIC_TYPE_HISTOGRAM:
+		return model.Histogram, nil
+	case MetricType_METRIC_TYPE_EXPONENTIAL_HISTOGRAM:
+		return model.ExponentialHistogram, nil
+	case MetricType_METRIC_TYPE_SUMMARY:
+		return model.Summary, nil
+	case MetricType_METRIC_TYPE_SET:
+		return model.Set, nil
+	default:
+		return "", fmt.Errorf("incorrect metric type %s", t)
+	}
+}
+
+func FromMetric(m *model.Metric) *Metric {
+	res := &Metric{
+		Id:      m.ID,
+		Type:    FromMetricType(m.MType),
+		Delta:   m.Delta,
+		Value:   m.Value,
+		Labels:  m.Labels,
+		Members: m.Members,
+	}
+	if m.Histogram != nil {
+		res.Histogram = &Histogram{
+			Bounds: m.Histogram.Bounds,
+			Counts: m.Histogram.Counts,
+			Sum:    m.Histogram.Sum,
+			Count:  m.Histogram.Count,
+		}
+	}
+	if h := m.ExponentialHistogram; h != nil {
+		res.ExponentialHistogram = &ExponentialHistogram{
+			Schema:        h.Schema,
+			ZeroThreshold: h.ZeroThreshold,
+			ZeroCount:     h.ZeroCount,
+			Positive:      &ExponentialBuckets{Offset: h.Positive.Offset, Counts: h.Positive.Counts},
+			Negative:      &ExponentialBuckets{Offset: h.Negative.Offset, Counts: h.Negative.Counts},
+			Sum:           h.Sum,
+			Count:         h.Count,
+		}
+	}
+	if sm := m.Summary; sm != nil {
+		res.Summary = &Summary{
+			RelativeAccuracy: sm.RelativeAccuracy,
+			ZeroCount:        sm.ZeroCount,
+			Positive:         &ExponentialBuckets{Offset: sm.Positive.Offset, Counts: sm.Positive.Counts},
+			Negative:         &ExponentialBuckets{Offset: sm.Negative.Offset, Counts: sm.Negative.Counts},
+			Sum:              sm.Sum,
+			Count:            sm.Count,
+		}
+	}
+	if m.Set != nil {
+		res.Set = &Set{Precision: uint32(m.Set.Precision), Registers: m.Set.Registers}
+	}
+	return res
+}
+
+// ToModel - преобразует в model.Metric с проверкой, что задано значение,
+// соответствующее типу метрики
+func (m *Metric) ToModel() (model.Metric, error) {
+	t, err := m.GetType().ToModel()
+	if err != nil {
+		return model.Metric{}, err
+	}
+	var labels model.Labels
+	if len(m.GetLabels()) > 0 {
+		labels = m.GetLabels()
+		if err := labels.Validate(); err != nil {
+			return model.Metric{}, err
+		}
+	}
+	res := model.Metric{
+		ID:     m.GetId(),
+		MType:  t,
+		Labels: labels,
+	}
+	switch t {
+	case model.Counter:
+		if m.Delta == nil {
+			return model.Metric{}, fmt.Errorf("no delta for counter %s", m.GetId())
+		}
+		d := m.GetDelta()
+		res.Delta = &d
+	case model.Gauge:
+		if m.Value == nil {
+			return model.Metric{}, fmt.Errorf("no value for gauge %s", m.GetId())
+		}
+		v := m.GetValue()
+		res.Value = &v
+	case model.Histogram:
+		if h := m.GetHistogram(); h != nil {
+			res.Histogram = &model.HistogramData{
+				Bounds: h.GetBounds(),
+				Counts: h.GetCounts(),
+				Sum:    h.GetSum(),
+				Count:  h.GetCount(),
+			}
+			if err := res.Histogram.Validate(); err != nil {
+				return model.Metric{}, err
+			}
+		} else if m.Value != nil {
+			v := m.GetValue()
+			res.Value = &v
+		} else {
+			return model.Metric{}, fmt.Errorf("no histogram or value for histogram %s", m.GetId())
+		}
+	case model.ExponentialHistogram:
+		if h := m.GetExponentialHistogram(); h != nil {
+			res.ExponentialHistogram = &model.ExponentialHistogramData{
+				Schema:        h.GetSchema(),
+				ZeroThreshold: h.GetZeroThreshold(),
+				ZeroCount:     h.GetZeroCount(),
+				Positive:      model.ExponentialBuckets{Offset: h.GetPositive().GetOffset(), Counts: h.GetPositive().GetCounts()},
+				Negative:      model.ExponentialBuckets{Offset: h.GetNegative().GetOffset(), Counts: h.GetNegative().GetCounts()},
+				Sum:           h.GetSum(),
+				Count:         h.GetCount(),
+			}
+			if err := res.ExponentialHistogram.Validate(); err != nil {
+				return model.Metric{}, err
+			}
+		} else if m.Value != nil {
+			v := m.GetValue()
+			res.Value = &v
+		} else {
+			return model.Metric{}, fmt.Errorf("no histogram or value for histogram %s", m.GetId())
+		}
+	case model.Summary:
+		if sm := m.GetSummary(); sm != nil {
+			res.Summary = &model.SummaryData{
+				RelativeAccuracy: sm.GetRelativeAccuracy(),
+				ZeroCount:        sm.GetZeroCount(),
+				Positive:         model.ExponentialBuckets{Offset: sm.GetPositive().GetOffset(), Counts: sm.GetPositive().GetCounts()},
+				Negative:         model.ExponentialBuckets{Offset: sm.GetNegative().GetOffset(), Counts: sm.GetNegative().GetCounts()},
+				Sum:              sm.GetSum(),
+				Count:            sm.GetCount(),
+			}
+			if err := res.Summary.Validate(); err != nil {
+				return model.Metric{}, err
+			}
+		} else if m.Value != nil {
+			v := m.GetValue()
+			res.Value = &v
+		} else {
+			return model.Metric{}, fmt.Errorf("no summary or value for summary %s", m.GetId())
+		}
+	case model.Set:
+		res.Members = m.GetMembers()
+		if st := m.GetSet(); st != nil {
+			if st.GetPrecision() > model.MaxSetPrecision {
+				return model.Metric{}, fmt.Errorf("incorrect set precision %d", st.GetPrecision())
+			}
+			res.Set = &model.SetData{Precision: uint8(st.GetPrecision()), Registers: st.GetRegisters()}
+			if err := res.Set.Validate(); err != nil {
+				return model.Metric{}, err
+			}
+		} else if len(res.Members) == 0 {
+			return model.Metric{}, fmt.Errorf("no set or members for set %s", m.GetId())
+		}
+	}
+	return res, nil
+}
+
+func FromMetadata(md *model.Metadata) *Metadata {
+	return &Metadata{
+		Type:  FromMetricType(md.Type),
+		Help:  md.Help,
+		Unit:  md.Unit,
+		Owner: md.Owner,
+	}
+}
+
+func (md *Metadata) ToModel() (model.Metadata, error) {
+	res := model.Metadata{
+		Help:  md.GetHelp(),
+		Unit:  md.GetUnit(),
+		Owner: md.GetOwner(),
+	}
+	if md.GetType() != MetricType_METRIC_TYPE_UNSPECIFIED {
+		t, err := md.GetType().ToModel()
+		if err != nil {
+			return model.Metadata{}, err
+		}
+		res.Type = t
+	}
+	if err := res.Validate(); err != nil {
+		return model.Metadata{}, err
+	}
+	return res, nil
+}
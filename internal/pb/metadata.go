@@ -0,0 +1,9 @@
+package pb
+
+// Ключи метаданных gRPC, общие для клиента и сервера
+const (
+	// RealIPMetadataKey - IP адрес клиента, аналог заголовка X-Real-IP
+	RealIPMetadataKey = "x-real-ip"
+	// SignMetadataKey - подпись запроса, аналог заголовка HashSHA256
+	SignMetadataKey = "hashsha256"
+)
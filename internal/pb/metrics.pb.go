@@ -0,0 +1,1305 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v5.29.3
+// source: internal/pb/metrics.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type MetricType int32
+
+const (
+	MetricType_METRIC_TYPE_UNSPECIFIED           MetricType = 0
+	MetricType_METRIC_TYPE_COUNTER               MetricType = 1
+	MetricType_METRIC_TYPE_GAUGE                 MetricType = 2
+	MetricType_METRIC_TYPE_HISTOGRAM             MetricType = 3
+	MetricType_METRIC_TYPE_EXPONENTIAL_HISTOGRAM MetricType = 4
+	MetricType_METRIC_TYPE_SUMMARY               MetricType = 5
+	MetricType_METRIC_TYPE_SET                   MetricType = 6
+)
+
+// Enum value maps for MetricType.
+var (
+	MetricType_name = map[int32]string{
+		0: "METRIC_TYPE_UNSPECIFIED",
+		1: "METRIC_TYPE_COUNTER",
+		2: "METRIC_TYPE_GAUGE",
+		3: "METRIC_TYPE_HISTOGRAM",
+		4: "METRIC_TYPE_EXPONENTIAL_HISTOGRAM",
+		5: "METRIC_TYPE_SUMMARY",
+		6: "METRIC_TYPE_SET",
+	}
+	MetricType_value = map[string]int32{
+		"METRIC_TYPE_UNSPECIFIED":           0,
+		"METRIC_TYPE_COUNTER":               1,
+		"METRIC_TYPE_GAUGE":                 2,
+		"METRIC_TYPE_HISTOGRAM":             3,
+		"METRIC_TYPE_EXPONENTIAL_HISTOGRAM": 4,
+		"METRIC_TYPE_SUMMARY":               5,
+		"METRIC_TYPE_SET":                   6,
+	}
+)
+
+func (x MetricType) Enum() *MetricType {
+	p := new(MetricType)
+	*p = x
+	return p
+}
+
+func (x MetricType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MetricType) Descriptor() protoreflect.EnumDescriptor {
+	return file_internal_pb_metrics_proto_enumTypes[0].Descriptor()
+}
+
+func (MetricType) Type() protoreflect.EnumType {
+	return &file_internal_pb_metrics_proto_enumTypes[0]
+}
+
+func (x MetricType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MetricType.Descriptor instead.
+func (MetricType) EnumDescriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{0}
+}
+
+// Histogram - аналог model.HistogramData
+type Histogram struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bounds        []float64              `protobuf:"fixed64,1,rep,packed,name=bounds,proto3" json:"bounds,omitempty"`
+	Counts        []uint64               `protobuf:"varint,2,rep,packed,name=counts,proto3" json:"counts,omitempty"`
+	Sum           float64                `protobuf:"fixed64,3,opt,name=sum,proto3" json:"sum,omitempty"`
+	Count         uint64                 `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Histogram) Reset() {
+	*x = Histogram{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Histogram) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Histogram) ProtoMessage() {}
+
+func (x *Histogram) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Histogram.ProtoReflect.Descriptor instead.
+func (*Histogram) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Histogram) GetBounds() []float64 {
+	if x != nil {
+		return x.Bounds
+	}
+	return nil
+}
+
+func (x *Histogram) GetCounts() []uint64 {
+	if x != nil {
+		return x.Counts
+	}
+	return nil
+}
+
+func (x *Histogram) GetSum() float64 {
+	if x != nil {
+		return x.Sum
+	}
+	return 0
+}
+
+func (x *Histogram) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type ExponentialBuckets struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Offset        int32                  `protobuf:"zigzag32,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Counts        []uint64               `protobuf:"varint,2,rep,packed,name=counts,proto3" json:"counts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExponentialBuckets) Reset() {
+	*x = ExponentialBuckets{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExponentialBuckets) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExponentialBuckets) ProtoMessage() {}
+
+func (x *ExponentialBuckets) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExponentialBuckets.ProtoReflect.Descriptor instead.
+func (*ExponentialBuckets) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ExponentialBuckets) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ExponentialBuckets) GetCounts() []uint64 {
+	if x != nil {
+		return x.Counts
+	}
+	return nil
+}
+
+// ExponentialHistogram - аналог model.ExponentialHistogramData
+type ExponentialHistogram struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Schema        int32                  `protobuf:"zigzag32,1,opt,name=schema,proto3" json:"schema,omitempty"`
+	ZeroThreshold float64                `protobuf:"fixed64,2,opt,name=zero_threshold,json=zeroThreshold,proto3" json:"zero_threshold,omitempty"`
+	ZeroCount     uint64                 `protobuf:"varint,3,opt,name=zero_count,json=zeroCount,proto3" json:"zero_count,omitempty"`
+	Positive      *ExponentialBuckets    `protobuf:"bytes,4,opt,name=positive,proto3" json:"positive,omitempty"`
+	Negative      *ExponentialBuckets    `protobuf:"bytes,5,opt,name=negative,proto3" json:"negative,omitempty"`
+	Sum           float64                `protobuf:"fixed64,6,opt,name=sum,proto3" json:"sum,omitempty"`
+	Count         uint64                 `protobuf:"varint,7,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExponentialHistogram) Reset() {
+	*x = ExponentialHistogram{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExponentialHistogram) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExponentialHistogram) ProtoMessage() {}
+
+func (x *ExponentialHistogram) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExponentialHistogram.ProtoReflect.Descriptor instead.
+func (*ExponentialHistogram) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ExponentialHistogram) GetSchema() int32 {
+	if x != nil {
+		return x.Schema
+	}
+	return 0
+}
+
+func (x *ExponentialHistogram) GetZeroThreshold() float64 {
+	if x != nil {
+		return x.ZeroThreshold
+	}
+	return 0
+}
+
+func (x *ExponentialHistogram) GetZeroCount() uint64 {
+	if x != nil {
+		return x.ZeroCount
+	}
+	return 0
+}
+
+func (x *ExponentialHistogram) GetPositive() *ExponentialBuckets {
+	if x != nil {
+		return x.Positive
+	}
+	return nil
+}
+
+func (x *ExponentialHistogram) GetNegative() *ExponentialBuckets {
+	if x != nil {
+		return x.Negative
+	}
+	return nil
+}
+
+func (x *ExponentialHistogram) GetSum() float64 {
+	if x != nil {
+		return x.Sum
+	}
+	return 0
+}
+
+func (x *ExponentialHistogram) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+// Summary - аналог model.SummaryData
+type Summary struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	RelativeAccuracy float64                `protobuf:"fixed64,1,opt,name=relative_accuracy,json=relativeAccuracy,proto3" json:"relative_accuracy,omitempty"`
+	ZeroCount        uint64                 `protobuf:"varint,2,opt,name=zero_count,json=zeroCount,proto3" json:"zero_count,omitempty"`
+	Positive         *ExponentialBuckets    `protobuf:"bytes,3,opt,name=positive,proto3" json:"positive,omitempty"`
+	Negative         *ExponentialBuckets    `protobuf:"bytes,4,opt,name=negative,proto3" json:"negative,omitempty"`
+	Sum              float64                `protobuf:"fixed64,5,opt,name=sum,proto3" json:"sum,omitempty"`
+	Count            uint64                 `protobuf:"varint,6,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Summary) Reset() {
+	*x = Summary{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Summary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Summary) ProtoMessage() {}
+
+func (x *Summary) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Summary.ProtoReflect.Descriptor instead.
+func (*Summary) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Summary) GetRelativeAccuracy() float64 {
+	if x != nil {
+		return x.RelativeAccuracy
+	}
+	return 0
+}
+
+func (x *Summary) GetZeroCount() uint64 {
+	if x != nil {
+		return x.ZeroCount
+	}
+	return 0
+}
+
+func (x *Summary) GetPositive() *ExponentialBuckets {
+	if x != nil {
+		return x.Positive
+	}
+	return nil
+}
+
+func (x *Summary) GetNegative() *ExponentialBuckets {
+	if x != nil {
+		return x.Negative
+	}
+	return nil
+}
+
+func (x *Summary) GetSum() float64 {
+	if x != nil {
+		return x.Sum
+	}
+	return 0
+}
+
+func (x *Summary) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+// Set - аналог model.SetData
+type Set struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Precision     uint32                 `protobuf:"varint,1,opt,name=precision,proto3" json:"precision,omitempty"`
+	Registers     []byte                 `protobuf:"bytes,2,opt,name=registers,proto3" json:"registers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Set) Reset() {
+	*x = Set{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Set) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Set) ProtoMessage() {}
+
+func (x *Set) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Set.ProtoReflect.Descriptor instead.
+func (*Set) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Set) GetPrecision() uint32 {
+	if x != nil {
+		return x.Precision
+	}
+	return 0
+}
+
+func (x *Set) GetRegisters() []byte {
+	if x != nil {
+		return x.Registers
+	}
+	return nil
+}
+
+// Metric - аналог model.Metric
+type Metric struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Id                   string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type                 MetricType             `protobuf:"varint,2,opt,name=type,proto3,enum=metrics.v1.MetricType" json:"type,omitempty"`
+	Delta                *int64                 `protobuf:"varint,3,opt,name=delta,proto3,oneof" json:"delta,omitempty"`
+	Value                *float64               `protobuf:"fixed64,4,opt,name=value,proto3,oneof" json:"value,omitempty"`
+	Labels               map[string]string      `protobuf:"bytes,5,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Histogram            *Histogram             `protobuf:"bytes,6,opt,name=histogram,proto3" json:"histogram,omitempty"`
+	ExponentialHistogram *ExponentialHistogram  `protobuf:"bytes,7,opt,name=exponential_histogram,json=exponentialHistogram,proto3" json:"exponential_histogram,omitempty"`
+	Summary              *Summary               `protobuf:"bytes,8,opt,name=summary,proto3" json:"summary,omitempty"`
+	Members              []string               `protobuf:"bytes,9,rep,name=members,proto3" json:"members,omitempty"`
+	Set                  *Set                   `protobuf:"bytes,10,opt,name=set,proto3" json:"set,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *Metric) Reset() {
+	*x = Metric{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metric) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metric) ProtoMessage() {}
+
+func (x *Metric) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metric.ProtoReflect.Descriptor instead.
+func (*Metric) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Metric) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Metric) GetType() MetricType {
+	if x != nil {
+		return x.Type
+	}
+	return MetricType_METRIC_TYPE_UNSPECIFIED
+}
+
+func (x *Metric) GetDelta() int64 {
+	if x != nil && x.Delta != nil {
+		return *x.Delta
+	}
+	return 0
+}
+
+func (x *Metric) GetValue() float64 {
+	if x != nil && x.Value != nil {
+		return *x.Value
+	}
+	return 0
+}
+
+func (x *Metric) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Metric) GetHistogram() *Histogram {
+	if x != nil {
+		return x.Histogram
+	}
+	return nil
+}
+
+func (x *Metric) GetExponentialHistogram() *ExponentialHistogram {
+	if x != nil {
+		return x.ExponentialHistogram
+	}
+	return nil
+}
+
+func (x *Metric) GetSummary() *Summary {
+	if x != nil {
+		return x.Summary
+	}
+	return nil
+}
+
+func (x *Metric) GetMembers() []string {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+func (x *Metric) GetSet() *Set {
+	if x != nil {
+		return x.Set
+	}
+	return nil
+}
+
+type UpdateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metric        *Metric                `protobuf:"bytes,1,opt,name=metric,proto3" json:"metric,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateRequest) Reset() {
+	*x = UpdateRequest{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateRequest) ProtoMessage() {}
+
+func (x *UpdateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateRequest.ProtoReflect.Descriptor instead.
+func (*UpdateRequest) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateRequest) GetMetric() *Metric {
+	if x != nil {
+		return x.Metric
+	}
+	return nil
+}
+
+type UpdateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateResponse) Reset() {
+	*x = UpdateResponse{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateResponse) ProtoMessage() {}
+
+func (x *UpdateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateResponse.ProtoReflect.Descriptor instead.
+func (*UpdateResponse) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{7}
+}
+
+type UpdateBatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Updated       int64                  `protobuf:"varint,1,opt,name=updated,proto3" json:"updated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateBatchResponse) Reset() {
+	*x = UpdateBatchResponse{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateBatchResponse) ProtoMessage() {}
+
+func (x *UpdateBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateBatchResponse.ProtoReflect.Descriptor instead.
+func (*UpdateBatchResponse) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UpdateBatchResponse) GetUpdated() int64 {
+	if x != nil {
+		return x.Updated
+	}
+	return 0
+}
+
+type GetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          MetricType             `protobuf:"varint,1,opt,name=type,proto3,enum=metrics.v1.MetricType" json:"type,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRequest) Reset() {
+	*x = GetRequest{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRequest) ProtoMessage() {}
+
+func (x *GetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetRequest) GetType() MetricType {
+	if x != nil {
+		return x.Type
+	}
+	return MetricType_METRIC_TYPE_UNSPECIFIED
+}
+
+func (x *GetRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type GetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metric        *Metric                `protobuf:"bytes,1,opt,name=metric,proto3" json:"metric,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetResponse) Reset() {
+	*x = GetResponse{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResponse) ProtoMessage() {}
+
+func (x *GetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
+func (*GetResponse) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetResponse) GetMetric() *Metric {
+	if x != nil {
+		return x.Metric
+	}
+	return nil
+}
+
+type ListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRequest) Reset() {
+	*x = ListRequest{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRequest) ProtoMessage() {}
+
+func (x *ListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{11}
+}
+
+type ListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metrics       []*Metric              `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResponse) Reset() {
+	*x = ListResponse{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResponse) ProtoMessage() {}
+
+func (x *ListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResponse.ProtoReflect.Descriptor instead.
+func (*ListResponse) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListResponse) GetMetrics() []*Metric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+// Metadata - аналог model.Metadata, METRIC_TYPE_UNSPECIFIED - тип не задан
+type Metadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          MetricType             `protobuf:"varint,1,opt,name=type,proto3,enum=metrics.v1.MetricType" json:"type,omitempty"`
+	Help          string                 `protobuf:"bytes,2,opt,name=help,proto3" json:"help,omitempty"`
+	Unit          string                 `protobuf:"bytes,3,opt,name=unit,proto3" json:"unit,omitempty"`
+	Owner         string                 `protobuf:"bytes,4,opt,name=owner,proto3" json:"owner,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Metadata) Reset() {
+	*x = Metadata{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metadata) ProtoMessage() {}
+
+func (x *Metadata) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metadata.ProtoReflect.Descriptor instead.
+func (*Metadata) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Metadata) GetType() MetricType {
+	if x != nil {
+		return x.Type
+	}
+	return MetricType_METRIC_TYPE_UNSPECIFIED
+}
+
+func (x *Metadata) GetHelp() string {
+	if x != nil {
+		return x.Help
+	}
+	return ""
+}
+
+func (x *Metadata) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+func (x *Metadata) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+type UpdateMetadataRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Metadata      *Metadata              `protobuf:"bytes,2,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateMetadataRequest) Reset() {
+	*x = UpdateMetadataRequest{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMetadataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMetadataRequest) ProtoMessage() {}
+
+func (x *UpdateMetadataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMetadataRequest.ProtoReflect.Descriptor instead.
+func (*UpdateMetadataRequest) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *UpdateMetadataRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *UpdateMetadataRequest) GetMetadata() *Metadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type UpdateMetadataResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateMetadataResponse) Reset() {
+	*x = UpdateMetadataResponse{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMetadataResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMetadataResponse) ProtoMessage() {}
+
+func (x *UpdateMetadataResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMetadataResponse.ProtoReflect.Descriptor instead.
+func (*UpdateMetadataResponse) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{15}
+}
+
+type GetMetadataRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetadataRequest) Reset() {
+	*x = GetMetadataRequest{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetadataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetadataRequest) ProtoMessage() {}
+
+func (x *GetMetadataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetadataRequest.ProtoReflect.Descriptor instead.
+func (*GetMetadataRequest) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetMetadataRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type GetMetadataResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metadata      *Metadata              `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetadataResponse) Reset() {
+	*x = GetMetadataResponse{}
+	mi := &file_internal_pb_metrics_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetadataResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetadataResponse) ProtoMessage() {}
+
+func (x *GetMetadataResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_metrics_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetadataResponse.ProtoReflect.Descriptor instead.
+func (*GetMetadataResponse) Descriptor() ([]byte, []int) {
+	return file_internal_pb_metrics_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *GetMetadataResponse) GetMetadata() *Metadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+var File_internal_pb_metrics_proto protoreflect.FileDescriptor
+
+const file_internal_pb_metrics_proto_rawDesc = "" +
+	"\n" +
+	"\x19internal/pb/metrics.proto\x12\n" +
+	"metrics.v1\"c\n" +
+	"\tHistogram\x12\x16\n" +
+	"\x06bounds\x18\x01 \x03(\x01R\x06bounds\x12\x16\n" +
+	"\x06counts\x18\x02 \x03(\x04R\x06counts\x12\x10\n" +
+	"\x03sum\x18\x03 \x01(\x01R\x03sum\x12\x14\n" +
+	"\x05count\x18\x04 \x01(\x04R\x05count\"D\n" +
+	"\x12ExponentialBuckets\x12\x16\n" +
+	"\x06offset\x18\x01 \x01(\x11R\x06offset\x12\x16\n" +
+	"\x06counts\x18\x02 \x03(\x04R\x06counts\"\x94\x02\n" +
+	"\x14ExponentialHistogram\x12\x16\n" +
+	"\x06schema\x18\x01 \x01(\x11R\x06schema\x12%\n" +
+	"\x0ezero_threshold\x18\x02 \x01(\x01R\rzeroThreshold\x12\x1d\n" +
+	"\n" +
+	"zero_count\x18\x03 \x01(\x04R\tzeroCount\x12:\n" +
+	"\bpositive\x18\x04 \x01(\v2\x1e.metrics.v1.ExponentialBucketsR\bpositive\x12:\n" +
+	"\bnegative\x18\x05 \x01(\v2\x1e.metrics.v1.ExponentialBucketsR\bnegative\x12\x10\n" +
+	"\x03sum\x18\x06 \x01(\x01R\x03sum\x12\x14\n" +
+	"\x05count\x18\a \x01(\x04R\x05count\"\xf5\x01\n" +
+	"\aSummary\x12+\n" +
+	"\x11relative_accuracy\x18\x01 \x01(\x01R\x10relativeAccuracy\x12\x1d\n" +
+	"\n" +
+	"zero_count\x18\x02 \x01(\x04R\tzeroCount\x12:\n" +
+	"\bpositive\x18\x03 \x01(\v2\x1e.metrics.v1.ExponentialBucketsR\bpositive\x12:\n" +
+	"\bnegative\x18\x04 \x01(\v2\x1e.metrics.v1.ExponentialBucketsR\bnegative\x12\x10\n" +
+	"\x03sum\x18\x05 \x01(\x01R\x03sum\x12\x14\n" +
+	"\x05count\x18\x06 \x01(\x04R\x05count\"A\n" +
+	"\x03Set\x12\x1c\n" +
+	"\tprecision\x18\x01 \x01(\rR\tprecision\x12\x1c\n" +
+	"\tregisters\x18\x02 \x01(\fR\tregisters\"\xf9\x03\n" +
+	"\x06Metric\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x16.metrics.v1.MetricTypeR\x04type\x12\x19\n" +
+	"\x05delta\x18\x03 \x01(\x03H\x00R\x05delta\x88\x01\x01\x12\x19\n" +
+	"\x05value\x18\x04 \x01(\x01H\x01R\x05value\x88\x01\x01\x126\n" +
+	"\x06labels\x18\x05 \x03(\v2\x1e.metrics.v1.Metric.LabelsEntryR\x06labels\x123\n" +
+	"\thistogram\x18\x06 \x01(\v2\x15.metrics.v1.HistogramR\thistogram\x12U\n" +
+	"\x15exponential_histogram\x18\a \x01(\v2 .metrics.v1.ExponentialHistogramR\x14exponentialHistogram\x12-\n" +
+	"\asummary\x18\b \x01(\v2\x13.metrics.v1.SummaryR\asummary\x12\x18\n" +
+	"\amembers\x18\t \x03(\tR\amembers\x12!\n" +
+	"\x03set\x18\n" +
+	" \x01(\v2\x0f.metrics.v1.SetR\x03set\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\b\n" +
+	"\x06_deltaB\b\n" +
+	"\x06_value\";\n" +
+	"\rUpdateRequest\x12*\n" +
+	"\x06metric\x18\x01 \x01(\v2\x12.metrics.v1.MetricR\x06metric\"\x10\n" +
+	"\x0eUpdateResponse\"/\n" +
+	"\x13UpdateBatchResponse\x12\x18\n" +
+	"\aupdated\x18\x01 \x01(\x03R\aupdated\"\xbf\x01\n" +
+	"\n" +
+	"GetRequest\x12*\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x16.metrics.v1.MetricTypeR\x04type\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12:\n" +
+	"\x06labels\x18\x03 \x03(\v2\".metrics.v1.GetRequest.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"9\n" +
+	"\vGetResponse\x12*\n" +
+	"\x06metric\x18\x01 \x01(\v2\x12.metrics.v1.MetricR\x06metric\"\r\n" +
+	"\vListRequest\"<\n" +
+	"\fListResponse\x12,\n" +
+	"\ametrics\x18\x01 \x03(\v2\x12.metrics.v1.MetricR\ametrics\"t\n" +
+	"\bMetadata\x12*\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x16.metrics.v1.MetricTypeR\x04type\x12\x12\n" +
+	"\x04help\x18\x02 \x01(\tR\x04help\x12\x12\n" +
+	"\x04unit\x18\x03 \x01(\tR\x04unit\x12\x14\n" +
+	"\x05owner\x18\x04 \x01(\tR\x05owner\"]\n" +
+	"\x15UpdateMetadataRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x120\n" +
+	"\bmetadata\x18\x02 \x01(\v2\x14.metrics.v1.MetadataR\bmetadata\"\x18\n" +
+	"\x16UpdateMetadataResponse\"(\n" +
+	"\x12GetMetadataRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"G\n" +
+	"\x13GetMetadataResponse\x120\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x14.metrics.v1.MetadataR\bmetadata*\xc9\x01\n" +
+	"\n" +
+	"MetricType\x12\x1b\n" +
+	"\x17METRIC_TYPE_UNSPECIFIED\x10\x00\x12\x17\n" +
+	"\x13METRIC_TYPE_COUNTER\x10\x01\x12\x15\n" +
+	"\x11METRIC_TYPE_GAUGE\x10\x02\x12\x19\n" +
+	"\x15METRIC_TYPE_HISTOGRAM\x10\x03\x12%\n" +
+	"!METRIC_TYPE_EXPONENTIAL_HISTOGRAM\x10\x04\x12\x17\n" +
+	"\x13METRIC_TYPE_SUMMARY\x10\x05\x12\x13\n" +
+	"\x0fMETRIC_TYPE_SET\x10\x062\xb3\x03\n" +
+	"\aMetrics\x12?\n" +
+	"\x06Update\x12\x19.metrics.v1.UpdateRequest\x1a\x1a.metrics.v1.UpdateResponse\x12K\n" +
+	"\vUpdateBatch\x12\x19.metrics.v1.UpdateRequest\x1a\x1f.metrics.v1.UpdateBatchResponse(\x01\x126\n" +
+	"\x03Get\x12\x16.metrics.v1.GetRequest\x1a\x17.metrics.v1.GetResponse\x129\n" +
+	"\x04List\x12\x17.metrics.v1.ListRequest\x1a\x18.metrics.v1.ListResponse\x12W\n" +
+	"\x0eUpdateMetadata\x12!.metrics.v1.UpdateMetadataRequest\x1a\".metrics.v1.UpdateMetadataResponse\x12N\n" +
+	"\vGetMetadata\x12\x1e.metrics.v1.GetMetadataRequest\x1a\x1f.metrics.v1.GetMetadataResponseB6Z4github.com/mikeziminio/go-custom-metrics/internal/pbb\x06proto3"
+
+var (
+	file_internal_pb_metrics_proto_rawDescOnce sync.Once
+	file_internal_pb_metrics_proto_rawDescData []byte
+)
+
+func file_internal_pb_metrics_proto_rawDescGZIP() []byte {
+	file_internal_pb_metrics_proto_rawDescOnce.Do(func() {
+		file_internal_pb_metrics_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_internal_pb_metrics_proto_rawDesc), len(file_internal_pb_metrics_proto_rawDesc)))
+	})
+	return file_internal_pb_metrics_proto_rawDescData
+}
+
+var file_internal_pb_metrics_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_internal_pb_metrics_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_internal_pb_metrics_proto_goTypes = []any{
+	(MetricType)(0),                // 0: metrics.v1.MetricType
+	(*Histogram)(nil),              // 1: metrics.v1.Histogram
+	(*ExponentialBuckets)(nil),     // 2: metrics.v1.ExponentialBuckets
+	(*ExponentialHistogram)(nil),   // 3: metrics.v1.ExponentialHistogram
+	(*Summary)(nil),                // 4: metrics.v1.Summary
+	(*Set)(nil),                    // 5: metrics.v1.Set
+	(*Metric)(nil),                 // 6: metrics.v1.Metric
+	(*UpdateRequest)(nil),          // 7: metrics.v1.UpdateRequest
+	(*UpdateResponse)(nil),         // 8: metrics.v1.UpdateResponse
+	(*UpdateBatchResponse)(nil),    // 9: metrics.v1.UpdateBatchResponse
+	(*GetRequest)(nil),             // 10: metrics.v1.GetRequest
+	(*GetResponse)(nil),            // 11: metrics.v1.GetResponse
+	(*ListRequest)(nil),            // 12: metrics.v1.ListRequest
+	(*ListResponse)(nil),           // 13: metrics.v1.ListResponse
+	(*Metadata)(nil),               // 14: metrics.v1.Metadata
+	(*UpdateMetadataRequest)(nil),  // 15: metrics.v1.UpdateMetadataRequest
+	(*UpdateMetadataResponse)(nil), // 16: metrics.v1.UpdateMetadataResponse
+	(*GetMetadataRequest)(nil),     // 17: metrics.v1.GetMetadataRequest
+	(*GetMetadataResponse)(nil),    // 18: metrics.v1.GetMetadataResponse
+	nil,                            // 19: metrics.v1.Metric.LabelsEntry
+	nil,                            // 20: metrics.v1.GetRequest.LabelsEntry
+}
+var file_internal_pb_metrics_proto_depIdxs = []int32{
+	2,  // 0: metrics.v1.ExponentialHistogram.positive:type_name -> metrics.v1.ExponentialBuckets
+	2,  // 1: metrics.v1.ExponentialHistogram.negative:type_name -> metrics.v1.ExponentialBuckets
+	2,  // 2: metrics.v1.Summary.positive:type_name -> metrics.v1.ExponentialBuckets
+	2,  // 3: metrics.v1.Summary.negative:type_name -> metrics.v1.ExponentialBuckets
+	0,  // 4: metrics.v1.Metric.type:type_name -> metrics.v1.MetricType
+	19, // 5: metrics.v1.Metric.labels:type_name -> metrics.v1.Metric.LabelsEntry
+	1,  // 6: metrics.v1.Metric.histogram:type_name -> metrics.v1.Histogram
+	3,  // 7: metrics.v1.Metric.exponential_histogram:type_name -> metrics.v1.ExponentialHistogram
+	4,  // 8: metrics.v1.Metric.summary:type_name -> metrics.v1.Summary
+	5,  // 9: metrics.v1.Metric.set:type_name -> metrics.v1.Set
+	6,  // 10: metrics.v1.UpdateRequest.metric:type_name -> metrics.v1.Metric
+	0,  // 11: metrics.v1.GetRequest.type:type_name -> metrics.v1.MetricType
+	20, // 12: metrics.v1.GetRequest.labels:type_name -> metrics.v1.GetRequest.LabelsEntry
+	6,  // 13: metrics.v1.GetResponse.metric:type_name -> metrics.v1.Metric
+	6,  // 14: metrics.v1.ListResponse.metrics:type_name -> metrics.v1.Metric
+	0,  // 15: metrics.v1.Metadata.type:type_name -> metrics.v1.MetricType
+	14, // 16: metrics.v1.UpdateMetadataRequest.metadata:type_name -> metrics.v1.Metadata
+	14, // 17: metrics.v1.GetMetadataResponse.metadata:type_name -> metrics.v1.Metadata
+	7,  // 18: metrics.v1.Metrics.Update:input_type -> metrics.v1.UpdateRequest
+	7,  // 19: metrics.v1.Metrics.UpdateBatch:input_type -> metrics.v1.UpdateRequest
+	10, // 20: metrics.v1.Metrics.Get:input_type -> metrics.v1.GetRequest
+	12, // 21: metrics.v1.Metrics.List:input_type -> metrics.v1.ListRequest
+	15, // 22: metrics.v1.Metrics.UpdateMetadata:input_type -> metrics.v1.UpdateMetadataRequest
+	17, // 23: metrics.v1.Metrics.GetMetadata:input_type -> metrics.v1.GetMetadataRequest
+	8,  // 24: metrics.v1.Metrics.Update:output_type -> metrics.v1.UpdateResponse
+	9,  // 25: metrics.v1.Metrics.UpdateBatch:output_type -> metrics.v1.UpdateBatchResponse
+	11, // 26: metrics.v1.Metrics.Get:output_type -> metrics.v1.GetResponse
+	13, // 27: metrics.v1.Metrics.List:output_type -> metrics.v1.ListResponse
+	16, // 28: metrics.v1.Metrics.UpdateMetadata:output_type -> metrics.v1.UpdateMetadataResponse
+	18, // 29: metrics.v1.Metrics.GetMetadata:output_type -> metrics.v1.GetMetadataResponse
+	24, // [24:30] is the sub-list for method output_type
+	18, // [18:24] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
+}
+
+func init() { file_internal_pb_metrics_proto_init() }
+func file_internal_pb_metrics_proto_init() {
+	if File_internal_pb_metrics_proto != nil {
+		return
+	}
+	file_internal_pb_metrics_proto_msgTypes[5].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_pb_metrics_proto_rawDesc), len(file_internal_pb_metrics_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   20,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_internal_pb_metrics_proto_goTypes,
+		DependencyIndexes: file_internal_pb_metrics_proto_depIdxs,
+		EnumInfos:         file_internal_pb_metrics_proto_enumTypes,
+		MessageInfos:      file_internal_pb_metrics_proto_msgTypes,
+	}.Build()
+	File_internal_pb_metrics_proto = out.File
+	file_internal_pb_metrics_proto_goTypes = nil
+	file_internal_pb_metrics_proto_depIdxs = nil
+}
@@ -0,0 +1,479 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+// LookbackDelta - максимальный возраст значения ряда, которое считается
+// текущим в момент вычисления выражения
+const LookbackDelta = 5 * time.Minute
+
+var ErrExecution = errors.New("execution error")
+
+// Storage - методы хранилища, необходимые для вычисления запросов.
+// ctx - контекст запроса, например для трассировки вызовов хранилища.
+type Storage interface {
+	List(ctx context.Context) map[string]model.Metric
+	Range(
+		ctx context.Context,
+		metricType model.MetricType,
+		metricName string,
+		labels model.Labels,
+		from, to time.Time,
+		step time.Duration,
+	) ([]model.Sample, error)
+}
+
+type ValueType string
+
+const (
+	ValueTypeScalar ValueType = "scalar"
+	ValueTypeVector ValueType = "vector"
+	ValueTypeMatrix ValueType = "matrix"
+)
+
+// Sample - значение ряда результата, метки включают имя метрики в MetricNameLabel
+type Sample struct {
+	Labels model.Labels
+	Value  float64
+}
+
+type Vector []Sample
+
+type Point struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+// Series - ряд результата запроса по интервалу
+type Series struct {
+	Labels model.Labels
+	Points []Point
+}
+
+type Matrix []Series
+
+// Result - результат запроса: Scalar или Vector для запроса в момент времени,
+// Matrix для запроса по интервалу
+type Result struct {
+	Type   ValueType
+	Scalar float64
+	Vector Vector
+	Matrix Matrix
+}
+
+type Engine struct {
+	storage Storage
+}
+
+func NewEngine(storage Storage) *Engine {
+	return &Engine{storage: storage}
+}
+
+// Instant - значение выражения в момент ts
+func (e *Engine) Instant(ctx context.Context, q string, ts time.Time) (*Result, error) {
+	expr, err := Parse(q)
+	if err != nil {
+		return nil, err
+	}
+	ev := &evaluator{storage: e.storage}
+	if err := ev.prefetch(ctx, expr, ts, ts); err != nil {
+		return nil, err
+	}
+	v, err := ev.eval(expr, ts)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case float64:
+		return &Result{Type: ValueTypeScalar, Scalar: v}, nil
+	case Vector:
+		return &Result{Type: ValueTypeVector, Vector: v}, nil
+	}
+	return nil, fmt.Errorf("%w: unexpected value type %T", ErrExecution, v)
+}
+
+// Range - значения выражения в моменты start, start + step, ..., не позже end.
+// Значения рядов селекторов читаются из хранилища прореженными с шагом step,
+// выборки за интервал (rate, increase) - без прореживания.
+func (e *Engine) Range(ctx context.Context, q string, start, end time.Time, step time.Duration) (*Result, error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("%w: step must be positive", ErrExecution)
+	}
+	expr, err := Parse(q)
+	if err != nil {
+		return nil, err
+	}
+	ev := &evaluator{storage: e.storage, step: step}
+	if err := ev.prefetch(ctx, expr, start, end); err != nil {
+		return nil, err
+	}
+
+	series := make(map[string]*Series)
+	for ts := start; !ts.After(end); ts = ts.Add(step) {
+		v, err := ev.eval(expr, ts)
+		if err != nil {
+			return nil, err
+		}
+		var vector Vector
+		switch v := v.(type) {
+		case float64:
+			vector = Vector{{Value: v}}
+		case Vector:
+			vector = v
+		}
+		for _, s := range vector {
+			key := s.Labels.String()
+			if _, ok := series[key]; !ok {
+				series[key] = &Series{Labels: s.Labels}
+			}
+			series[key].Points = append(series[key].Points, Point{Timestamp: ts, Value: s.Value})
+		}
+	}
+
+	matrix := make(Matrix, 0, len(series))
+	for _, key := range slices.Sorted(maps.Keys(series)) {
+		matrix = append(matrix, *series[key])
+	}
+	return &Result{Type: ValueTypeMatrix, Matrix: matrix}, nil
+}
+
+// storedSeries - значения ряда хранилища, выбранного селектором
+type storedSeries struct {
+	labels  model.Labels
+	samples []model.Sample
+}
+
+type evaluator struct {
+	storage Storage
+	step    time.Duration
+	series  map[*VectorSelector][]storedSeries
+}
+
+// prefetch - читает из хранилища значения всех рядов, выбранных селекторами выражения,
+// за интервал, необходимый для вычисления в моменты от start до end
+func (ev *evaluator) prefetch(ctx context.Context, expr Expr, start, end time.Time) error {
+	switch e := expr.(type) {
+	case *VectorSelector:
+		return ev.fetch(ctx, e, ev.fetchStart(start, LookbackDelta), end, ev.step)
+	case *MatrixSelector:
+		// после прореживания с шагом не меньше e.Range в интервал попадало бы не больше
+		// одного значения, поэтому значения читаются без прореживания
+		return ev.fetch(ctx, e.Selector, start.Add(-e.Range), end, 0)
+	case *Call:
+		return ev.prefetch(ctx, e.Arg, start, end)
+	case *AggregateExpr:
+		return ev.prefetch(ctx, e.Expr, start, end)
+	case *BinaryExpr:
+		if err := ev.prefetch(ctx, e.LHS, start, end); err != nil {
+			return err
+		}
+		return ev.prefetch(ctx, e.RHS, start, end)
+	}
+	return nil
+}
+
+// fetchStart - начало интервала чтения значений за window до start. При чтении
+// с шагом окна прореживания хранилища выравниваются так, чтобы каждое окно
+// заканчивалось сразу после момента вычисления и значение окна не было из будущего.
+func (ev *evaluator) fetchStart(start time.Time, window time.Duration) time.Time {
+	if ev.step <= 0 {
+		return start.Add(-window)
+	}
+	steps := (window + ev.step - 1) / ev.step
+	return start.Add(time.Millisecond).Add(-time.Duration(steps) * ev.step)
+}
+
+// fetch - гистограммы выбираются по рядам <имя>_bucket, <имя>_sum и <имя>_count.
+// Тип метрики доступен селекторам в метке MetricTypeLabel, в результате метка остается
+// только у рядов разных типов с одинаковыми именем и метками, чтобы они не совпадали.
+func (ev *evaluator) fetch(ctx context.Context, vs *VectorSelector, from, to time.Time, step time.Duration) error {
+	if ev.series == nil {
+		ev.series = make(map[*VectorSelector][]storedSeries)
+	}
+	var res []storedSeries
+	for _, m := range ev.storage.List(ctx) {
+		for _, sv := range m.Series() {
+			if vs.Name != "" && sv.Name != vs.Name {
+				continue
+			}
+			labels := sv.Labels.Merge(model.NewLabels(MetricNameLabel, sv.Name, MetricTypeLabel, string(m.MType)))
+			if !matches(vs.Matchers, labels) {
+				continue
+			}
+			samples, err := ev.storage.Range(ctx, m.MType, sv.Name, sv.Labels, from, to, step)
+			if errors.Is(err, model.ErrMetricNotFound) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("%w: failed to read %s: %w", ErrExecution, m.Key(), err)
+			}
+			res = append(res, storedSeries{labels: labels, samples: samples})
+		}
+	}
+	dropUniqueTypes(res)
+	slices.SortFunc(res, func(a, b storedSeries) int {
+		return strings.Compare(a.labels.String(), b.labels.String())
+	})
+	ev.series[vs] = res
+	return nil
+}
+
+// dropUniqueTypes - удаляет MetricTypeLabel у рядов, метки которых без типа не совпадают
+// с метками другого ряда
+func dropUniqueTypes(series []storedSeries) {
+	keys := make(map[string]int, len(series))
+	for _, s := range series {
+		keys[typelessKey(s.labels)]++
+	}
+	for _, s := range series {
+		if keys[typelessKey(s.labels)] == 1 {
+			delete(s.labels, MetricTypeLabel)
+		}
+	}
+}
+
+func typelessKey(labels model.Labels) string {
+	l := maps.Clone(labels)
+	delete(l, MetricTypeLabel)
+	return l.String()
+}
+
+func matches(matchers []*Matcher, labels model.Labels) bool {
+	for _, m := range matchers {
+		if !m.Matches(labels[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// eval - значение выражения в момент ts: float64 или Vector
+func (ev *evaluator) eval(expr Expr, ts time.Time) (any, error) {
+	switch e := expr.(type) {
+	case *NumberLiteral:
+		return e.Value, nil
+	case *VectorSelector:
+		return ev.evalSelector(e, ts), nil
+	case *MatrixSelector:
+		return nil, fmt.Errorf("%w: range vector must be used as function argument", ErrExecution)
+	case *Call:
+		return ev.evalCall(e, ts), nil
+	case *AggregateExpr:
+		v, err := ev.eval(e.Expr, ts)
+		if err != nil {
+			return nil, err
+		}
+		vector, ok := v.(Vector)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s expects instant vector argument", ErrExecution, e.Op)
+		}
+		return aggregate(e, vector), nil
+	case *BinaryExpr:
+		lhs, err := ev.eval(e.LHS, ts)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := ev.eval(e.RHS, ts)
+		if err != nil {
+			return nil, err
+		}
+		return binary(e.Op, lhs, rhs)
+	}
+	return nil, fmt.Errorf("%w: unexpected expression %T", ErrExecution, expr)
+}
+
+// evalSelector - последнее значение каждого ряда не старше LookbackDelta
+func (ev *evaluator) evalSelector(vs *VectorSelector, ts time.Time) Vector {
+	var res Vector
+	for _, s := range ev.series[vs] {
+		samples := samplesBetween(s.samples, ts.Add(-LookbackDelta), ts)
+		if len(samples) == 0 {
+			continue
+		}
+		res = append(res, Sample{Labels: s.labels, Value: samples[len(samples)-1].Value})
+	}
+	return res
+}
+
+// evalCall - increase и rate по значениям ряда за интервал без экстраполяции
+// на границы интервала. Уменьшение значения считается сбросом счетчика.
+func (ev *evaluator) evalCall(c *Call, ts time.Time) Vector {
+	var res Vector
+	for _, s := range ev.series[c.Arg.Selector] {
+		samples := samplesBetween(s.samples, ts.Add(-c.Arg.Range), ts)
+		if len(samples) < 2 {
+			continue
+		}
+		var increase float64
+		for i := 1; i < len(samples); i++ {
+			if samples[i].Value < samples[i-1].Value {
+				increase += samples[i].Value
+				continue
+			}
+			increase += samples[i].Value - samples[i-1].Value
+		}
+		v := increase
+		if c.Func == "rate" {
+			v = increase / c.Arg.Range.Seconds()
+		}
+		res = append(res, Sample{Labels: dropName(s.labels), Value: v})
+	}
+	return res
+}
+
+// samplesBetween - значения с метками времени в интервале (from, to]
+func samplesBetween(samples []model.Sample, from, to time.Time) []model.Sample {
+	i, _ := slices.BinarySearchFunc(samples, from, func(s model.Sample, t time.Time) int {
+		if s.Timestamp.After(t) {
+			return 1
+		}
+		return -1
+	})
+	j, _ := slices.BinarySearchFunc(samples, to, func(s model.Sample, t time.Time) int {
+		if s.Timestamp.After(t) {
+			return 1
+		}
+		return -1
+	})
+	return samples[i:j]
+}
+
+func aggregate(e *AggregateExpr, vector Vector) Vector {
+	type group struct {
+		labels model.Labels
+		value  float64
+		count  int
+	}
+	groups := make(map[string]*group)
+	for _, s := range vector {
+		var labels model.Labels
+		for _, name := range e.By {
+			if v, ok := s.Labels[name]; ok {
+				labels = labels.Merge(model.NewLabels(name, v))
+			}
+		}
+		key := labels.String()
+		g, ok := groups[key]
+		if !ok {
+			groups[key] = &group{labels: labels, value: s.Value, count: 1}
+			continue
+		}
+		g.count++
+		switch e.Op {
+		case "sum", "avg":
+			g.value += s.Value
+		case "max":
+			g.value = math.Max(g.value, s.Value)
+		case "min":
+			g.value = math.Min(g.value, s.Value)
+		}
+	}
+
+	res := make(Vector, 0, len(groups))
+	for _, key := range slices.Sorted(maps.Keys(groups)) {
+		g := groups[key]
+		v := g.value
+		switch e.Op {
+		case "avg":
+			v /= float64(g.count)
+		case "count":
+			v = float64(g.count)
+		}
+		res = append(res, Sample{Labels: g.labels, Value: v})
+	}
+	return res
+}
+
+// binary - арифметика между числами, рядами и числом или рядами с одинаковыми
+// метками (без учета имени метрики). Имя метрики в результат не попадает.
+func binary(op string, lhs, rhs any) (any, error) {
+	switch l := lhs.(type) {
+	case float64:
+		switch r := rhs.(type) {
+		case float64:
+			return arithmetic(op, l, r), nil
+		case Vector:
+			res := make(Vector, 0, len(r))
+			for _, s := range r {
+				res = append(res, Sample{Labels: dropName(s.Labels), Value: arithmetic(op, l, s.Value)})
+			}
+			return res, nil
+		}
+	case Vector:
+		switch r := rhs.(type) {
+		case float64:
+			res := make(Vector, 0, len(l))
+			for _, s := range l {
+				res = append(res, Sample{Labels: dropName(s.Labels), Value: arithmetic(op, s.Value, r)})
+			}
+			return res, nil
+		case Vector:
+			return vectorBinary(op, l, r)
+		}
+	}
+	return nil, fmt.Errorf("%w: unexpected operand types %T %s %T", ErrExecution, lhs, op, rhs)
+}
+
+func vectorBinary(op string, lhs, rhs Vector) (Vector, error) {
+	right := make(map[string]Sample, len(rhs))
+	for _, s := range rhs {
+		labels := dropName(s.Labels)
+		key := labels.String()
+		if _, ok := right[key]; ok {
+			return nil, fmt.Errorf("%w: many-to-many matching not allowed: duplicate series %s", ErrExecution, key)
+		}
+		right[key] = Sample{Labels: labels, Value: s.Value}
+	}
+	var res Vector
+	matched := make(map[string]bool, len(lhs))
+	for _, s := range lhs {
+		labels := dropName(s.Labels)
+		key := labels.String()
+		r, ok := right[key]
+		if !ok {
+			continue
+		}
+		if matched[key] {
+			return nil, fmt.Errorf("%w: many-to-many matching not allowed: duplicate series %s", ErrExecution, key)
+		}
+		matched[key] = true
+		res = append(res, Sample{Labels: labels, Value: arithmetic(op, s.Value, r.Value)})
+	}
+	return res, nil
+}
+
+func arithmetic(op string, l, r float64) float64 {
+	switch op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	case "/":
+		return l / r
+	}
+	return math.NaN()
+}
+
+func dropName(labels model.Labels) model.Labels {
+	if _, ok := labels[MetricNameLabel]; !ok {
+		return labels
+	}
+	res := maps.Clone(labels)
+	delete(res, MetricNameLabel)
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
@@ -0,0 +1,241 @@
+package query_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/memstorage"
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/query"
+	"github.com/mikeziminio/go-custom-metrics/internal/test/helper"
+)
+
+// contextStorage - хранилище в памяти с методами query.Storage
+type contextStorage struct {
+	*memstorage.MemStorage
+}
+
+func (s contextStorage) List(context.Context) map[string]model.Metric {
+	return s.MemStorage.List()
+}
+
+func (s contextStorage) Range(
+	_ context.Context,
+	metricType model.MetricType,
+	metricName string,
+	labels model.Labels,
+	from, to time.Time,
+	step time.Duration,
+) ([]model.Sample, error) {
+	return s.MemStorage.Range(metricType, metricName, labels, from, to, step)
+}
+
+// newStorage - счетчик requests растет на 1 (host a) и на 2 (host b) каждые 10 секунд
+// в течение 10 минут, gauge temperature задан для тех же хостов,
+// в гистограмму latency добавлены наблюдения 0.05 и 0.3
+func newStorage(t *testing.T, start time.Time) *memstorage.MemStorage {
+	ms := memstorage.New(0)
+	for i := range 60 {
+		ts := start.Add(time.Duration(i) * 10 * time.Second)
+		for host, delta := range map[string]int64{"a": 1, "b": 2} {
+			require.NoError(t, ms.Update(model.Metric{
+				ID:        "requests",
+				MType:     model.Counter,
+				Labels:    model.NewLabels("host", host, "dc", "eu"),
+				Delta:     helper.NewInt64(t, delta),
+				Timestamp: ts,
+			}))
+		}
+	}
+	for host, v := range map[string]float64{"a": 20, "b": 30} {
+		require.NoError(t, ms.Update(model.Metric{
+			ID:        "temperature",
+			MType:     model.Gauge,
+			Labels:    model.NewLabels("host", host, "dc", "eu"),
+			Value:     helper.NewFloat64(t, v),
+			Timestamp: start,
+		}))
+	}
+	for _, v := range []float64{0.05, 0.3} {
+		require.NoError(t, ms.Update(model.Metric{
+			ID:        "latency",
+			MType:     model.Histogram,
+			Labels:    model.NewLabels("host", "a"),
+			Value:     helper.NewFloat64(t, v),
+			Timestamp: start,
+		}))
+	}
+	return ms
+}
+
+func TestInstant(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	now := start.Add(590 * time.Second)
+	e := query.NewEngine(contextStorage{newStorage(t, start)})
+
+	testCases := []struct {
+		name           string
+		query          string
+		ts             time.Time
+		expectedType   query.ValueType
+		expectedScalar float64
+		expectedVector query.Vector
+	}{
+		{
+			name:           "scalar",
+			query:          "1 + 2 * 3",
+			expectedType:   query.ValueTypeScalar,
+			expectedScalar: 7,
+		},
+		{
+			name:         "selector",
+			query:        `requests{host="a"}`,
+			expectedType: query.ValueTypeVector,
+			expectedVector: query.Vector{
+				{Labels: model.NewLabels("__name__", "requests", "host", "a", "dc", "eu"), Value: 60},
+			},
+		},
+		{
+			name:         "increase",
+			query:        "increase(requests[1m])",
+			expectedType: query.ValueTypeVector,
+			expectedVector: query.Vector{
+				{Labels: model.NewLabels("host", "a", "dc", "eu"), Value: 5},
+				{Labels: model.NewLabels("host", "b", "dc", "eu"), Value: 10},
+			},
+		},
+		{
+			name:         "sum rate by",
+			query:        "sum by (dc) (rate(requests[100s]))",
+			expectedType: query.ValueTypeVector,
+			expectedVector: query.Vector{
+				{Labels: model.NewLabels("dc", "eu"), Value: 0.27},
+			},
+		},
+		{
+			name:         "avg and count",
+			query:        "avg(temperature) + count(temperature)",
+			ts:           start.Add(4 * time.Minute),
+			expectedType: query.ValueTypeVector,
+			expectedVector: query.Vector{
+				{Value: 27},
+			},
+		},
+		{
+			name:         "arithmetic between series",
+			query:        `requests / temperature`,
+			ts:           start.Add(4 * time.Minute),
+			expectedType: query.ValueTypeVector,
+			expectedVector: query.Vector{
+				{Labels: model.NewLabels("host", "a", "dc", "eu"), Value: 25.0 / 20},
+				{Labels: model.NewLabels("host", "b", "dc", "eu"), Value: 50.0 / 30},
+			},
+		},
+		{
+			name:         "histogram series",
+			query:        `sum(latency_bucket{le="0.1"}) / sum(latency_count)`,
+			ts:           start.Add(4 * time.Minute),
+			expectedType: query.ValueTypeVector,
+			expectedVector: query.Vector{
+				{Value: 0.5},
+			},
+		},
+		{
+			name:         "stale series",
+			query:        `temperature`,
+			expectedType: query.ValueTypeVector,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := tc.ts
+			if ts.IsZero() {
+				ts = now
+			}
+			res, err := e.Instant(context.Background(), tc.query, ts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedType, res.Type)
+			assert.InDelta(t, tc.expectedScalar, res.Scalar, 1e-9)
+			require.Len(t, res.Vector, len(tc.expectedVector))
+			for i, s := range res.Vector {
+				assert.Equal(t, tc.expectedVector[i].Labels, s.Labels)
+				assert.InDelta(t, tc.expectedVector[i].Value, s.Value, 1e-9)
+			}
+		})
+	}
+}
+
+func TestRange(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	e := query.NewEngine(contextStorage{newStorage(t, start)})
+
+	res, err := e.Range(context.Background(), `max(requests)`, start.Add(time.Minute), start.Add(3*time.Minute), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, query.ValueTypeMatrix, res.Type)
+	require.Len(t, res.Matrix, 1)
+	assert.Nil(t, res.Matrix[0].Labels)
+	assert.Equal(t, []query.Point{
+		{Timestamp: start.Add(time.Minute), Value: 14},
+		{Timestamp: start.Add(2 * time.Minute), Value: 26},
+		{Timestamp: start.Add(3 * time.Minute), Value: 38},
+	}, res.Matrix[0].Points)
+
+	_, err = e.Range(context.Background(), `max(requests)`, start, start, 0)
+	require.ErrorIs(t, err, query.ErrExecution)
+}
+
+func TestRangeRateRawSamples(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	e := query.NewEngine(contextStorage{newStorage(t, start)})
+
+	// шаг больше интервала rate: значения за интервал читаются без прореживания
+	res, err := e.Range(context.Background(), `increase(requests{host="a"}[1m])`,
+		start.Add(2*time.Minute), start.Add(4*time.Minute), 2*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, res.Matrix, 1)
+	assert.Equal(t, []query.Point{
+		{Timestamp: start.Add(2 * time.Minute), Value: 5},
+		{Timestamp: start.Add(4 * time.Minute), Value: 5},
+	}, res.Matrix[0].Points)
+}
+
+func TestMetricTypes(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	ms := newStorage(t, start)
+	require.NoError(t, ms.Update(model.Metric{
+		ID:        "requests",
+		MType:     model.Gauge,
+		Labels:    model.NewLabels("host", "a", "dc", "eu"),
+		Value:     helper.NewFloat64(t, 1),
+		Timestamp: start,
+	}))
+	e := query.NewEngine(contextStorage{ms})
+
+	// ряды разных типов с одинаковыми именем и метками различаются меткой типа
+	res, err := e.Instant(context.Background(), `requests{host="a"}`, start.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, res.Vector, 2)
+	assert.Equal(t, model.NewLabels("__name__", "requests", "__type__", "counter", "host", "a", "dc", "eu"), res.Vector[0].Labels)
+	assert.Equal(t, model.NewLabels("__name__", "requests", "__type__", "gauge", "host", "a", "dc", "eu"), res.Vector[1].Labels)
+
+	res, err = e.Instant(context.Background(), `requests{host="a",__type__="gauge"}`, start.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, res.Vector, 1)
+	assert.Equal(t, model.NewLabels("__name__", "requests", "host", "a", "dc", "eu"), res.Vector[0].Labels)
+	assert.InDelta(t, 1.0, res.Vector[0].Value, 1e-9)
+}
+
+func TestExecutionErrors(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	e := query.NewEngine(contextStorage{newStorage(t, start)})
+	_, err := e.Instant(context.Background(), "requests + requests[5m]", start)
+	require.ErrorIs(t, err, query.ErrExecution)
+	// без имени метрики ряды requests и temperature неразличимы
+	_, err = e.Instant(context.Background(), `{dc="eu"} / {dc="eu"}`, start)
+	require.ErrorIs(t, err, query.ErrExecution)
+}
@@ -0,0 +1,247 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenIdent
+	tokenNumber
+	tokenDuration
+	tokenString
+	tokenLParen
+	tokenRParen
+	tokenLBrace
+	tokenRBrace
+	tokenLBracket
+	tokenRBracket
+	tokenComma
+	tokenEQ
+	tokenNEQ
+	tokenREQ
+	tokenNREQ
+	tokenAdd
+	tokenSub
+	tokenMul
+	tokenDiv
+)
+
+type token struct {
+	typ tokenType
+	// text - исходный текст токена, для строк - значение без кавычек
+	text     string
+	number   float64
+	duration time.Duration
+	pos      int
+}
+
+// lex - разбивает выражение на токены
+func lex(input string) ([]token, error) {
+	var tokens []token
+	pos := 0
+	for pos < len(input) {
+		c := input[pos]
+		start := pos
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+			continue
+		case isIdentStart(c):
+			for pos < len(input) && isIdentChar(input[pos]) {
+				pos++
+			}
+			tokens = append(tokens, token{typ: tokenIdent, text: input[start:pos], pos: start})
+			continue
+		case isDigit(c) || c == '.' && pos+1 < len(input) && isDigit(input[pos+1]):
+			t, err := lexNumber(input, &pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			continue
+		case c == '"' || c == '\'':
+			s, err := lexString(input, &pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{typ: tokenString, text: s, pos: start})
+			continue
+		}
+
+		typ, size := lexOperator(input[pos:])
+		if size == 0 {
+			r, _ := utf8.DecodeRuneInString(input[pos:])
+			return nil, fmt.Errorf("%w: unexpected character %q at position %d", ErrParse, r, pos)
+		}
+		tokens = append(tokens, token{typ: typ, text: input[pos : pos+size], pos: pos})
+		pos += size
+	}
+	return append(tokens, token{typ: tokenEOF, pos: len(input)}), nil
+}
+
+func lexOperator(s string) (tokenType, int) {
+	switch {
+	case strings.HasPrefix(s, "!="):
+		return tokenNEQ, 2
+	case strings.HasPrefix(s, "=~"):
+		return tokenREQ, 2
+	case strings.HasPrefix(s, "!~"):
+		return tokenNREQ, 2
+	}
+	switch s[0] {
+	case '(':
+		return tokenLParen, 1
+	case ')':
+		return tokenRParen, 1
+	case '{':
+		return tokenLBrace, 1
+	case '}':
+		return tokenRBrace, 1
+	case '[':
+		return tokenLBracket, 1
+	case ']':
+		return tokenRBracket, 1
+	case ',':
+		return tokenComma, 1
+	case '=':
+		return tokenEQ, 1
+	case '+':
+		return tokenAdd, 1
+	case '-':
+		return tokenSub, 1
+	case '*':
+		return tokenMul, 1
+	case '/':
+		return tokenDiv, 1
+	}
+	return tokenEOF, 0
+}
+
+// lexNumber - число или длительность вида 5m, 1h30m
+func lexNumber(input string, pos *int) (token, error) {
+	start := *pos
+	for *pos < len(input) && (isDigit(input[*pos]) || input[*pos] == '.') {
+		*pos++
+	}
+	for *pos < len(input) && isAlnum(input[*pos]) {
+		*pos++
+	}
+	text := input[start:*pos]
+	if d, err := parseDuration(text); err == nil {
+		return token{typ: tokenDuration, text: text, duration: d, pos: start}, nil
+	}
+	// экспонента со знаком, например 1e-3
+	if *pos < len(input) && (input[*pos] == '+' || input[*pos] == '-') &&
+		(input[*pos-1] == 'e' || input[*pos-1] == 'E') {
+		*pos++
+		for *pos < len(input) && isDigit(input[*pos]) {
+			*pos++
+		}
+		text = input[start:*pos]
+	}
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return token{}, fmt.Errorf("%w: incorrect number %q at position %d", ErrParse, text, start)
+	}
+	return token{typ: tokenNumber, text: text, number: v, pos: start}, nil
+}
+
+func lexString(input string, pos *int) (string, error) {
+	start := *pos
+	quote := input[start]
+	*pos++
+	for *pos < len(input) && input[*pos] != quote {
+		if input[*pos] == '\\' {
+			*pos++
+		}
+		*pos++
+	}
+	if *pos >= len(input) {
+		return "", fmt.Errorf("%w: unterminated string at position %d", ErrParse, start)
+	}
+	*pos++
+	raw := input[start:*pos]
+	if quote == '\'' {
+		raw = `"` + strings.ReplaceAll(strings.ReplaceAll(raw[1:len(raw)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	s, err := strconv.Unquote(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: incorrect string at position %d", ErrParse, start)
+	}
+	return s, nil
+}
+
+var durationUnits = []struct {
+	suffix string
+	unit   time.Duration
+}{
+	{"ms", time.Millisecond},
+	{"s", time.Second},
+	{"m", time.Minute},
+	{"h", time.Hour},
+	{"d", 24 * time.Hour},
+	{"w", 7 * 24 * time.Hour},
+	{"y", 365 * 24 * time.Hour},
+}
+
+// parseDuration - длительность в формате Prometheus: целые числа с единицами
+// ms, s, m, h, d, w, y, например 1h30m
+func parseDuration(s string) (time.Duration, error) {
+	var d time.Duration
+	rest := s
+	for rest != "" {
+		n := 0
+		for n < len(rest) && isDigit(rest[n]) {
+			n++
+		}
+		if n == 0 {
+			return 0, fmt.Errorf("incorrect duration %q", s)
+		}
+		v, err := strconv.ParseInt(rest[:n], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("incorrect duration %q", s)
+		}
+		rest = rest[n:]
+		found := false
+		for _, u := range durationUnits {
+			if strings.HasPrefix(rest, u.suffix) {
+				d += time.Duration(v) * u.unit
+				rest = rest[len(u.suffix):]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("incorrect duration %q", s)
+		}
+	}
+	if d == 0 {
+		return 0, fmt.Errorf("incorrect duration %q", s)
+	}
+	return d, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isIdentStart - имена метрик и меток как в Prometheus, дополнительно
+// допускается точка, используемая в именах метрик Graphite и StatsD
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == ':'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '.'
+}
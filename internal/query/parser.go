@@ -0,0 +1,401 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+)
+
+var ErrParse = errors.New("parse error")
+
+// MetricNameLabel - метка с именем метрики в селекторах и результатах запроса
+const MetricNameLabel = "__name__"
+
+// MetricTypeLabel - метка с типом метрики хранилища в селекторах, например requests{__type__="counter"}
+const MetricTypeLabel = "__type__"
+
+// Expr - узел выражения
+type Expr interface {
+	expr()
+}
+
+// NumberLiteral - числовая константа
+type NumberLiteral struct {
+	Value float64
+}
+
+// VectorSelector - выборка рядов по имени и условиям на метки
+type VectorSelector struct {
+	Name     string
+	Matchers []*Matcher
+}
+
+// MatrixSelector - выборка значений рядов за интервал Range до момента вычисления
+type MatrixSelector struct {
+	Selector *VectorSelector
+	Range    time.Duration
+}
+
+// Call - вызов функции над значениями рядов за интервал
+type Call struct {
+	Func string
+	Arg  *MatrixSelector
+}
+
+// AggregateExpr - агрегация рядов с группировкой по меткам By
+type AggregateExpr struct {
+	Op   string
+	By   []string
+	Expr Expr
+}
+
+// BinaryExpr - арифметическая операция
+type BinaryExpr struct {
+	Op  string
+	LHS Expr
+	RHS Expr
+}
+
+func (*NumberLiteral) expr()  {}
+func (*VectorSelector) expr() {}
+func (*MatrixSelector) expr() {}
+func (*Call) expr()           {}
+func (*AggregateExpr) expr()  {}
+func (*BinaryExpr) expr()     {}
+
+type MatchType string
+
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+// Matcher - условие на значение метки, регулярные выражения
+// должны совпадать со значением целиком
+type Matcher struct {
+	Name  string
+	Type  MatchType
+	Value string
+	re    *regexp.Regexp
+}
+
+func NewMatcher(name string, t MatchType, value string) (*Matcher, error) {
+	m := &Matcher{Name: name, Type: t, Value: value}
+	if t == MatchRegexp || t == MatchNotRegexp {
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("%w: incorrect regexp %q: %w", ErrParse, value, err)
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+// Matches - отсутствующая метка считается меткой с пустым значением
+func (m *Matcher) Matches(v string) bool {
+	switch m.Type {
+	case MatchEqual:
+		return v == m.Value
+	case MatchNotEqual:
+		return v != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(v)
+	case MatchNotRegexp:
+		return !m.re.MatchString(v)
+	}
+	return false
+}
+
+var (
+	functions   = []string{"rate", "increase"}
+	aggregators = []string{"sum", "avg", "max", "min", "count"}
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse - разбирает выражение. Поддерживается подмножество PromQL:
+// селекторы с условиями на метки, функции rate и increase,
+// агрегации sum, avg, max, min, count с группировкой by и арифметика + - * /.
+func Parse(input string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != tokenEOF {
+		return nil, p.unexpected(t)
+	}
+	if _, ok := e.(*MatrixSelector); ok {
+		return nil, fmt.Errorf("%w: range vector must be used as function argument", ErrParse)
+	}
+	return e, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(typ tokenType) (token, error) {
+	t := p.next()
+	if t.typ != typ {
+		return t, p.unexpected(t)
+	}
+	return t, nil
+}
+
+func (p *parser) unexpected(t token) error {
+	if t.typ == tokenEOF {
+		return fmt.Errorf("%w: unexpected end of input", ErrParse)
+	}
+	return fmt.Errorf("%w: unexpected %q at position %d", ErrParse, t.text, t.pos)
+}
+
+// parseExpr - сложение и вычитание имеют меньший приоритет, чем умножение и деление
+func (p *parser) parseExpr() (Expr, error) {
+	lhs, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.typ != tokenAdd && t.typ != tokenSub {
+			return lhs, nil
+		}
+		p.next()
+		rhs, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryExpr{Op: t.text, LHS: lhs, RHS: rhs}
+	}
+}
+
+func (p *parser) parseTerm() (Expr, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.typ != tokenMul && t.typ != tokenDiv {
+			return lhs, nil
+		}
+		p.next()
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryExpr{Op: t.text, LHS: lhs, RHS: rhs}
+	}
+}
+
+// parseUnary - унарный минус вычисляется как вычитание из нуля
+func (p *parser) parseUnary() (Expr, error) {
+	if t := p.peek(); t.typ == tokenSub || t.typ == tokenAdd {
+		p.next()
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if t.typ == tokenAdd {
+			return e, nil
+		}
+		if n, ok := e.(*NumberLiteral); ok {
+			return &NumberLiteral{Value: -n.Value}, nil
+		}
+		return &BinaryExpr{Op: "-", LHS: &NumberLiteral{}, RHS: e}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.peek()
+	switch t.typ {
+	case tokenNumber:
+		p.next()
+		return &NumberLiteral{Value: t.number}, nil
+	case tokenLParen:
+		p.next()
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen); err != nil {
+			return nil, err
+		}
+		return e, nil
+	case tokenLBrace:
+		return p.parseSelector("")
+	case tokenIdent:
+		p.next()
+		next := p.peek()
+		if slices.Contains(aggregators, t.text) && (next.typ == tokenLParen || isBy(next)) {
+			return p.parseAggregate(t.text)
+		}
+		if slices.Contains(functions, t.text) && next.typ == tokenLParen {
+			return p.parseCall(t.text)
+		}
+		return p.parseSelector(t.text)
+	default:
+		return nil, p.unexpected(t)
+	}
+}
+
+func (p *parser) parseCall(name string) (Expr, error) {
+	p.next()
+	arg, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	ms, ok := arg.(*MatrixSelector)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s expects range vector argument", ErrParse, name)
+	}
+	if _, err := p.expect(tokenRParen); err != nil {
+		return nil, err
+	}
+	return &Call{Func: name, Arg: ms}, nil
+}
+
+// parseAggregate - группировка может быть указана до или после аргумента:
+// sum by (host) (x) или sum(x) by (host)
+func (p *parser) parseAggregate(op string) (Expr, error) {
+	agg := &AggregateExpr{Op: op}
+	var err error
+	if isBy(p.peek()) {
+		if agg.By, err = p.parseGrouping(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expect(tokenLParen); err != nil {
+		return nil, err
+	}
+	if agg.Expr, err = p.parseExpr(); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokenRParen); err != nil {
+		return nil, err
+	}
+	if agg.By == nil && isBy(p.peek()) {
+		if agg.By, err = p.parseGrouping(); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := agg.Expr.(*MatrixSelector); ok {
+		return nil, fmt.Errorf("%w: %s expects instant vector argument", ErrParse, op)
+	}
+	return agg, nil
+}
+
+func isBy(t token) bool {
+	return t.typ == tokenIdent && t.text == "by"
+}
+
+func (p *parser) parseGrouping() ([]string, error) {
+	p.next()
+	if _, err := p.expect(tokenLParen); err != nil {
+		return nil, err
+	}
+	labels := []string{}
+	for p.peek().typ != tokenRParen {
+		t, err := p.expect(tokenIdent)
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, t.text)
+		if p.peek().typ != tokenComma {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(tokenRParen); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+func (p *parser) parseSelector(name string) (Expr, error) {
+	vs := &VectorSelector{Name: name}
+	if p.peek().typ == tokenLBrace {
+		p.next()
+		for p.peek().typ != tokenRBrace {
+			m, err := p.parseMatcher()
+			if err != nil {
+				return nil, err
+			}
+			if m.Name == MetricNameLabel && m.Type == MatchEqual && vs.Name == "" {
+				vs.Name = m.Value
+			} else {
+				vs.Matchers = append(vs.Matchers, m)
+			}
+			if p.peek().typ != tokenComma {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.expect(tokenRBrace); err != nil {
+			return nil, err
+		}
+	}
+	if vs.Name == "" && !slices.ContainsFunc(vs.Matchers, func(m *Matcher) bool { return !m.Matches("") }) {
+		return nil, fmt.Errorf("%w: selector must contain metric name or non-empty matcher", ErrParse)
+	}
+
+	if p.peek().typ != tokenLBracket {
+		return vs, nil
+	}
+	p.next()
+	t, err := p.expect(tokenDuration)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokenRBracket); err != nil {
+		return nil, err
+	}
+	return &MatrixSelector{Selector: vs, Range: t.duration}, nil
+}
+
+func (p *parser) parseMatcher() (*Matcher, error) {
+	name, err := p.expect(tokenIdent)
+	if err != nil {
+		return nil, err
+	}
+	op := p.next()
+	var t MatchType
+	switch op.typ {
+	case tokenEQ:
+		t = MatchEqual
+	case tokenNEQ:
+		t = MatchNotEqual
+	case tokenREQ:
+		t = MatchRegexp
+	case tokenNREQ:
+		t = MatchNotRegexp
+	default:
+		return nil, p.unexpected(op)
+	}
+	value, err := p.expect(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	return NewMatcher(name.text, t, value.text)
+}
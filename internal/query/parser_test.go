@@ -0,0 +1,108 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	mustMatcher := func(name string, mt MatchType, value string) *Matcher {
+		m, err := NewMatcher(name, mt, value)
+		require.NoError(t, err)
+		return m
+	}
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected Expr
+	}{
+		{
+			name:     "selector",
+			input:    "cpu.load",
+			expected: &VectorSelector{Name: "cpu.load"},
+		},
+		{
+			name:  "selector with matchers",
+			input: `requests{host="a", path=~'/api/.*', code!="500"}`,
+			expected: &VectorSelector{
+				Name: "requests",
+				Matchers: []*Matcher{
+					mustMatcher("host", MatchEqual, "a"),
+					mustMatcher("path", MatchRegexp, "/api/.*"),
+					mustMatcher("code", MatchNotEqual, "500"),
+				},
+			},
+		},
+		{
+			name:     "name matcher",
+			input:    `{__name__="requests"}`,
+			expected: &VectorSelector{Name: "requests"},
+		},
+		{
+			name:  "rate",
+			input: "rate(requests[1h30m])",
+			expected: &Call{
+				Func: "rate",
+				Arg:  &MatrixSelector{Selector: &VectorSelector{Name: "requests"}, Range: 90 * time.Minute},
+			},
+		},
+		{
+			name:  "aggregation by before and after argument",
+			input: "sum by (host) (x) - max(y) by (host, dc)",
+			expected: &BinaryExpr{
+				Op:  "-",
+				LHS: &AggregateExpr{Op: "sum", By: []string{"host"}, Expr: &VectorSelector{Name: "x"}},
+				RHS: &AggregateExpr{Op: "max", By: []string{"host", "dc"}, Expr: &VectorSelector{Name: "y"}},
+			},
+		},
+		{
+			name:  "precedence",
+			input: "-a + b * 2 / (c - 1e-3)",
+			expected: &BinaryExpr{
+				Op:  "+",
+				LHS: &BinaryExpr{Op: "-", LHS: &NumberLiteral{}, RHS: &VectorSelector{Name: "a"}},
+				RHS: &BinaryExpr{
+					Op: "/",
+					LHS: &BinaryExpr{
+						Op:  "*",
+						LHS: &VectorSelector{Name: "b"},
+						RHS: &NumberLiteral{Value: 2},
+					},
+					RHS: &BinaryExpr{Op: "-", LHS: &VectorSelector{Name: "c"}, RHS: &NumberLiteral{Value: 0.001}},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := Parse(tc.input)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, e)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"a +",
+		"rate(a)",
+		"sum(a[5m])",
+		"a[5m]",
+		"a[5x]",
+		`a{host="x"`,
+		`a{host=x}`,
+		`{host=~".*"}`,
+		`a{host=~"("}`,
+		"a $ b",
+		"sum by host (a)",
+	} {
+		_, err := Parse(input)
+		require.ErrorIs(t, err, ErrParse, input)
+	}
+}
@@ -2,15 +2,158 @@ package config
 
 import (
 	"flag"
+	"strings"
+	"time"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/graphite"
+	"github.com/mikeziminio/go-custom-metrics/internal/telemetry"
+	"github.com/mikeziminio/go-custom-metrics/internal/tracing"
 )
 
 type Config struct {
-	Address string
+	Address                 string
+	StatsdAddress           string
+	StatsdFlushInterval     float64
+	GraphiteAddress         string
+	GraphiteTemplates       StringList
+	GraphiteSeparator       string
+	GraphiteCounterSuffixes StringList
+	GRPCAddress             string
+	TrustedSubnet           string
+	TrustedProxies          StringList
+	Key                     string
+	HistoryRetention        time.Duration
+	RollupTiers             StringList
+	TelemetryInterval       time.Duration
+	ShutdownDelay           time.Duration
+	WebSocketOrigins        StringList
+	PprofAddress            string
+	TraceExporter           string
+	TraceEndpoint           string
+}
+
+var (
+	DefaultStatsdFlushInterval = 10.0
+	DefaultHistoryRetention    = 24 * time.Hour
+)
+
+// StringList - значение флага, который можно указать несколько раз
+type StringList []string
+
+func (l *StringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *StringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
 }
 
 func NewFromFlags() *Config {
 	c := Config{}
 	flag.StringVar(&c.Address, "a", "localhost:8080", "хост:порт http сервера")
+	flag.StringVar(
+		&c.StatsdAddress,
+		"statsd-address",
+		"",
+		"хост:порт UDP сервера StatsD, если не задан - сервер не запускается",
+	)
+	flag.Float64Var(
+		&c.StatsdFlushInterval,
+		"statsd-flush-interval",
+		DefaultStatsdFlushInterval,
+		"частота сброса агрегированных метрик StatsD в хранилище",
+	)
+	flag.StringVar(
+		&c.GraphiteAddress,
+		"graphite-address",
+		"",
+		"хост:порт TCP сервера Graphite, если не задан - сервер не запускается",
+	)
+	flag.Var(
+		&c.GraphiteTemplates,
+		"graphite-template",
+		"шаблон преобразования пути Graphite в имя метрики, например \"servers.* .host.measurement*\", "+
+			"можно указать несколько раз",
+	)
+	flag.StringVar(
+		&c.GraphiteSeparator,
+		"graphite-separator",
+		graphite.DefaultSeparator,
+		"разделитель частей имени метрики, собранного по шаблону Graphite",
+	)
+	flag.Var(
+		&c.GraphiteCounterSuffixes,
+		"graphite-counter-suffix",
+		"суффикс имени метрики Graphite, при котором она сохраняется как counter, можно указать несколько раз",
+	)
+	flag.StringVar(
+		&c.GRPCAddress,
+		"grpc-address",
+		"",
+		"хост:порт gRPC сервера, если не задан - сервер не запускается",
+	)
+	flag.StringVar(
+		&c.TrustedSubnet,
+		"t",
+		"",
+		"доверенная подсеть в нотации CIDR для запросов gRPC, если не задана - проверка не выполняется",
+	)
+	flag.Var(
+		&c.TrustedProxies,
+		"trusted-proxy",
+		"подсеть прокси в нотации CIDR, которому разрешено передавать IP клиента в метаданных x-real-ip, "+
+			"можно указать несколько раз",
+	)
+	flag.StringVar(&c.Key, "k", "", "ключ подписи запросов gRPC, если не задан - подпись не проверяется")
+	flag.DurationVar(
+		&c.HistoryRetention,
+		"history-retention",
+		DefaultHistoryRetention,
+		"время хранения истории значений метрик, 0 - без ограничений",
+	)
+	flag.Var(
+		&c.RollupTiers,
+		"rollup-tier",
+		"уровень прореживания истории в формате <интервал>=<время хранения>, например 5m=720h, "+
+			"можно указать несколько раз, по умолчанию 1m=168h, 5m=720h и 1h=9600h",
+	)
+	flag.DurationVar(
+		&c.TelemetryInterval,
+		"telemetry-interval",
+		0,
+		"частота выгрузки собственных метрик сервера в хранилище с префиксом "+telemetry.Prefix+", 0 - не выгружать",
+	)
+	flag.DurationVar(
+		&c.ShutdownDelay,
+		"shutdown-delay",
+		0,
+		"время между переходом /readyz в состояние \"не готов\" и остановкой http сервера при завершении",
+	)
+	flag.Var(
+		&c.WebSocketOrigins,
+		"ws-origin",
+		"шаблон хоста источника (Origin), странице которого разрешено подключаться к /api/v1/ws, например *.example.com, "+
+			"можно указать несколько раз, хост самого сервера разрешен всегда",
+	)
+	flag.StringVar(
+		&c.PprofAddress,
+		"pprof-address",
+		"",
+		"хост:порт служебного http сервера с профилированием /debug/pprof/, если не задан - сервер не запускается",
+	)
+	flag.StringVar(
+		&c.TraceExporter,
+		"trace-exporter",
+		tracing.ExporterNone,
+		"экспортер спанов трассировки: none, stdout, file, otlp-http, otlp-grpc",
+	)
+	flag.StringVar(
+		&c.TraceEndpoint,
+		"trace-endpoint",
+		"",
+		"путь к файлу для экспортера file, URL коллектора для otlp-http и otlp-grpc",
+	)
 	flag.Parse()
 
 	return &c
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(dashboardHTML))
+
+type dashboardData struct {
+	Rows []dashboardRow
+}
+
+// dashboardRow - строка таблицы метрик, SortValue - числовое значение для сортировки по столбцу Value,
+// ChartQuery - выражение для графика ряда в интерфейсе графиков
+type dashboardRow struct {
+	Name        string
+	ChartQuery  string
+	Type        model.MetricType
+	Value       string
+	SortValue   float64
+	Help        string
+	Unit        string
+	Updated     string
+	UpdatedUnix int64
+}
+
+// acceptsHTML - клиент явно принимает text/html, например браузер
+func acceptsHTML(req *http.Request) bool {
+	for _, v := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(v))
+		if err == nil && mediaType == "text/html" && params["q"] != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeDashboard - HTML страница с таблицей метрик, сортировка, фильтрация и
+// автообновление выполняются на стороне клиента. Метрики передаются уже упорядоченными.
+func (a *APIServer) writeDashboard(res http.ResponseWriter, metrics []model.Metric, metadata map[string]model.Metadata) {
+	data := dashboardData{Rows: make([]dashboardRow, 0, len(metrics))}
+	for _, m := range metrics {
+		row := dashboardRow{
+			Name: m.ID + m.Labels.String(),
+			Type: m.MType,
+		}
+		row.Value, row.SortValue = dashboardValue(m)
+		// ряды гистограмм и summary выбираются по суффиксам, см. model.Metric.Series
+		if m.MType == model.Gauge || m.MType == model.Counter || m.MType == model.Set {
+			row.ChartQuery = row.Name
+		}
+		if md, ok := metadata[m.ID]; ok {
+			row.Help, row.Unit = md.Help, md.Unit
+		}
+		if !m.Timestamp.IsZero() {
+			row.Updated = m.Timestamp.UTC().Format(time.DateTime)
+			row.UpdatedUnix = m.Timestamp.Unix()
+		}
+		data.Rows = append(data.Rows, row)
+	}
+
+	var b bytes.Buffer
+	if err := dashboardTemplate.Execute(&b, data); err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		a.logger.Error("failed to render dashboard", zap.Error(err))
+		return
+	}
+	res.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := res.Write(b.Bytes()); err != nil {
+		a.logger.Error("failed to write body", zap.Error(err))
+	}
+}
+
+// dashboardValue - значение метрики для таблицы, для гистограмм и summary - количество
+// наблюдений и сумма, сортируются они по количеству наблюдений
+func dashboardValue(m model.Metric) (string, float64) {
+	switch m.MType {
+	case model.Gauge:
+		return strconv.FormatFloat(*m.Value, 'f', -1, 64), *m.Value
+	case model.Counter:
+		return strconv.FormatInt(*m.Delta, 10), float64(*m.Delta)
+	case model.Set:
+		n := m.Set.Estimate()
+		return strconv.FormatUint(n, 10), float64(n)
+	}
+	var sum float64
+	var count uint64
+	switch {
+	case m.Histogram != nil:
+		sum, count = m.Histogram.Sum, m.Histogram.Count
+	case m.ExponentialHistogram != nil:
+		sum, count = m.ExponentialHistogram.Sum, m.ExponentialHistogram.Count
+	case m.Summary != nil:
+		sum, count = m.Summary.Sum, m.Summary.Count
+	}
+	return fmt.Sprintf("count %d, sum %s", count, strconv.FormatFloat(sum, 'f', -1, 64)), float64(count)
+}
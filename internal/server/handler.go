@@ -2,20 +2,33 @@ package server
 
 import (
 	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 	"go.uber.org/zap"
 
+	"github.com/mikeziminio/go-custom-metrics/internal/influx"
 	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/otlp"
 )
 
 func (a *APIServer) Update(res http.ResponseWriter, req *http.Request) {
-	a.logger.Info("request start", zap.String("path", req.URL.Path))
-
 	mt := chi.URLParam(req, "metricType")
 	metricType, err := model.NewMetricTypeFromString(mt)
 	if err != nil {
@@ -23,59 +36,70 @@ func (a *APIServer) Update(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 	metricName := chi.URLParam(req, "metricName")
-	var delta *int64
-	var value *float64
+	labels, err := labelsFromQuery(req.URL.Query())
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	m := model.Metric{
+		ID:     metricName,
+		MType:  metricType,
+		Labels: labels,
+	}
 
 	val := chi.URLParam(req, "value")
-	if metricType == model.Counter {
+	switch metricType {
+	case model.Counter:
 		d, err := strconv.ParseInt(val, 10, 64)
 		if err != nil {
 			res.WriteHeader(http.StatusBadRequest)
 			return
 		}
-		delta = &d
-	}
-
-	if metricType == model.Gauge {
+		m.Delta = &d
+	case model.Set:
+		// для множества значение - добавляемый элемент
+		m.Members = []string{val}
+	default:
+		// для гистограмм и summary значение - одно наблюдение
 		v, err := strconv.ParseFloat(val, 64)
 		if err != nil {
 			res.WriteHeader(http.StatusBadRequest)
 			return
 		}
-		value = &v
+		m.Value = &v
 	}
 
-	err = a.storage.Update(model.Metric{
-		ID:    metricName,
-		MType: metricType,
-		Delta: delta,
-		Value: value,
-	})
+	err = a.tracedStorage(req.Context()).Update(m)
 	if err != nil {
 		res.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
 	res.WriteHeader(http.StatusOK)
-	a.logger.Info("request end")
 }
 
+// List - все метрики в текстовом формате, для браузера - HTML страница с таблицей метрик
 func (a *APIServer) List(res http.ResponseWriter, req *http.Request) {
-	a.logger.Info("request start", zap.String("path", req.URL.Path))
+	storage := a.tracedStorage(req.Context())
+	metrics := storage.List()
+	metadata := storage.ListMetadata()
+	a.logger.Info("metrics", zap.Int("len", len(metrics)))
+	// ряды одной метрики выводятся подряд после ее метаданных
+	sorted := sortMetrics(metrics)
+	if acceptsHTML(req) {
+		a.writeDashboard(res, sorted, metadata)
+		return
+	}
 
 	var b bytes.Buffer
-	metrics := a.storage.List()
-	a.logger.Info("metrics", zap.Int("len", len(metrics)))
-	for id, m := range metrics {
-		b.WriteString(id)
-		switch m.MType {
-		case model.Gauge:
-			b.WriteString(fmt.Sprintf(" %.5f\n", *m.Value))
-		case model.Counter:
-			b.WriteString(fmt.Sprintf(" %d\n", *m.Delta))
+	for i, m := range sorted {
+		if md, ok := metadata[m.ID]; ok && (i == 0 || sorted[i-1].ID != m.ID) {
+			writeMetadataText(&b, m.ID, md)
 		}
+		writeMetricText(&b, m)
 	}
 
+	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	_, err := res.Write(b.Bytes())
 	if err != nil {
 		res.WriteHeader(http.StatusInternalServerError)
@@ -83,9 +107,10 @@ func (a *APIServer) List(res http.ResponseWriter, req *http.Request) {
 	}
 }
 
-func (a *APIServer) Get(res http.ResponseWriter, req *http.Request) {
-	a.logger.Info("request start", zap.String("path", req.URL.Path))
+// paramQuantile - параметр запроса значения метрики с квантилем, метки передаются в параметрах с model.LabelParamPrefix
+const paramQuantile = "q"
 
+func (a *APIServer) Get(res http.ResponseWriter, req *http.Request) {
 	mt := chi.URLParam(req, "metricType")
 	metricType, err := model.NewMetricTypeFromString(mt)
 	if err != nil {
@@ -93,8 +118,25 @@ func (a *APIServer) Get(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 	metricName := chi.URLParam(req, "metricName")
+	query := req.URL.Query()
+	// для экспоненциальной гистограммы и summary параметр q - квантиль, значение которого нужно вернуть
+	var quantile *float64
+	if (metricType == model.ExponentialHistogram || metricType == model.Summary) && query.Has(paramQuantile) {
+		q, err := strconv.ParseFloat(query.Get(paramQuantile), 64)
+		if err != nil || q < 0 || q > 1 {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		quantile = &q
+		query.Del(paramQuantile)
+	}
+	labels, err := labelsFromQuery(query)
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
 
-	m, err := a.storage.Get(metricType, metricName)
+	m, err := a.tracedStorage(req.Context()).Get(metricType, metricName, labels)
 	if err != nil {
 		if errors.Is(err, model.ErrMetricNotFound) {
 			res.WriteHeader(http.StatusNotFound)
@@ -105,10 +147,26 @@ func (a *APIServer) Get(res http.ResponseWriter, req *http.Request) {
 	}
 
 	var r string
-	if m.MType == model.Gauge {
+	switch m.MType {
+	case model.Gauge:
 		r = strconv.FormatFloat(*m.Value, 'f', -1, 64)
-	} else {
+	case model.Counter:
 		r = fmt.Sprintf("%d", *m.Delta)
+	case model.Set:
+		r = strconv.FormatUint(m.Set.Estimate(), 10)
+	case model.Histogram, model.ExponentialHistogram, model.Summary:
+		if quantile != nil {
+			v, err := m.Quantile(*quantile)
+			if err != nil {
+				res.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			r = strconv.FormatFloat(v, 'f', -1, 64)
+			break
+		}
+		var b bytes.Buffer
+		writeMetricText(&b, *m)
+		r = b.String()
 	}
 
 	_, err = res.Write([]byte(r))
@@ -117,3 +175,292 @@ func (a *APIServer) Get(res http.ResponseWriter, req *http.Request) {
 		a.logger.Error("failed to write body", zap.Error(err))
 	}
 }
+
+// writeMetricText - строки "<имя><метки> <значение>", гистограммы и summary
+// выводятся рядами, см. model.Metric.Series
+func writeMetricText(b *bytes.Buffer, m model.Metric) {
+	buf := b.AvailableBuffer()
+	switch m.MType {
+	case model.Gauge:
+		buf = appendSeriesName(buf, m.ID, m.Labels)
+		buf = strconv.AppendFloat(buf, *m.Value, 'f', 5, 64)
+		buf = append(buf, '\n')
+	case model.Counter:
+		buf = appendSeriesName(buf, m.ID, m.Labels)
+		buf = strconv.AppendInt(buf, *m.Delta, 10)
+		buf = append(buf, '\n')
+	case model.Set:
+		buf = appendSeriesName(buf, m.ID, m.Labels)
+		buf = strconv.AppendUint(buf, m.Set.Estimate(), 10)
+		buf = append(buf, '\n')
+	case model.Histogram, model.ExponentialHistogram, model.Summary:
+		for _, sv := range m.Series() {
+			buf = appendSeriesName(buf, sv.Name, sv.Labels)
+			buf = strconv.AppendFloat(buf, sv.Value, 'f', -1, 64)
+			buf = append(buf, '\n')
+		}
+	}
+	b.Write(buf)
+}
+
+// appendSeriesName - "<имя><метки> "
+func appendSeriesName(b []byte, name string, labels model.Labels) []byte {
+	b = append(b, name...)
+	b = labels.Append(b)
+	return append(b, ' ')
+}
+
+// writeMetadataText - строки "# HELP <имя> <описание>" и "# UNIT <имя> <единица>"
+// для заданных полей, как в текстовом формате Prometheus
+func writeMetadataText(b *bytes.Buffer, name string, md model.Metadata) {
+	if md.Help != "" {
+		b.WriteString("# HELP ")
+		b.WriteString(name)
+		b.WriteByte(' ')
+		_, _ = helpEscaper.WriteString(b, md.Help) // запись в bytes.Buffer не возвращает ошибок
+		b.WriteByte('\n')
+	}
+	if md.Unit != "" {
+		b.WriteString("# UNIT ")
+		b.WriteString(name)
+		b.WriteByte(' ')
+		b.WriteString(md.Unit)
+		b.WriteByte('\n')
+	}
+}
+
+// sortMetrics - метрики по имени, ряды одной метрики - по идентификатору ряда.
+// Идентификатор вычисляется один раз для ряда, а не при каждом сравнении.
+func sortMetrics(metrics map[string]model.Metric) []model.Metric {
+	values := slices.AppendSeq(make([]model.Metric, 0, len(metrics)), maps.Values(metrics))
+	keys := make([]string, len(values))
+	order := make([]int, len(values))
+	for i := range values {
+		keys[i] = values[i].Key()
+		order[i] = i
+	}
+	slices.SortFunc(order, func(i, j int) int {
+		return cmp.Or(cmp.Compare(values[i].ID, values[j].ID), cmp.Compare(keys[i], keys[j]))
+	})
+	res := make([]model.Metric, len(order))
+	for i, j := range order {
+		res[i] = values[j]
+	}
+	return res
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// UpdateJSON - обновление метрики, переданной в теле запроса в формате JSON,
+// в ответе - значение метрики после обновления
+func (a *APIServer) UpdateJSON(res http.ResponseWriter, req *http.Request) {
+	var m model.Metric
+	if err := json.NewDecoder(req.Body).Decode(&m); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := m.Validate(); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := a.tracedStorage(req.Context()).Update(m); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.writeMetricJSON(req.Context(), res, m.MType, m.ID, m.Labels)
+}
+
+// ValueJSON - значение метрики, тип, имя и метки которой переданы в теле запроса в формате JSON
+func (a *APIServer) ValueJSON(res http.ResponseWriter, req *http.Request) {
+	var m model.Metric
+	if err := json.NewDecoder(req.Body).Decode(&m); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.writeMetricJSON(req.Context(), res, m.MType, m.ID, m.Labels)
+}
+
+func (a *APIServer) writeMetricJSON(
+	ctx context.Context,
+	res http.ResponseWriter,
+	metricType model.MetricType,
+	id string,
+	labels model.Labels,
+) {
+	m, err := a.tracedStorage(ctx).Get(metricType, id, labels)
+	if err != nil {
+		if errors.Is(err, model.ErrMetricNotFound) {
+			http.Error(res, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(res).Encode(m); err != nil {
+		a.logger.Error("failed to write body", zap.Error(err))
+	}
+}
+
+// Write - прием метрик в формате line protocol InfluxDB (совместим с выходом influxdb в Telegraf).
+// Каждое поле точки сохраняется отдельной метрикой, при ошибке разбора
+// хотя бы одной строки запрос отклоняется целиком. Метрики, отклоненные хранилищем,
+// пропускаются, остальные сохраняются, а в ответе сообщается число отклоненных.
+func (a *APIServer) Write(res http.ResponseWriter, req *http.Request) {
+	precision, err := influx.Precision(req.URL.Query().Get("precision"))
+	if err != nil {
+		a.writeInfluxError(res, http.StatusBadRequest, err)
+		return
+	}
+
+	body, err := requestBody(res, req)
+	if err != nil {
+		a.writeInfluxError(res, http.StatusBadRequest, err)
+		return
+	}
+	defer body.Close() //nolint:errcheck // it's ok
+
+	points, err := influx.Parse(body, precision, time.Now())
+	if err != nil {
+		a.writeInfluxError(res, bodyErrorStatus(err), err)
+		return
+	}
+
+	// вызовы хранилища не записываются отдельными спанами: в пакете могут быть тысячи метрик
+	trace.SpanFromContext(req.Context()).SetAttributes(attribute.Int("points", len(points)))
+	var (
+		total, rejected int
+		firstErr        error
+	)
+	for _, p := range points {
+		for _, m := range p.Metrics() {
+			total++
+			if err := a.storage.Update(m); err != nil {
+				rejected++
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+	}
+
+	if rejected > 0 {
+		// как и InfluxDB, отвечаем 400 на частичную запись: принятые метрики уже сохранены,
+		// и повтор запроса клиентом учел бы их дважды
+		err := fmt.Errorf("partial write: %d of %d metrics rejected: %w", rejected, total, firstErr)
+		a.writeInfluxError(res, http.StatusBadRequest, err)
+		return
+	}
+
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func (a *APIServer) writeInfluxError(res http.ResponseWriter, status int, err error) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	err = json.NewEncoder(res).Encode(map[string]string{"error": err.Error()})
+	if err != nil {
+		a.logger.Error("failed to write body", zap.Error(err))
+	}
+}
+
+// OTLPMetrics - прием метрик OpenTelemetry по OTLP/HTTP в кодировках protobuf и JSON.
+// Точки неподдерживаемых типов отклоняются и учитываются в partial_success ответа.
+func (a *APIServer) OTLPMetrics(res http.ResponseWriter, req *http.Request) {
+	mediaType, err := otlp.MediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := requestBody(res, req)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer body.Close() //nolint:errcheck // it's ok
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(res, err.Error(), bodyErrorStatus(err))
+		return
+	}
+
+	exportReq, err := otlp.DecodeRequest(data, mediaType)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rejected, reason := a.otlpReceiver.Export(req.Context(), exportReq)
+
+	exportRes := &colmetricspb.ExportMetricsServiceResponse{}
+	if rejected > 0 {
+		exportRes.PartialSuccess = &colmetricspb.ExportMetricsPartialSuccess{
+			RejectedDataPoints: rejected,
+			ErrorMessage:       reason,
+		}
+	}
+	out, err := otlp.EncodeResponse(exportRes, mediaType)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		a.logger.Error("failed to encode response", zap.Error(err))
+		return
+	}
+
+	res.Header().Set("Content-Type", mediaType)
+	_, err = res.Write(out)
+	if err != nil {
+		a.logger.Error("failed to write body", zap.Error(err))
+	}
+}
+
+// maxBodySize - наибольший размер тела запроса с пакетом метрик, до и после распаковки
+const maxBodySize = 8 << 20
+
+// requestBody - тело запроса с учетом сжатия gzip
+func requestBody(res http.ResponseWriter, req *http.Request) (io.ReadCloser, error) {
+	body := http.MaxBytesReader(res, req.Body, maxBodySize)
+	if req.Header.Get("Content-Encoding") != "gzip" {
+		return body, nil
+	}
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init gzip reader: %w", err)
+	}
+	// ограничение распакованного тела, сжатое тело ограничено выше
+	return http.MaxBytesReader(res, gz, maxBodySize), nil
+}
+
+// bodyErrorStatus - код ответа на ошибку чтения тела запроса
+func bodyErrorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// labelsFromQuery - метки метрики из параметров запроса с префиксом model.LabelParamPrefix,
+// каждая метка должна быть указана не более одного раза. Параметры без префикса,
+// не разобранные обработчиком, считаются ошибкой.
+func labelsFromQuery(q url.Values) (model.Labels, error) {
+	if len(q) == 0 {
+		return nil, nil
+	}
+	labels := make(model.Labels, len(q))
+	for param, values := range q {
+		name, ok := strings.CutPrefix(param, model.LabelParamPrefix)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown parameter %q, labels are passed as %s<name>",
+				model.ErrInvalidLabel, param, model.LabelParamPrefix)
+		}
+		if len(values) != 1 {
+			return nil, fmt.Errorf("%w: label %q must have exactly one value", model.ErrInvalidLabel, name)
+		}
+		labels[name] = values[0]
+	}
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
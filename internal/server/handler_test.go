@@ -1,16 +1,34 @@
 package server
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/coder/websocket"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 
 	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/telemetry"
 	"github.com/mikeziminio/go-custom-metrics/internal/test/helper"
 )
 
@@ -20,6 +38,7 @@ func TestUpdate(t *testing.T) {
 		metricType         model.MetricType
 		metricName         string
 		metricValue        string
+		query              string
 		metric             *model.Metric
 		storageReturnError error
 		expectedStatus     int
@@ -53,19 +72,69 @@ func TestUpdate(t *testing.T) {
 			storageReturnError: nil,
 			expectedStatus:     200,
 		},
+		{
+			name:        "update gauge value with labels",
+			metricType:  model.Gauge,
+			metricName:  "some",
+			metricValue: "1",
+			query:       "?label.host=a&label.service=b",
+			metric: &model.Metric{
+				ID:     "some",
+				MType:  model.Gauge,
+				Labels: model.NewLabels("host", "a", "service", "b"),
+				Value:  helper.NewFloat64(t, 1),
+			},
+			storageReturnError: nil,
+			expectedStatus:     200,
+		},
+		{
+			name:        "observe histogram value",
+			metricType:  model.Histogram,
+			metricName:  "latency",
+			metricValue: "0.25",
+			metric: &model.Metric{
+				ID:    "latency",
+				MType: model.Histogram,
+				Value: helper.NewFloat64(t, 0.25),
+			},
+			storageReturnError: nil,
+			expectedStatus:     200,
+		},
+		{
+			name:           "observe histogram NaN",
+			metricType:     model.Histogram,
+			metricName:     "latency",
+			metricValue:    "NaN",
+			expectedStatus: 400,
+		},
+		{
+			name:        "add set member",
+			metricType:  model.Set,
+			metricName:  "users",
+			metricValue: "alice",
+			metric: &model.Metric{
+				ID:      "users",
+				MType:   model.Set,
+				Members: []string{"alice"},
+			},
+			storageReturnError: nil,
+			expectedStatus:     200,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			storage := NewMockStorage(t)
-			storage.EXPECT().Update(*tc.metric).
-				Return(tc.storageReturnError).
-				Once()
+			if tc.metric != nil {
+				storage.EXPECT().Update(*tc.metric).
+					Return(tc.storageReturnError).
+					Once()
+			}
 
 			server := New("", storage, zap.L())
 			server.RegisterRoutes()
 
-			path := fmt.Sprintf("/update/%s/%s/%s", tc.metricType, tc.metricName, tc.metricValue)
+			path := fmt.Sprintf("/update/%s/%s/%s%s", tc.metricType, tc.metricName, tc.metricValue, tc.query)
 			req := httptest.NewRequest(http.MethodPost, path, http.NoBody)
 			rec := httptest.NewRecorder()
 
@@ -76,11 +145,27 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestLabelsFromQuery(t *testing.T) {
+	labels, err := labelsFromQuery(url.Values{"label.host": {"a"}, "label.service.name": {"api"}})
+	require.NoError(t, err)
+	assert.Equal(t, model.NewLabels("host", "a", "service.name", "api"), labels)
+
+	// параметры без префикса не становятся метками
+	_, err = labelsFromQuery(url.Values{"utm_source": {"mail"}})
+	require.ErrorIs(t, err, model.ErrInvalidLabel)
+	_, err = labelsFromQuery(url.Values{"label.host": {"a", "b"}})
+	require.ErrorIs(t, err, model.ErrInvalidLabel)
+	_, err = labelsFromQuery(url.Values{"label.": {"a"}})
+	require.ErrorIs(t, err, model.ErrInvalidLabel)
+}
+
 func TestGet(t *testing.T) {
 	testCases := []struct {
 		name                string
 		metricType          model.MetricType
 		metricName          string
+		query               string
+		labels              model.Labels
 		storageReturnMetric *model.Metric
 		storageReturnError  error
 		expectedStatus      int
@@ -123,19 +208,109 @@ func TestGet(t *testing.T) {
 			expectedStatus:     200,
 			expectedBody:       "64.555",
 		},
+		{
+			name:       "gauge value with labels",
+			metricType: model.Gauge,
+			metricName: "some",
+			query:      "?label.host=a",
+			labels:     model.NewLabels("host", "a"),
+			storageReturnMetric: &model.Metric{
+				ID:     "some",
+				MType:  model.Gauge,
+				Labels: model.NewLabels("host", "a"),
+				Value:  helper.NewFloat64(t, 1.5),
+			},
+			storageReturnError: nil,
+			expectedStatus:     200,
+			expectedBody:       "1.5",
+		},
+		{
+			name:       "histogram value",
+			metricType: model.Histogram,
+			metricName: "latency",
+			storageReturnMetric: &model.Metric{
+				ID:    "latency",
+				MType: model.Histogram,
+				Histogram: &model.HistogramData{
+					Bounds: []float64{0.5},
+					Counts: []uint64{1, 1},
+					Sum:    1.25,
+					Count:  2,
+				},
+			},
+			storageReturnError: nil,
+			expectedStatus:     200,
+			expectedBody: `latency_bucket{le="0.5"} 1
+latency_bucket{le="+Inf"} 2
+latency_sum 1.25
+latency_count 2
+`,
+		},
+		{
+			name:       "exponential histogram quantile",
+			metricType: model.ExponentialHistogram,
+			metricName: "latency",
+			query:      "?q=0.5&label.host=a",
+			labels:     model.NewLabels("host", "a"),
+			storageReturnMetric: &model.Metric{
+				ID:     "latency",
+				MType:  model.ExponentialHistogram,
+				Labels: model.NewLabels("host", "a"),
+				ExponentialHistogram: &model.ExponentialHistogramData{
+					Positive: model.ExponentialBuckets{Offset: 1, Counts: []uint64{2}},
+					Sum:      6,
+					Count:    2,
+				},
+			},
+			storageReturnError: nil,
+			expectedStatus:     200,
+			expectedBody:       "2.8284271247461903",
+		},
+		{
+			name:       "summary quantile",
+			metricType: model.Summary,
+			metricName: "latency",
+			query:      "?q=0.99",
+			storageReturnMetric: &model.Metric{
+				ID:    "latency",
+				MType: model.Summary,
+				Summary: &model.SummaryData{
+					RelativeAccuracy: 0.5,
+					Positive:         model.ExponentialBuckets{Offset: 1, Counts: []uint64{1}},
+					Sum:              2,
+					Count:            1,
+				},
+			},
+			storageReturnError: nil,
+			expectedStatus:     200,
+			expectedBody:       "1.5",
+		},
+		{
+			name:       "set value",
+			metricType: model.Set,
+			metricName: "users",
+			storageReturnMetric: &model.Metric{
+				ID:    "users",
+				MType: model.Set,
+				Set:   newSet(t, "alice", "bob"),
+			},
+			storageReturnError: nil,
+			expectedStatus:     200,
+			expectedBody:       "2",
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			storage := NewMockStorage(t)
-			storage.EXPECT().Get(tc.metricType, tc.metricName).
+			storage.EXPECT().Get(tc.metricType, tc.metricName, tc.labels).
 				Return(tc.storageReturnMetric, tc.storageReturnError).
 				Once()
 
 			server := New("", storage, zap.L())
 			server.RegisterRoutes()
 
-			path := fmt.Sprintf("/value/%s/%s", tc.metricType, tc.metricName)
+			path := fmt.Sprintf("/value/%s/%s%s", tc.metricType, tc.metricName, tc.query)
 			req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
 			rec := httptest.NewRecorder()
 
@@ -148,6 +323,18 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func newSet(t *testing.T, members ...string) *model.SetData {
+	t.Helper()
+	s, err := model.NewSet(model.DefaultSetPrecision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range members {
+		s.Add(member)
+	}
+	return s
+}
+
 func TestList(t *testing.T) {
 	storage := NewMockStorage(t)
 	storage.EXPECT().List().
@@ -162,6 +349,17 @@ func TestList(t *testing.T) {
 				MType: model.Counter,
 				Delta: helper.NewInt64(t, 64),
 			},
+			"labeled": {
+				ID:     "other",
+				MType:  model.Counter,
+				Labels: model.NewLabels("host", "a"),
+				Delta:  helper.NewInt64(t, 2),
+			},
+		}).
+		Once()
+	storage.EXPECT().ListMetadata().
+		Return(map[string]model.Metadata{
+			"other": {Type: model.Counter, Help: "Some\nhelp", Unit: "bytes"},
 		}).
 		Once()
 
@@ -178,4 +376,905 @@ func TestList(t *testing.T) {
 	body, _ := io.ReadAll(rec.Body)
 	assert.Contains(t, string(body), "some 8.12345")
 	assert.Contains(t, string(body), "other 64")
+	assert.Contains(t, string(body), "# HELP other Some\\nhelp\n# UNIT other bytes\nother 64\n"+`other{host="a"} 2`)
+}
+
+func TestListHTML(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	storage := NewMockStorage(t)
+	storage.EXPECT().List().
+		Return(map[string]model.Metric{
+			"gauge:some": {
+				ID:        "some",
+				MType:     model.Gauge,
+				Value:     helper.NewFloat64(t, 8.5),
+				Timestamp: ts,
+			},
+			`counter:other{host="a"}`: {
+				ID:     "other",
+				MType:  model.Counter,
+				Labels: model.NewLabels("host", "a"),
+				Delta:  helper.NewInt64(t, 2),
+			},
+			"histogram:latency": {
+				ID:        "latency",
+				MType:     model.Histogram,
+				Histogram: &model.HistogramData{Bounds: []float64{1}, Counts: []uint64{2, 1}, Sum: 3.5, Count: 3},
+			},
+		}).
+		Once()
+	storage.EXPECT().ListMetadata().
+		Return(map[string]model.Metadata{"some": {Help: "Some <value>", Unit: "bytes"}}).
+		Once()
+
+	server := New("", storage, zap.L())
+	server.RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	gz, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	b, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	body := string(b)
+
+	assert.Contains(t, body, `<td>latency</td><td>histogram</td><td class="num" data-value="3">count 3, sum 3.5</td>`)
+	assert.Contains(t, body, `<td><a href="/ui/?query=other%7bhost%3d%22a%22%7d">other{host=&#34;a&#34;}</a></td><td>counter</td>`)
+	assert.Contains(t, body, `<td title="Some &lt;value&gt;"><a href="/ui/?query=some">some</a></td><td>gauge</td>`+
+		`<td class="num" data-value="8.5">8.5 <span class="unit">bytes</span></td>`+
+		`<td data-value="1700000000">2023-11-14 22:13:20</td>`)
+	// строки упорядочены по имени метрики
+	assert.Less(t, strings.Index(body, "<td>latency"), strings.Index(body, ">other"))
+}
+
+func TestUI(t *testing.T) {
+	server := New("", NewMockStorage(t), zap.L())
+	server.RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/ui", http.NoBody)
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "/ui/", rec.Header().Get("Location"))
+
+	req = httptest.NewRequest(http.MethodGet, "/ui/", http.NoBody)
+	rec = httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<script src="app.js"></script>`)
+
+	req = httptest.NewRequest(http.MethodGet, "/ui/app.js", http.NoBody)
+	rec = httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "javascript")
+	assert.Contains(t, rec.Body.String(), "/api/v1/query_range")
+}
+
+func TestUpdateJSON(t *testing.T) {
+	histogram := &model.HistogramData{Bounds: []float64{0.5}, Counts: []uint64{1, 0}, Sum: 0.1, Count: 1}
+	testCases := []struct {
+		name           string
+		body           string
+		metric         *model.Metric
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "update histogram",
+			body: `{"id":"latency","type":"histogram","labels":{"host":"a"},` +
+				`"histogram":{"bounds":[0.5],"counts":[1,0],"sum":0.1,"count":1}}`,
+			metric: &model.Metric{
+				ID:        "latency",
+				MType:     model.Histogram,
+				Labels:    model.NewLabels("host", "a"),
+				Histogram: histogram,
+			},
+			expectedStatus: 200,
+			expectedBody: `{"id":"latency","type":"histogram","labels":{"host":"a"},` +
+				`"histogram":{"bounds":[0.5],"counts":[1,0],"sum":0.1,"count":1}}`,
+		},
+		{
+			name:           "invalid histogram",
+			body:           `{"id":"latency","type":"histogram","histogram":{"bounds":[0.5],"counts":[1],"count":1}}`,
+			expectedStatus: 400,
+		},
+		{
+			name:           "gauge without value",
+			body:           `{"id":"some","type":"gauge"}`,
+			expectedStatus: 400,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			storage := NewMockStorage(t)
+			if tc.metric != nil {
+				storage.EXPECT().Update(*tc.metric).Return(nil).Once()
+				storage.EXPECT().Get(tc.metric.MType, tc.metric.ID, tc.metric.Labels).Return(tc.metric, nil).Once()
+			}
+
+			server := New("", storage, zap.L())
+			server.RegisterRoutes()
+
+			req := httptest.NewRequest(http.MethodPost, "/update/", strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+
+			server.router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestValueJSON(t *testing.T) {
+	storage := NewMockStorage(t)
+	storage.EXPECT().Get(model.Counter, "some", model.Labels(nil)).
+		Return(&model.Metric{ID: "some", MType: model.Counter, Delta: helper.NewInt64(t, 5)}, nil).
+		Once()
+	storage.EXPECT().Get(model.Gauge, "none", model.Labels(nil)).
+		Return(nil, model.ErrMetricNotFound).
+		Once()
+
+	server := New("", storage, zap.L())
+	server.RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodPost, "/value/", strings.NewReader(`{"id":"some","type":"counter"}`))
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":"some","type":"counter","delta":5}`, rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/value/", strings.NewReader(`{"id":"none","type":"gauge"}`))
+	rec = httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestWrite(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	testCases := []struct {
+		name           string
+		body           string
+		metrics        []model.Metric
+		rejected       int
+		expectedStatus int
+	}{
+		{
+			name: "write points",
+			body: "cpu,host=a usage=0.5,procs=3i 1700000000000000000\nmem free=10i 1700000000000000000",
+			metrics: []model.Metric{
+				{
+					ID: "cpu_usage", MType: model.Gauge, Labels: model.NewLabels("host", "a"),
+					Value: helper.NewFloat64(t, 0.5), Timestamp: ts,
+				},
+				{
+					ID: "cpu_procs", MType: model.Counter, Labels: model.NewLabels("host", "a"),
+					Delta: helper.NewInt64(t, 3), Timestamp: ts,
+				},
+				{ID: "mem_free", MType: model.Counter, Delta: helper.NewInt64(t, 10), Timestamp: ts},
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "incorrect line",
+			body:           "cpu usage=0.5\ncpu",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "partial write",
+			body: "cpu usage=0.5 1700000000000000000\nmem free=10i 1700000000000000000",
+			metrics: []model.Metric{
+				{ID: "cpu_usage", MType: model.Gauge, Value: helper.NewFloat64(t, 0.5), Timestamp: ts},
+				{ID: "mem_free", MType: model.Counter, Delta: helper.NewInt64(t, 10), Timestamp: ts},
+			},
+			rejected:       1,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			storage := NewMockStorage(t)
+			for i, m := range tc.metrics {
+				var err error
+				if i < tc.rejected {
+					err = model.ErrInvalidMetric
+				}
+				storage.EXPECT().Update(m).Return(err).Once()
+			}
+
+			server := New("", storage, zap.L())
+			server.RegisterRoutes()
+
+			req := httptest.NewRequest(http.MethodPost, "/write", strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+
+			server.router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestOTLPMetrics(t *testing.T) {
+	body := `{"resourceMetrics":[{"scopeMetrics":[{"metrics":[` +
+		`{"name":"temperature","gauge":{"dataPoints":[{"asDouble":36.6}]}},` +
+		`{"name":"latency","summary":{"dataPoints":[{}]}}` +
+		`]}]}]}`
+
+	storage := NewMockStorage(t)
+	storage.EXPECT().Update(model.Metric{
+		ID:    "temperature",
+		MType: model.Gauge,
+		Value: helper.NewFloat64(t, 36.6),
+	}).Return(nil).Once()
+
+	server := New("", storage, zap.L())
+	server.RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/metrics", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	server.router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"partialSuccess":{"rejectedDataPoints":"1","errorMessage":`+
+		`"latency: unsupported metric data type *v1.Metric_Summary"}}`, rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/metrics", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/plain")
+	rec = httptest.NewRecorder()
+
+	server.router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+	// сжатое тело, которое после распаковки больше maxBodySize
+	var gzBody bytes.Buffer
+	gz := gzip.NewWriter(&gzBody)
+	_, err := gz.Write(bytes.Repeat([]byte(" "), maxBodySize+1))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	req = httptest.NewRequest(http.MethodPost, "/v1/metrics", &gzBody)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+
+	server.router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestSeries(t *testing.T) {
+	from := time.Unix(1700000000, 0).UTC()
+	to := from.Add(time.Hour)
+	samples := []model.Sample{
+		{Timestamp: from.UTC(), Value: 1.5},
+	}
+
+	testCases := []struct {
+		name           string
+		query          string
+		labels         model.Labels
+		step           time.Duration
+		storageCalled  bool
+		storageError   error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "unix time",
+			query:          "?from=1700000000&to=1700003600",
+			storageCalled:  true,
+			expectedStatus: 200,
+			expectedBody: `{"id":"some","type":"gauge","samples":[` +
+				`{"timestamp":"2023-11-14T22:13:20Z","value":1.5}]}`,
+		},
+		{
+			name:           "rfc3339, step and labels",
+			query:          "?from=2023-11-14T22:13:20Z&to=2023-11-14T23:13:20Z&step=30s&label.host=a",
+			labels:         model.NewLabels("host", "a"),
+			step:           30 * time.Second,
+			storageCalled:  true,
+			expectedStatus: 200,
+			expectedBody: `{"id":"some","type":"gauge","labels":{"host":"a"},"samples":[` +
+				`{"timestamp":"2023-11-14T22:13:20Z","value":1.5}]}`,
+		},
+		{
+			name:           "not found",
+			query:          "?from=1700000000&to=1700003600&step=60",
+			step:           time.Minute,
+			storageCalled:  true,
+			storageError:   model.ErrMetricNotFound,
+			expectedStatus: 404,
+		},
+		{
+			name:           "incorrect from",
+			query:          "?from=yesterday",
+			expectedStatus: 400,
+		},
+		{
+			name:           "from after to",
+			query:          "?from=1700003600&to=1700000000",
+			expectedStatus: 400,
+		},
+		{
+			name:           "incorrect step",
+			query:          "?from=1700000000&to=1700003600&step=-1s",
+			expectedStatus: 400,
+		},
+		{
+			name:           "step below minimum",
+			query:          "?from=1700000000&to=1700000000&step=1ns",
+			expectedStatus: 400,
+		},
+		{
+			name:           "too many points",
+			query:          "?from=1700000000&to=1700003600&step=100ms",
+			expectedStatus: 400,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			storage := NewMockStorage(t)
+			if tc.storageCalled {
+				storage.EXPECT().Range(model.Gauge, "some", tc.labels, from, to, tc.step).
+					Return(samples, tc.storageError).
+					Once()
+			}
+
+			server := New("", storage, zap.L())
+			server.RegisterRoutes()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/series/gauge/some"+tc.query, http.NoBody)
+			rec := httptest.NewRecorder()
+			server.router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	metric := model.Metric{ID: "load", MType: model.Gauge, Labels: model.NewLabels("host", "a")}
+	samples := []model.Sample{{Timestamp: ts.Add(-time.Minute), Value: 1.5}}
+
+	testCases := []struct {
+		name           string
+		path           string
+		storageCalled  bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "instant vector",
+			path:           "/api/v1/query?query=load*2&time=1700000000",
+			storageCalled:  true,
+			expectedStatus: 200,
+			expectedBody: `{"status":"success","data":{"resultType":"vector","result":[` +
+				`{"metric":{"host":"a"},"value":[1700000000,"3"]}]}}`,
+		},
+		{
+			name:           "instant scalar",
+			path:           "/api/v1/query?query=1/0&time=1700000000.5",
+			expectedStatus: 200,
+			expectedBody:   `{"status":"success","data":{"resultType":"scalar","result":[1700000000.5,"+Inf"]}}`,
+		},
+		{
+			name:           "range",
+			path:           "/api/v1/query_range?query=load&start=1700000000&end=1700000060&step=60",
+			storageCalled:  true,
+			expectedStatus: 200,
+			expectedBody: `{"status":"success","data":{"resultType":"matrix","result":[` +
+				`{"metric":{"__name__":"load","host":"a"},"values":[[1700000000,"1.5"],[1700000060,"1.5"]]}]}}`,
+		},
+		{
+			name:           "parse error",
+			path:           "/api/v1/query?query=sum(",
+			expectedStatus: 400,
+			expectedBody:   `{"status":"error","errorType":"bad_data","error":"parse error: unexpected end of input"}`,
+		},
+		{
+			name:           "too many points",
+			path:           "/api/v1/query_range?query=load&start=0&end=1700000000&step=1",
+			expectedStatus: 400,
+		},
+		{
+			name:           "step below minimum",
+			path:           "/api/v1/query_range?query=load&start=1700000000&end=1700000000&step=1ns",
+			expectedStatus: 400,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			storage := NewMockStorage(t)
+			if tc.storageCalled {
+				storage.EXPECT().List().Return(map[string]model.Metric{metric.Key(): metric}).Once()
+				storage.EXPECT().Range(model.Gauge, "load", metric.Labels, mock.Anything, mock.Anything, mock.Anything).
+					Return(samples, nil).
+					Once()
+			}
+
+			server := New("", storage, zap.L())
+			server.RegisterRoutes()
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, http.NoBody)
+			rec := httptest.NewRecorder()
+			server.router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	md := model.Metadata{Type: model.Gauge, Help: "Bytes of memory in mcache structures.", Unit: "bytes", Owner: "runtime"}
+	storage := NewMockStorage(t)
+	storage.EXPECT().UpdateMetadata("MCacheSys", md).Return(nil).Once()
+	storage.EXPECT().Metadata("MCacheSys").Return(&md, nil).Twice()
+	storage.EXPECT().Metadata("none").Return(nil, model.ErrMetadataNotFound).Once()
+	storage.EXPECT().ListMetadata().
+		Return(map[string]model.Metadata{"MCacheSys": md, "latency": {Type: model.ExponentialHistogram}}).
+		Once()
+
+	server := New("", storage, zap.L())
+	server.RegisterRoutes()
+
+	expected := `{"type":"gauge","help":"Bytes of memory in mcache structures.","unit":"bytes","owner":"runtime"}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/metadata/MCacheSys", strings.NewReader(expected))
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, expected, rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/metadata/MCacheSys", strings.NewReader(`{"type":"timer"}`))
+	rec = httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// имена собственных метрик сервера зарезервированы
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/metadata/"+telemetry.Prefix+"uptime", strings.NewReader(expected))
+	rec = httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/metadata/MCacheSys", http.NoBody)
+	rec = httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, expected, rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/metadata/none", http.NoBody)
+	rec = httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/metadata", http.NoBody)
+	rec = httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"success","data":{`+
+		`"MCacheSys":[{"type":"gauge","help":"Bytes of memory in mcache structures.","unit":"bytes"}],`+
+		`"latency":[{"type":"summary","help":"","unit":""}]}}`, rec.Body.String())
+}
+
+func TestStream(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	storage := NewMockStorage(t)
+	storage.EXPECT().Update(mock.Anything).Return(nil).Twice()
+	storage.EXPECT().Get(model.Gauge, "errors", model.Labels(nil)).
+		Return(&model.Metric{ID: "errors", MType: model.Gauge, Value: helper.NewFloat64(t, 1)}, nil).
+		Once()
+	storage.EXPECT().Get(model.Counter, "errors", model.Labels(nil)).
+		Return(&model.Metric{ID: "errors", MType: model.Counter, Delta: helper.NewInt64(t, 5), Timestamp: ts}, nil).
+		Once()
+
+	server := New("", storage, zap.L())
+	server.RegisterRoutes()
+	srv := httptest.NewServer(server.router)
+	defer srv.Close()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		srv.URL+"/api/v1/stream?match=err*&type=counter", http.NoBody)
+	require.NoError(t, err)
+	res, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close() //nolint:errcheck // it's ok
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	// обновление gauge не подходит под фильтр типа
+	for _, path := range []string{"/update/gauge/errors/1", "/update/counter/errors/2"} {
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, http.NoBody))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	r := bufio.NewReader(res.Body)
+	var lines []string
+	for range 3 {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		lines = append(lines, line)
+	}
+	assert.Equal(t, []string{
+		"event: update\n",
+		`data: {"id":"errors","type":"counter","delta":5,"timestamp":"` + ts.Format(time.RFC3339) + `"}` + "\n",
+		"\n",
+	}, lines)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/stream?type=timer", http.NoBody)
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestWebSocket(t *testing.T) {
+	storage := NewMockStorage(t)
+	storage.EXPECT().Get(model.Gauge, "Alloc", model.Labels(nil)).
+		Return(&model.Metric{ID: "Alloc", MType: model.Gauge, Value: helper.NewFloat64(t, 1)}, nil).
+		Once()
+	storage.EXPECT().Get(model.Counter, "errors", model.Labels(nil)).
+		Return(nil, model.ErrMetricNotFound).
+		Once()
+	storage.EXPECT().Update(mock.Anything).Return(nil).Twice()
+	storage.EXPECT().Get(model.Gauge, "other", model.Labels(nil)).
+		Return(&model.Metric{ID: "other", MType: model.Gauge, Value: helper.NewFloat64(t, 3)}, nil).
+		Once()
+	storage.EXPECT().Get(model.Counter, "errors", model.Labels(nil)).
+		Return(&model.Metric{ID: "errors", MType: model.Counter, Delta: helper.NewInt64(t, 2)}, nil).
+		Once()
+
+	server := New("", storage, zap.L())
+	server.RegisterRoutes()
+	srv := httptest.NewServer(server.router)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/ws"
+	conn, _, err := websocket.Dial(ctx, wsURL, nil) //nolint:bodyclose // тело ответа закрывает Dial
+	require.NoError(t, err)
+	defer conn.CloseNow() //nolint:errcheck // it's ok
+	read := func() string {
+		_, msg, err := conn.Read(ctx)
+		require.NoError(t, err)
+		return string(msg)
+	}
+
+	require.NoError(t, conn.Write(ctx, websocket.MessageText, []byte("{")))
+	assert.JSONEq(t, `{"type":"error","error":"incorrect request: unexpected end of JSON input"}`, read())
+
+	// ряда errors еще нет, он придет в update после первой записи
+	require.NoError(t, conn.Write(ctx, websocket.MessageText,
+		[]byte(`{"action":"subscribe","metrics":[{"id":"Alloc","type":"gauge"},{"id":"errors","type":"counter"}]}`)))
+	assert.JSONEq(t, `{"type":"snapshot","metrics":[{"id":"Alloc","type":"gauge","value":1}]}`, read())
+
+	// на other клиент не подписан
+	for _, path := range []string{"/update/gauge/other/3", "/update/counter/errors/2"} {
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, http.NoBody))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.JSONEq(t, `{"type":"update","metrics":[{"id":"errors","type":"counter","delta":2}]}`, read())
+
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/ws", http.NoBody))
+	assert.Equal(t, http.StatusUpgradeRequired, rec.Code)
+
+	// текстовое сообщение не в UTF-8 закрывает соединение
+	require.NoError(t, conn.Write(ctx, websocket.MessageText, []byte{0xff, 0xfe}))
+	_, _, err = conn.Read(ctx)
+	assert.Equal(t, websocket.StatusInvalidFramePayloadData, websocket.CloseStatus(err))
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	server := New("", NewMockStorage(t), zap.L())
+	require.Error(t, server.SetWebSocketOrigins([]string{"["}))
+	require.NoError(t, server.SetWebSocketOrigins([]string{"*.example.com"}))
+	server.RegisterRoutes()
+	srv := httptest.NewServer(server.router)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/ws"
+	testCases := []struct {
+		origin   string
+		expected int
+	}{
+		{origin: srv.URL, expected: http.StatusSwitchingProtocols},
+		{origin: "https://ui.example.com", expected: http.StatusSwitchingProtocols},
+		{origin: "https://evil.test", expected: http.StatusForbidden},
+		{origin: "null", expected: http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.origin, func(t *testing.T) {
+			opts := &websocket.DialOptions{HTTPHeader: http.Header{"Origin": {tc.origin}}}
+			conn, res, err := websocket.Dial(ctx, wsURL, opts) //nolint:bodyclose // тело ответа закрывает Dial
+			if conn != nil {
+				_ = conn.CloseNow()
+			}
+			if tc.expected != http.StatusSwitchingProtocols {
+				require.Error(t, err)
+			}
+			require.NotNil(t, res)
+			assert.Equal(t, tc.expected, res.StatusCode)
+		})
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	storage := NewMockStorage(t)
+	storage.EXPECT().Update(mock.Anything).Return(nil).Twice()
+
+	core, logs := observer.New(zap.InfoLevel)
+	server := New("", storage, zap.New(core))
+	server.RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodPost, "/update/counter/a/1", http.NoBody)
+	req.Header.Set(RequestIDHeader, "client-id")
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	assert.Equal(t, "client-id", rec.Header().Get(RequestIDHeader))
+
+	// некорректный идентификатор клиента заменяется новым
+	req = httptest.NewRequest(http.MethodPost, "/update/counter/a/1", http.NoBody)
+	req.Header.Set(RequestIDHeader, "bad\nid")
+	rec = httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	generated := rec.Header().Get(RequestIDHeader)
+	assert.Len(t, generated, 16)
+
+	rec = httptest.NewRecorder()
+	server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/value/timer/a", http.NoBody))
+
+	entries := logs.FilterMessage("request").All()
+	require.Len(t, entries, 3)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "client-id", fields["request_id"])
+	assert.Equal(t, "POST", fields["method"])
+	assert.Equal(t, "/update/counter/a/1", fields["uri"])
+	assert.Equal(t, int64(http.StatusOK), fields["status"])
+	assert.Contains(t, fields, "duration")
+	assert.Equal(t, generated, entries[1].ContextMap()["request_id"])
+
+	fields = entries[2].ContextMap()
+	assert.Equal(t, int64(http.StatusBadRequest), fields["status"])
+	assert.Equal(t, int64(rec.Body.Len()), fields["size"])
+}
+
+func TestInternalMetrics(t *testing.T) {
+	storage := NewMockStorage(t)
+	storage.EXPECT().Update(mock.Anything).Return(nil).Once()
+	storage.EXPECT().List().Return(map[string]model.Metric{"counter:a": {}}).Once()
+
+	server := New("", storage, zap.L())
+	server.RegisterRoutes()
+	for _, path := range []string{"/update/counter/a/1", "/update/counter/internal_a/1", "/update/histogram/a/x"} {
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, http.NoBody))
+	}
+	// гистограмма без наблюдений отклоняется при проверке, до хранилища не доходит
+	require.ErrorIs(t, server.Storage().Update(model.Metric{ID: "b", MType: model.Histogram}), model.ErrInvalidMetric)
+
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/internal/metrics", http.NoBody))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# HELP http_requests_total HTTP requests by route pattern, method and status code.\n",
+		`http_requests_total{method="POST",route="/update/{metricType}/{metricName}/{value}",status="200"} 1` + "\n",
+		`http_requests_total{method="POST",route="/update/{metricType}/{metricName}/{value}",status="400"} 2` + "\n",
+		`http_request_duration_seconds_count{route="/update/{metricType}/{metricName}/{value}"} 3` + "\n",
+		"storage_series 1.00000\n",
+		"updates_accepted_total 1\n",
+		`updates_rejected_total{reason="invalid_metric"} 1` + "\n",
+		`updates_rejected_total{reason="reserved_name"} 1` + "\n",
+	} {
+		assert.Contains(t, body, line)
+	}
+	assert.Contains(t, body, "go_goroutines ")
+}
+
+type pingStorage struct {
+	*MockStorage
+	err error
+}
+
+func (s *pingStorage) Ping(context.Context) error {
+	return s.err
+}
+
+func TestHealth(t *testing.T) {
+	storage := &pingStorage{MockStorage: NewMockStorage(t)}
+	server := New("", storage, zap.L())
+	restored := false
+	server.AddReadinessCheck("restore", func(context.Context) error {
+		if !restored {
+			return errors.New("in progress")
+		}
+		return nil
+	})
+	server.RegisterRoutes()
+
+	check := func(path string, code int, body string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		assert.Equal(t, code, rec.Code)
+		assert.Equal(t, body, rec.Body.String())
+	}
+
+	check("/healthz", http.StatusOK, "ok\n")
+	check("/readyz", http.StatusServiceUnavailable, "restore: in progress\n")
+	restored = true
+	check("/readyz", http.StatusOK, "ok\n")
+
+	storage.err = errors.New("connection refused")
+	check("/readyz", http.StatusServiceUnavailable, "storage: connection refused\n")
+	storage.err = nil
+
+	// при завершении сервер перестает быть готовым, но остается живым
+	server.shuttingDown.Store(true)
+	check("/readyz", http.StatusServiceUnavailable, "shutdown: server is shutting down\n")
+	check("/healthz", http.StatusOK, "ok\n")
+}
+
+// benchStorage - хранилище с фиксированным набором метрик для бенчмарков обработчиков,
+// остальные методы Storage не вызываются
+type benchStorage struct {
+	Storage
+	metrics  map[string]model.Metric
+	metadata map[string]model.Metadata
+}
+
+func (s *benchStorage) List() map[string]model.Metric {
+	return s.metrics
+}
+
+func (s *benchStorage) ListMetadata() map[string]model.Metadata {
+	return s.metadata
+}
+
+func (s *benchStorage) Get(metricType model.MetricType, metricName string, labels model.Labels) (*model.Metric, error) {
+	m, ok := s.metrics[model.SeriesKey(metricType, metricName, labels)]
+	if !ok {
+		return nil, model.ErrMetricNotFound
+	}
+	return &m, nil
+}
+
+// newBenchStorage - n рядов: gauge, counter и гистограмма по n/3 рядов с меткой instance,
+// у каждой метрики есть метаданные
+func newBenchStorage(n int) *benchStorage {
+	s := &benchStorage{
+		metrics: make(map[string]model.Metric, n),
+		metadata: map[string]model.Metadata{
+			"memory":   {Type: model.Gauge, Help: "Memory in use.", Unit: "bytes"},
+			"requests": {Type: model.Counter, Help: "Requests served."},
+			"latency":  {Type: model.Histogram, Help: "Request latency.", Unit: "seconds"},
+		},
+	}
+	for i := range n {
+		labels := model.NewLabels("instance", strconv.Itoa(i/3))
+		var m model.Metric
+		switch i % 3 {
+		case 0:
+			v := float64(i) * 1.5
+			m = model.Metric{ID: "memory", MType: model.Gauge, Labels: labels, Value: &v}
+		case 1:
+			d := int64(i)
+			m = model.Metric{ID: "requests", MType: model.Counter, Labels: labels, Delta: &d}
+		default:
+			h, _ := model.NewHistogram(model.DefaultBuckets)
+			h.Observe(float64(i) / 1000)
+			m = model.Metric{ID: "latency", MType: model.Histogram, Labels: labels, Histogram: h}
+		}
+		s.metrics[m.Key()] = m
+	}
+	return s
+}
+
+// benchSeriesCounts - количество рядов в хранилище: текущий объем и рост в 10 раз
+var benchSeriesCounts = []int{1000, 10000}
+
+func BenchmarkList(b *testing.B) {
+	for _, n := range benchSeriesCounts {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			server := New("", newBenchStorage(n), zap.NewNop())
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				rec := httptest.NewRecorder()
+				server.List(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+				if rec.Code != http.StatusOK {
+					b.Fatal(rec.Code)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkValueJSON(b *testing.B) {
+	server := New("", newBenchStorage(3), zap.NewNop())
+	for _, tc := range []struct {
+		name string
+		body string
+	}{
+		{name: "gauge", body: `{"id":"memory","type":"gauge","labels":{"instance":"0"}}`},
+		{name: "histogram", body: `{"id":"latency","type":"histogram","labels":{"instance":"0"}}`},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				rec := httptest.NewRecorder()
+				server.ValueJSON(rec, httptest.NewRequest(http.MethodPost, "/value/", strings.NewReader(tc.body)))
+				if rec.Code != http.StatusOK {
+					b.Fatal(rec.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	storage := NewMockStorage(t)
+	storage.EXPECT().Get(model.Counter, "some", model.Labels(nil)).
+		Return(&model.Metric{ID: "some", MType: model.Counter, Delta: helper.NewInt64(t, 5)}, nil).
+		Once()
+	server := New("", storage, zap.L())
+	server.RegisterRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/value/counter/some", http.NoBody)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	storageSpan, handlerSpan := spans[0], spans[1]
+	assert.Equal(t, "GET /value/{metricType}/{metricName}", handlerSpan.Name())
+	// трассировка клиента продолжается
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", handlerSpan.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", handlerSpan.Parent().SpanID().String())
+	assert.Equal(t, "storage.Get", storageSpan.Name())
+	assert.Equal(t, handlerSpan.SpanContext().SpanID(), storageSpan.Parent().SpanID())
+
+	// вызовы хранилища при вычислении запроса - тоже дочерние спаны запроса
+	storage.EXPECT().List().Return(map[string]model.Metric{}).Once()
+	rec = httptest.NewRecorder()
+	server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/query?query=requests", http.NoBody))
+	require.Equal(t, http.StatusOK, rec.Code)
+	spans = recorder.Ended()[2:]
+	require.Len(t, spans, 2)
+	storageSpan, handlerSpan = spans[0], spans[1]
+	assert.Equal(t, "storage.List", storageSpan.Name())
+	assert.Equal(t, handlerSpan.SpanContext().SpanID(), storageSpan.Parent().SpanID())
 }
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// readinessTimeout - ограничение времени всех проверок готовности
+const readinessTimeout = time.Second
+
+// ErrShuttingDown - сервер завершает работу и не принимает новые запросы
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// Pinger - хранилище, доступность которого можно проверить, например внешняя база данных
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// ReadinessCheck - проверка готовности сервера принимать запросы, nil - готов
+type ReadinessCheck func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check ReadinessCheck
+}
+
+// AddReadinessCheck - дополнительная проверка для /readyz, например завершения
+// восстановления хранилища. Добавляется до запуска сервера. Хранилищу в памяти
+// восстанавливать нечего, поэтому сейчас дополнительные проверки не регистрируются.
+func (a *APIServer) AddReadinessCheck(name string, check ReadinessCheck) {
+	a.readinessChecks = append(a.readinessChecks, namedCheck{name: name, check: check})
+}
+
+// SetShutdownDelay - время между переходом в состояние "не готов" и остановкой
+// http сервера, за которое балансировщики успевают исключить сервер
+func (a *APIServer) SetShutdownDelay(d time.Duration) {
+	a.shutdownDelay = d
+}
+
+// Healthz - процесс жив и обрабатывает запросы
+func (a *APIServer) Healthz(res http.ResponseWriter, _ *http.Request) {
+	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err := res.Write([]byte("ok\n")); err != nil {
+		a.logger.Error("failed to write body", zap.Error(err))
+	}
+}
+
+// Readyz - сервер не завершает работу, хранилище доступно и дополнительные проверки пройдены.
+// Если хотя бы одна проверка не пройдена - 503 со списком ошибок.
+func (a *APIServer) Readyz(res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := append([]namedCheck{
+		{name: "shutdown", check: a.checkShutdown},
+		{name: "storage", check: a.checkStorage},
+	}, a.readinessChecks...)
+	var failed strings.Builder
+	for _, c := range checks {
+		if err := c.check(ctx); err != nil {
+			fmt.Fprintf(&failed, "%s: %v\n", c.name, err)
+		}
+	}
+
+	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	body := "ok\n"
+	if failed.Len() > 0 {
+		res.WriteHeader(http.StatusServiceUnavailable)
+		body = failed.String()
+	}
+	if _, err := res.Write([]byte(body)); err != nil {
+		a.logger.Error("failed to write body", zap.Error(err))
+	}
+}
+
+func (a *APIServer) checkShutdown(context.Context) error {
+	if a.shuttingDown.Load() {
+		return ErrShuttingDown
+	}
+	return nil
+}
+
+// checkStorage - хранилище в памяти процесса доступно всегда, внешнее проверяется через Pinger
+func (a *APIServer) checkStorage(ctx context.Context) error {
+	if p, ok := a.ingest.Storage.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/stream"
+	"github.com/mikeziminio/go-custom-metrics/internal/telemetry"
+)
+
+// Причины отклонения обновлений, метка reason метрики updates_rejected_total
+const (
+	rejectReservedName = "reserved_name"
+	rejectInvalid      = "invalid_metric"
+	rejectLabel        = "invalid_label"
+	rejectHistogram    = "invalid_histogram"
+	rejectSummary      = "invalid_summary"
+	rejectSet          = "invalid_set"
+	rejectStorage      = "storage"
+)
+
+var errReservedPrefix = fmt.Errorf("%w: prefix %q is reserved", model.ErrInvalidMetric, telemetry.Prefix)
+
+// ingestStorage - хранилище, через которое проходят обновления всех приемников метрик:
+// проверяет обновление, учитывает принятые и отклоненные в собственных метриках
+// и рассылает принятые подписчикам hub. Рассылается значение метрики после обновления,
+// а не переданное приращение.
+type ingestStorage struct {
+	Storage
+	hub       *stream.Hub
+	telemetry *telemetry.Registry
+}
+
+// Update - имена с префиксом telemetry.Prefix зарезервированы для собственных метрик
+func (s *ingestStorage) Update(m model.Metric) error {
+	if strings.HasPrefix(m.ID, telemetry.Prefix) {
+		s.telemetry.Add(metricUpdatesRejected, model.NewLabels(labelReason, rejectReservedName), 1)
+		return errReservedPrefix
+	}
+	if err := s.store(m); err != nil {
+		s.telemetry.Add(metricUpdatesRejected, model.NewLabels(labelReason, rejectReason(err)), 1)
+		return err
+	}
+	s.telemetry.Add(metricUpdatesAccepted, nil, 1)
+	return nil
+}
+
+// store - проверяет и сохраняет обновление, принятое рассылает подписчикам
+func (s *ingestStorage) store(m model.Metric) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	if err := s.Storage.Update(m); err != nil {
+		return err
+	}
+	if !s.hub.Active() {
+		return nil
+	}
+	// обновление уже принято, поэтому ошибка чтения только пропускает рассылку
+	if current, err := s.Storage.Get(m.MType, m.ID, m.Labels); err == nil {
+		s.hub.Publish(*current)
+	}
+	return nil
+}
+
+// UpdateMetadata - метаданные собственных метрик с префиксом telemetry.Prefix задает только сервер
+func (s *ingestStorage) UpdateMetadata(metricName string, md model.Metadata) error {
+	if strings.HasPrefix(metricName, telemetry.Prefix) {
+		return errReservedPrefix
+	}
+	if err := model.ValidateName(metricName); err != nil {
+		return err
+	}
+	return s.Storage.UpdateMetadata(metricName, md)
+}
+
+// rejectReason - причина отклонения обновления по ошибке проверки или хранилища
+func rejectReason(err error) string {
+	switch {
+	case errors.Is(err, model.ErrInvalidLabel):
+		return rejectLabel
+	case errors.Is(err, model.ErrInvalidHistogram):
+		return rejectHistogram
+	case errors.Is(err, model.ErrInvalidSummary):
+		return rejectSummary
+	case errors.Is(err, model.ErrInvalidSet):
+		return rejectSet
+	case errors.Is(err, model.ErrInvalidMetric):
+		return rejectInvalid
+	}
+	return rejectStorage
+}
+
+// telemetryStorage - выгрузка собственных метрик в хранилище под зарезервированным префиксом,
+// выгруженные обновления не учитываются в updates_accepted_total
+type telemetryStorage struct {
+	*ingestStorage
+}
+
+func (s telemetryStorage) Update(m model.Metric) error {
+	return s.store(m)
+}
+
+func (s telemetryStorage) UpdateMetadata(metricName string, md model.Metadata) error {
+	return s.Storage.UpdateMetadata(metricName, md)
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+// promMetadata - метаданные метрики в формате /api/v1/metadata Prometheus
+type promMetadata struct {
+	Type string `json:"type"`
+	Help string `json:"help"`
+	Unit string `json:"unit"`
+}
+
+// UpdateMetadata - заменяет метаданные метрики переданными в теле запроса в формате JSON,
+// в ответе - сохраненные метаданные
+func (a *APIServer) UpdateMetadata(res http.ResponseWriter, req *http.Request) {
+	metricName := chi.URLParam(req, "metricName")
+	var md model.Metadata
+	if err := json.NewDecoder(req.Body).Decode(&md); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := md.Validate(); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	storage := a.tracedStorage(req.Context())
+	if err := storage.UpdateMetadata(metricName, md); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.writeMetadataJSON(res, storage, metricName)
+}
+
+// GetMetadata - метаданные метрики в формате JSON
+func (a *APIServer) GetMetadata(res http.ResponseWriter, req *http.Request) {
+	a.writeMetadataJSON(res, a.tracedStorage(req.Context()), chi.URLParam(req, "metricName"))
+}
+
+func (a *APIServer) writeMetadataJSON(res http.ResponseWriter, storage Storage, metricName string) {
+	md, err := storage.Metadata(metricName)
+	if err != nil {
+		if errors.Is(err, model.ErrMetadataNotFound) {
+			http.Error(res, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(res).Encode(md); err != nil {
+		a.logger.Error("failed to write body", zap.Error(err))
+	}
+}
+
+// ListMetadata - метаданные всех метрик в формате Prometheus, параметр metric
+// ограничивает ответ одной метрикой, limit - количеством метрик
+func (a *APIServer) ListMetadata(res http.ResponseWriter, req *http.Request) {
+	limit := -1
+	if v := req.FormValue("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil {
+			a.writePromError(res, errorTypeBadData, errors.New("limit must be integer"))
+			return
+		}
+	}
+	metric := req.FormValue("metric")
+
+	data := make(map[string][]promMetadata)
+	for name, md := range a.tracedStorage(req.Context()).ListMetadata() {
+		if limit >= 0 && len(data) >= limit {
+			break
+		}
+		if metric != "" && name != metric {
+			continue
+		}
+		data[name] = []promMetadata{{Type: promMetadataType(md.Type), Help: md.Help, Unit: md.Unit}}
+	}
+	a.writePromData(res, data)
+}
+
+// promMetadataType - тип метрики Prometheus, в который раскладываются ряды метрики, см. model.Metric.Series
+func promMetadataType(t model.MetricType) string {
+	switch t {
+	case model.Counter, model.Gauge, model.Histogram, model.Summary:
+		return string(t)
+	case model.ExponentialHistogram:
+		return string(model.Summary)
+	case model.Set:
+		return string(model.Gauge)
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader - заголовок с идентификатором запроса, принимается от клиента
+// или создается сервером и возвращается в ответе
+const RequestIDHeader = "X-Request-ID"
+
+// Ограничения идентификатора запроса, полученного от клиента
+const (
+	maxRequestIDLen = 128
+	requestIDBytes  = 8
+)
+
+// RequestID - идентификатор запроса из заголовка X-Request-ID или новый, если заголовка нет
+// или он некорректен. Сохраняется в контексте запроса, см. middleware.GetReqID.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		res.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(req.Context(), middleware.RequestIDKey, id)
+		next.ServeHTTP(res, req.WithContext(ctx))
+	})
+}
+
+// validRequestID - непустая строка ограниченной длины из печатных ASCII символов,
+// чтобы идентификатор клиента нельзя было использовать для подделки записей журнала
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := range len(id) {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, requestIDBytes)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestLogger - журналирует завершенные запросы: метод, URI, статус, размер ответа и длительность,
+// а если запрос трассируется - идентификатор трассировки.
+// Для соединений WebSocket и потоков SSE длительность - время жизни соединения.
+func RequestLogger(logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(res, req.ProtoMajor)
+			next.ServeHTTP(ww, req)
+
+			status := ww.Status()
+			if status == 0 {
+				// заголовок не записан явно или соединение перехвачено
+				status = http.StatusOK
+			}
+			fields := []zap.Field{
+				zap.String("request_id", middleware.GetReqID(req.Context())),
+				zap.String("method", req.Method),
+				zap.String("uri", req.RequestURI),
+				zap.Int("status", status),
+				zap.Int("size", ww.BytesWritten()),
+				zap.Duration("duration", time.Since(start)),
+			}
+			if sc := trace.SpanContextFromContext(req.Context()); sc.IsSampled() {
+				fields = append(fields, zap.Stringer("trace_id", sc.TraceID()))
+			}
+			logger.Info("request", fields...)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/query"
+)
+
+// MaxQueryPoints - ограничение количества точек ряда в ответе на запрос по интервалу
+const MaxQueryPoints = 11000
+
+// Типы ошибок в ответах API, совместимого с Prometheus
+const (
+	errorTypeBadData   = "bad_data"
+	errorTypeExecution = "execution"
+	errorTypeInternal  = "internal"
+)
+
+type promResponse struct {
+	Status    string `json:"status"`
+	Data      any    `json:"data,omitempty"`
+	ErrorType string `json:"errorType,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type promQueryData struct {
+	ResultType query.ValueType `json:"resultType"`
+	Result     any             `json:"result"`
+}
+
+type promSample struct {
+	Metric model.Labels `json:"metric"`
+	Value  promPoint    `json:"value"`
+}
+
+type promSeries struct {
+	Metric model.Labels `json:"metric"`
+	Values []promPoint  `json:"values"`
+}
+
+// promPoint - значение в формате [<unix time в секундах>, "<значение>"]
+type promPoint struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+func (p promPoint) MarshalJSON() ([]byte, error) {
+	b := []byte{'['}
+	b = strconv.AppendFloat(b, float64(p.Timestamp.UnixMilli())/1000, 'f', -1, 64)
+	b = append(b, ',')
+	b = strconv.AppendQuote(b, strconv.FormatFloat(p.Value, 'f', -1, 64))
+	return append(b, ']'), nil
+}
+
+// Query - значение выражения в момент time (по умолчанию - текущий момент),
+// формат запроса и ответа совместим с HTTP API Prometheus
+func (a *APIServer) Query(res http.ResponseWriter, req *http.Request) {
+	ts := time.Now()
+	if v := req.FormValue("time"); v != "" {
+		var err error
+		if ts, err = parseTime(v); err != nil {
+			a.writePromError(res, errorTypeBadData, err)
+			return
+		}
+	}
+
+	result, err := a.queryEngine.Instant(req.Context(), req.FormValue("query"), ts)
+	if err != nil {
+		a.writePromError(res, queryErrorType(err), err)
+		return
+	}
+
+	data := promQueryData{ResultType: result.Type}
+	switch result.Type {
+	case query.ValueTypeScalar:
+		data.Result = promPoint{Timestamp: ts, Value: result.Scalar}
+	case query.ValueTypeVector:
+		samples := make([]promSample, 0, len(result.Vector))
+		for _, s := range result.Vector {
+			samples = append(samples, promSample{
+				Metric: promLabels(s.Labels),
+				Value:  promPoint{Timestamp: ts, Value: s.Value},
+			})
+		}
+		data.Result = samples
+	}
+	a.writePromData(res, data)
+}
+
+// QueryRange - значения выражения в моменты start, start + step, ..., не позже end
+func (a *APIServer) QueryRange(res http.ResponseWriter, req *http.Request) {
+	start, err := parseTime(req.FormValue("start"))
+	if err != nil {
+		a.writePromError(res, errorTypeBadData, err)
+		return
+	}
+	end, err := parseTime(req.FormValue("end"))
+	if err != nil {
+		a.writePromError(res, errorTypeBadData, err)
+		return
+	}
+	step, err := parseStep(req.FormValue("step"))
+	// шаг меньше MinStep не ограничен и числом точек: при start == end
+	// хранилище прореживало бы весь интервал LookbackDelta с таким шагом
+	if err != nil || step < MinStep {
+		a.writePromError(res, errorTypeBadData, fmt.Errorf("step must be duration of at least %s", MinStep))
+		return
+	}
+	if end.Before(start) {
+		a.writePromError(res, errorTypeBadData, errors.New("end must not be before start"))
+		return
+	}
+	if end.Sub(start)/step >= MaxQueryPoints {
+		a.writePromError(res, errorTypeBadData, errors.New("too many points per series, increase step"))
+		return
+	}
+
+	result, err := a.queryEngine.Range(req.Context(), req.FormValue("query"), start, end, step)
+	if err != nil {
+		a.writePromError(res, queryErrorType(err), err)
+		return
+	}
+
+	series := make([]promSeries, 0, len(result.Matrix))
+	for _, s := range result.Matrix {
+		values := make([]promPoint, 0, len(s.Points))
+		for _, p := range s.Points {
+			values = append(values, promPoint(p))
+		}
+		series = append(series, promSeries{Metric: promLabels(s.Labels), Values: values})
+	}
+	a.writePromData(res, promQueryData{ResultType: result.Type, Result: series})
+}
+
+// LabelNames - имена меток всех рядов, включая имя метрики
+func (a *APIServer) LabelNames(res http.ResponseWriter, req *http.Request) {
+	names := map[string]struct{}{query.MetricNameLabel: {}}
+	for _, m := range a.tracedStorage(req.Context()).List() {
+		for name := range m.Labels {
+			names[name] = struct{}{}
+		}
+	}
+	a.writePromData(res, slices.Sorted(maps.Keys(names)))
+}
+
+// LabelValues - значения метки всех рядов, для __name__ - имена метрик
+func (a *APIServer) LabelValues(res http.ResponseWriter, req *http.Request) {
+	name := chi.URLParam(req, "name")
+	values := make(map[string]struct{})
+	for _, m := range a.tracedStorage(req.Context()).List() {
+		if name == query.MetricNameLabel {
+			values[m.ID] = struct{}{}
+			continue
+		}
+		if v, ok := m.Labels[name]; ok {
+			values[v] = struct{}{}
+		}
+	}
+	a.writePromData(res, slices.Sorted(maps.Keys(values)))
+}
+
+func (a *APIServer) writePromData(res http.ResponseWriter, data any) {
+	a.writePromResponse(res, http.StatusOK, promResponse{Status: "success", Data: data})
+}
+
+func (a *APIServer) writePromError(res http.ResponseWriter, errorType string, err error) {
+	status := http.StatusInternalServerError
+	switch errorType {
+	case errorTypeBadData:
+		status = http.StatusBadRequest
+	case errorTypeExecution:
+		status = http.StatusUnprocessableEntity
+	}
+	a.writePromResponse(res, status, promResponse{Status: "error", ErrorType: errorType, Error: err.Error()})
+}
+
+func (a *APIServer) writePromResponse(res http.ResponseWriter, status int, r promResponse) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	if err := json.NewEncoder(res).Encode(r); err != nil {
+		a.logger.Error("failed to write body", zap.Error(err))
+	}
+}
+
+func queryErrorType(err error) string {
+	switch {
+	case errors.Is(err, query.ErrParse):
+		return errorTypeBadData
+	case errors.Is(err, query.ErrExecution):
+		return errorTypeExecution
+	}
+	return errorTypeInternal
+}
+
+// promLabels - метки ряда, пустой набор кодируется как {}
+func promLabels(l model.Labels) model.Labels {
+	if l == nil {
+		return model.Labels{}
+	}
+	return l
+}
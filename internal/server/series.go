@@ -0,0 +1,138 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+// DefaultSeriesRange - интервал истории по умолчанию, если не задан параметр from
+const DefaultSeriesRange = time.Hour
+
+// MinStep - минимальный шаг прореживания истории и запросов по интервалу
+const MinStep = time.Millisecond
+
+// Параметры запроса истории, метки ряда передаются в параметрах с model.LabelParamPrefix
+const (
+	paramFrom = "from"
+	paramTo   = "to"
+	paramStep = "step"
+)
+
+// Series - история значений ряда в формате JSON.
+// Параметры from и to задаются в RFC3339 или в секундах unix time,
+// step - в формате time.Duration (30s, 5m) или в секундах, не меньше MinStep и так, чтобы
+// в интервале было меньше MaxQueryPoints окон. Если step не меньше интервала одного
+// из уровней прореживания хранилища, значения содержат агрегаты за окно.
+func (a *APIServer) Series(res http.ResponseWriter, req *http.Request) {
+	mt := chi.URLParam(req, "metricType")
+	metricType, err := model.NewMetricTypeFromString(mt)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	metricName := chi.URLParam(req, "metricName")
+
+	q := req.URL.Query()
+	to := time.Now()
+	if v := q.Get(paramTo); v != "" {
+		if to, err = parseTime(v); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	from := to.Add(-DefaultSeriesRange)
+	if v := q.Get(paramFrom); v != "" {
+		if from, err = parseTime(v); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	var step time.Duration
+	if v := q.Get(paramStep); v != "" {
+		if step, err = parseStep(v); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if from.After(to) {
+		http.Error(res, "from must not be after to", http.StatusBadRequest)
+		return
+	}
+	if step != 0 && (step < MinStep || to.Sub(from)/step >= MaxQueryPoints) {
+		http.Error(res, fmt.Sprintf("step must be at least %s and give less than %d points", MinStep, MaxQueryPoints),
+			http.StatusBadRequest)
+		return
+	}
+
+	q.Del(paramFrom)
+	q.Del(paramTo)
+	q.Del(paramStep)
+	labels, err := labelsFromQuery(q)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	samples, err := a.tracedStorage(req.Context()).Range(metricType, metricName, labels, from, to, step)
+	if err != nil {
+		if errors.Is(err, model.ErrMetricNotFound) {
+			http.Error(res, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if samples == nil {
+		samples = []model.Sample{}
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(res).Encode(model.Series{
+		ID:      metricName,
+		MType:   metricType,
+		Labels:  labels,
+		Samples: samples,
+	})
+	if err != nil {
+		a.logger.Error("failed to write body", zap.Error(err))
+	}
+}
+
+// parseTime - время в формате RFC3339 или в секундах unix time
+func parseTime(s string) (time.Time, error) {
+	if sec, err := strconv.ParseFloat(s, 64); err == nil {
+		whole, frac := math.Modf(sec)
+		return time.Unix(int64(whole), int64(frac*float64(time.Second))).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("incorrect time %q", s)
+	}
+	return t.UTC(), nil
+}
+
+// parseStep - шаг в формате time.Duration или в секундах
+func parseStep(s string) (time.Duration, error) {
+	step, err := time.ParseDuration(s)
+	if err != nil {
+		sec, errFloat := strconv.ParseFloat(s, 64)
+		if errFloat != nil {
+			return 0, fmt.Errorf("incorrect step %q", s)
+		}
+		step = time.Duration(sec * float64(time.Second))
+	}
+	if step < 0 {
+		return 0, fmt.Errorf("incorrect step %q", s)
+	}
+	return step, nil
+}
@@ -1,23 +1,46 @@
 package server
 
 import (
+	"compress/gzip"
 	"context"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
 
 	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/otlp"
+	"github.com/mikeziminio/go-custom-metrics/internal/query"
+	"github.com/mikeziminio/go-custom-metrics/internal/stream"
+	"github.com/mikeziminio/go-custom-metrics/internal/telemetry"
 )
 
 type Storage interface {
 	Update(m model.Metric) error
+	// List - все метрики, ключ - идентификатор ряда model.Metric.Key.
+	// Результат только для чтения, хранилище может возвращать его нескольким вызывающим.
 	List() map[string]model.Metric
-	Get(metricType model.MetricType, metricName string) (*model.Metric, error)
+	Get(metricType model.MetricType, metricName string, labels model.Labels) (*model.Metric, error)
+	// Range - история значений ряда в интервале [from, to],
+	// при step > 0 - не больше одного значения на каждый интервал step
+	Range(
+		metricType model.MetricType,
+		metricName string,
+		labels model.Labels,
+		from, to time.Time,
+		step time.Duration,
+	) ([]model.Sample, error)
+	// UpdateMetadata - заменяет метаданные метрики с именем metricName
+	UpdateMetadata(metricName string, md model.Metadata) error
+	Metadata(metricName string) (*model.Metadata, error)
+	// ListMetadata - метаданные всех метрик, ключ - имя метрики
+	ListMetadata() map[string]model.Metadata
 }
 
 // todo: next sprints
@@ -28,10 +51,22 @@ type Storage interface {
 // в следующих спринтах.
 
 type APIServer struct {
-	storage    Storage
-	router     *chi.Mux
-	httpServer *http.Server
-	logger     *zap.Logger
+	storage      Storage
+	ingest       *ingestStorage
+	hub          *stream.Hub
+	telemetry    *telemetry.Registry
+	otlpReceiver *otlp.Receiver
+	queryEngine  *query.Engine
+	router       *chi.Mux
+	httpServer   *http.Server
+	// readinessChecks - дополнительные проверки /readyz, см. AddReadinessCheck
+	readinessChecks []namedCheck
+	// shuttingDown - получен сигнал завершения, /readyz отвечает 503
+	shuttingDown  atomic.Bool
+	shutdownDelay time.Duration
+	// wsOrigins - разрешенные источники подключений к /api/v1/ws, см. SetWebSocketOrigins
+	wsOrigins []string
+	logger    *zap.Logger
 }
 
 func New(address string, storage Storage, logger *zap.Logger) *APIServer {
@@ -44,22 +79,66 @@ func New(address string, storage Storage, logger *zap.Logger) *APIServer {
 		ReadHeaderTimeout: 1 * time.Second,
 	}
 
+	// все обновления, в том числе по OTLP, проходят через хранилище с проверкой,
+	// учетом в собственных метриках и рассылкой подписчикам
+	hub := stream.NewHub()
+	reg := telemetry.NewRegistry()
+	ingest := &ingestStorage{Storage: storage, hub: hub, telemetry: reg}
+	httpServer.RegisterOnShutdown(hub.Close)
+
 	a := &APIServer{
-		storage:    storage,
-		router:     r,
-		httpServer: httpServer,
-		logger:     logger,
+		storage:      ingest,
+		ingest:       ingest,
+		hub:          hub,
+		telemetry:    reg,
+		otlpReceiver: otlp.NewReceiver(contextStorage{ingest}),
+		queryEngine:  query.NewEngine(contextStorage{ingest}),
+		router:       r,
+		httpServer:   httpServer,
+		logger:       logger,
 	}
+	a.registerTelemetry()
 
 	return a
 }
 
+// Storage - хранилище, обновления через которое рассылаются подписчикам потока обновлений,
+// его нужно передавать остальным приемникам метрик
+func (a *APIServer) Storage() Storage {
+	return a.storage
+}
+
 func (a *APIServer) RegisterRoutes() {
 	r := a.router
-
-	r.Get("/", a.List)
+	r.Use(RequestID, Tracing, RequestLogger(a.logger), a.instrument)
+
+	// текстовый формат и HTML страница хорошо сжимаются
+	compress := middleware.Compress(gzip.DefaultCompression, "text/plain", "text/html", "text/css", "text/javascript")
+	r.Get("/healthz", a.Healthz)
+	r.Get("/readyz", a.Readyz)
+	r.With(compress).Get("/", a.List)
+	r.Handle("/ui", http.RedirectHandler(UIPrefix, http.StatusMovedPermanently))
+	r.With(compress).Handle(UIPrefix+"*", UI())
 	r.Get("/value/{metricType}/{metricName}", a.Get)
 	r.Post("/update/{metricType}/{metricName}/{value}", a.Update)
+	r.Post("/update/", a.UpdateJSON)
+	r.Post("/value/", a.ValueJSON)
+	r.Post("/write", a.Write)
+	r.Post("/v1/metrics", a.OTLPMetrics)
+	r.Get("/api/v1/series/{metricType}/{metricName}", a.Series)
+	r.Get("/api/v1/query", a.Query)
+	r.Post("/api/v1/query", a.Query)
+	r.Get("/api/v1/query_range", a.QueryRange)
+	r.Post("/api/v1/query_range", a.QueryRange)
+	r.Get("/api/v1/labels", a.LabelNames)
+	r.Post("/api/v1/labels", a.LabelNames)
+	r.Get("/api/v1/label/{name}/values", a.LabelValues)
+	r.Get("/api/v1/stream", a.Stream)
+	r.Get("/api/v1/ws", a.WebSocket)
+	r.Get("/api/v1/metadata", a.ListMetadata)
+	r.Get("/api/v1/metadata/{metricName}", a.GetMetadata)
+	r.Put("/api/v1/metadata/{metricName}", a.UpdateMetadata)
+	r.Get("/internal/metrics", a.InternalMetrics)
 }
 
 func (a *APIServer) Run(ctx context.Context) {
@@ -81,6 +160,13 @@ func (a *APIServer) Run(ctx context.Context) {
 	case <-ctx.Done():
 	}
 
+	// балансировщики исключают сервер по /readyz, пока он еще обрабатывает запросы
+	a.shuttingDown.Store(true)
+	if a.shutdownDelay > 0 {
+		a.logger.Info("Server is not ready, waiting before shutdown", zap.Duration("delay", a.shutdownDelay))
+		time.Sleep(a.shutdownDelay)
+	}
+
 	err := a.httpServer.Shutdown(context.Background())
 	if err != nil {
 		a.logger.Fatal("failed to gracefully shutdown", zap.Error(err))
@@ -5,6 +5,8 @@
 package server
 
 import (
+	"time"
+
 	mock "github.com/stretchr/testify/mock"
 
 	"github.com/mikeziminio/go-custom-metrics/internal/model"
@@ -38,8 +40,8 @@ func (_m *MockStorage) EXPECT() *MockStorage_Expecter {
 }
 
 // Get provides a mock function for the type MockStorage
-func (_mock *MockStorage) Get(metricType model.MetricType, metricName string) (*model.Metric, error) {
-	ret := _mock.Called(metricType, metricName)
+func (_mock *MockStorage) Get(metricType model.MetricType, metricName string, labels model.Labels) (*model.Metric, error) {
+	ret := _mock.Called(metricType, metricName, labels)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Get")
@@ -47,18 +49,18 @@ func (_mock *MockStorage) Get(metricType model.MetricType, metricName string) (*
 
 	var r0 *model.Metric
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(model.MetricType, string) (*model.Metric, error)); ok {
-		return returnFunc(metricType, metricName)
+	if returnFunc, ok := ret.Get(0).(func(model.MetricType, string, model.Labels) (*model.Metric, error)); ok {
+		return returnFunc(metricType, metricName, labels)
 	}
-	if returnFunc, ok := ret.Get(0).(func(model.MetricType, string) *model.Metric); ok {
-		r0 = returnFunc(metricType, metricName)
+	if returnFunc, ok := ret.Get(0).(func(model.MetricType, string, model.Labels) *model.Metric); ok {
+		r0 = returnFunc(metricType, metricName, labels)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*model.Metric)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(model.MetricType, string) error); ok {
-		r1 = returnFunc(metricType, metricName)
+	if returnFunc, ok := ret.Get(1).(func(model.MetricType, string, model.Labels) error); ok {
+		r1 = returnFunc(metricType, metricName, labels)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -73,11 +75,12 @@ type MockStorage_Get_Call struct {
 // Get is a helper method to define mock.On call
 //   - metricType model.MetricType
 //   - metricName string
-func (_e *MockStorage_Expecter) Get(metricType interface{}, metricName interface{}) *MockStorage_Get_Call {
-	return &MockStorage_Get_Call{Call: _e.mock.On("Get", metricType, metricName)}
+//   - labels model.Labels
+func (_e *MockStorage_Expecter) Get(metricType interface{}, metricName interface{}, labels interface{}) *MockStorage_Get_Call {
+	return &MockStorage_Get_Call{Call: _e.mock.On("Get", metricType, metricName, labels)}
 }
 
-func (_c *MockStorage_Get_Call) Run(run func(metricType model.MetricType, metricName string)) *MockStorage_Get_Call {
+func (_c *MockStorage_Get_Call) Run(run func(metricType model.MetricType, metricName string, labels model.Labels)) *MockStorage_Get_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 model.MetricType
 		if args[0] != nil {
@@ -87,9 +90,14 @@ func (_c *MockStorage_Get_Call) Run(run func(metricType model.MetricType, metric
 		if args[1] != nil {
 			arg1 = args[1].(string)
 		}
+		var arg2 model.Labels
+		if args[2] != nil {
+			arg2 = args[2].(model.Labels)
+		}
 		run(
 			arg0,
 			arg1,
+			arg2,
 		)
 	})
 	return _c
@@ -100,7 +108,7 @@ func (_c *MockStorage_Get_Call) Return(metric *model.Metric, err error) *MockSto
 	return _c
 }
 
-func (_c *MockStorage_Get_Call) RunAndReturn(run func(metricType model.MetricType, metricName string) (*model.Metric, error)) *MockStorage_Get_Call {
+func (_c *MockStorage_Get_Call) RunAndReturn(run func(metricType model.MetricType, metricName string, labels model.Labels) (*model.Metric, error)) *MockStorage_Get_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -151,6 +159,206 @@ func (_c *MockStorage_List_Call) RunAndReturn(run func() map[string]model.Metric
 	return _c
 }
 
+// ListMetadata provides a mock function for the type MockStorage
+func (_mock *MockStorage) ListMetadata() map[string]model.Metadata {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListMetadata")
+	}
+
+	var r0 map[string]model.Metadata
+	if returnFunc, ok := ret.Get(0).(func() map[string]model.Metadata); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]model.Metadata)
+		}
+	}
+	return r0
+}
+
+// MockStorage_ListMetadata_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListMetadata'
+type MockStorage_ListMetadata_Call struct {
+	*mock.Call
+}
+
+// ListMetadata is a helper method to define mock.On call
+func (_e *MockStorage_Expecter) ListMetadata() *MockStorage_ListMetadata_Call {
+	return &MockStorage_ListMetadata_Call{Call: _e.mock.On("ListMetadata")}
+}
+
+func (_c *MockStorage_ListMetadata_Call) Run(run func()) *MockStorage_ListMetadata_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockStorage_ListMetadata_Call) Return(stringToMetadata map[string]model.Metadata) *MockStorage_ListMetadata_Call {
+	_c.Call.Return(stringToMetadata)
+	return _c
+}
+
+func (_c *MockStorage_ListMetadata_Call) RunAndReturn(run func() map[string]model.Metadata) *MockStorage_ListMetadata_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Metadata provides a mock function for the type MockStorage
+func (_mock *MockStorage) Metadata(metricName string) (*model.Metadata, error) {
+	ret := _mock.Called(metricName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Metadata")
+	}
+
+	var r0 *model.Metadata
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string) (*model.Metadata, error)); ok {
+		return returnFunc(metricName)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string) *model.Metadata); ok {
+		r0 = returnFunc(metricName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Metadata)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(string) error); ok {
+		r1 = returnFunc(metricName)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockStorage_Metadata_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Metadata'
+type MockStorage_Metadata_Call struct {
+	*mock.Call
+}
+
+// Metadata is a helper method to define mock.On call
+//   - metricName string
+func (_e *MockStorage_Expecter) Metadata(metricName interface{}) *MockStorage_Metadata_Call {
+	return &MockStorage_Metadata_Call{Call: _e.mock.On("Metadata", metricName)}
+}
+
+func (_c *MockStorage_Metadata_Call) Run(run func(metricName string)) *MockStorage_Metadata_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockStorage_Metadata_Call) Return(metadata *model.Metadata, err error) *MockStorage_Metadata_Call {
+	_c.Call.Return(metadata, err)
+	return _c
+}
+
+func (_c *MockStorage_Metadata_Call) RunAndReturn(run func(metricName string) (*model.Metadata, error)) *MockStorage_Metadata_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Range provides a mock function for the type MockStorage
+func (_mock *MockStorage) Range(metricType model.MetricType, metricName string, labels model.Labels, from time.Time, to time.Time, step time.Duration) ([]model.Sample, error) {
+	ret := _mock.Called(metricType, metricName, labels, from, to, step)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Range")
+	}
+
+	var r0 []model.Sample
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(model.MetricType, string, model.Labels, time.Time, time.Time, time.Duration) ([]model.Sample, error)); ok {
+		return returnFunc(metricType, metricName, labels, from, to, step)
+	}
+	if returnFunc, ok := ret.Get(0).(func(model.MetricType, string, model.Labels, time.Time, time.Time, time.Duration) []model.Sample); ok {
+		r0 = returnFunc(metricType, metricName, labels, from, to, step)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Sample)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(model.MetricType, string, model.Labels, time.Time, time.Time, time.Duration) error); ok {
+		r1 = returnFunc(metricType, metricName, labels, from, to, step)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockStorage_Range_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Range'
+type MockStorage_Range_Call struct {
+	*mock.Call
+}
+
+// Range is a helper method to define mock.On call
+//   - metricType model.MetricType
+//   - metricName string
+//   - labels model.Labels
+//   - from time.Time
+//   - to time.Time
+//   - step time.Duration
+func (_e *MockStorage_Expecter) Range(metricType interface{}, metricName interface{}, labels interface{}, from interface{}, to interface{}, step interface{}) *MockStorage_Range_Call {
+	return &MockStorage_Range_Call{Call: _e.mock.On("Range", metricType, metricName, labels, from, to, step)}
+}
+
+func (_c *MockStorage_Range_Call) Run(run func(metricType model.MetricType, metricName string, labels model.Labels, from time.Time, to time.Time, step time.Duration)) *MockStorage_Range_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 model.MetricType
+		if args[0] != nil {
+			arg0 = args[0].(model.MetricType)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 model.Labels
+		if args[2] != nil {
+			arg2 = args[2].(model.Labels)
+		}
+		var arg3 time.Time
+		if args[3] != nil {
+			arg3 = args[3].(time.Time)
+		}
+		var arg4 time.Time
+		if args[4] != nil {
+			arg4 = args[4].(time.Time)
+		}
+		var arg5 time.Duration
+		if args[5] != nil {
+			arg5 = args[5].(time.Duration)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+			arg4,
+			arg5,
+		)
+	})
+	return _c
+}
+
+func (_c *MockStorage_Range_Call) Return(samples []model.Sample, err error) *MockStorage_Range_Call {
+	_c.Call.Return(samples, err)
+	return _c
+}
+
+func (_c *MockStorage_Range_Call) RunAndReturn(run func(metricType model.MetricType, metricName string, labels model.Labels, from time.Time, to time.Time, step time.Duration) ([]model.Sample, error)) *MockStorage_Range_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Update provides a mock function for the type MockStorage
 func (_mock *MockStorage) Update(m model.Metric) error {
 	ret := _mock.Called(m)
@@ -201,3 +409,60 @@ func (_c *MockStorage_Update_Call) RunAndReturn(run func(m model.Metric) error)
 	_c.Call.Return(run)
 	return _c
 }
+
+// UpdateMetadata provides a mock function for the type MockStorage
+func (_mock *MockStorage) UpdateMetadata(metricName string, md model.Metadata) error {
+	ret := _mock.Called(metricName, md)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateMetadata")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string, model.Metadata) error); ok {
+		r0 = returnFunc(metricName, md)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockStorage_UpdateMetadata_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateMetadata'
+type MockStorage_UpdateMetadata_Call struct {
+	*mock.Call
+}
+
+// UpdateMetadata is a helper method to define mock.On call
+//   - metricName string
+//   - md model.Metadata
+func (_e *MockStorage_Expecter) UpdateMetadata(metricName interface{}, md interface{}) *MockStorage_UpdateMetadata_Call {
+	return &MockStorage_UpdateMetadata_Call{Call: _e.mock.On("UpdateMetadata", metricName, md)}
+}
+
+func (_c *MockStorage_UpdateMetadata_Call) Run(run func(metricName string, md model.Metadata)) *MockStorage_UpdateMetadata_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 model.Metadata
+		if args[1] != nil {
+			arg1 = args[1].(model.Metadata)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockStorage_UpdateMetadata_Call) Return(err error) *MockStorage_UpdateMetadata_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockStorage_UpdateMetadata_Call) RunAndReturn(run func(metricName string, md model.Metadata) error) *MockStorage_UpdateMetadata_Call {
+	_c.Call.Return(run)
+	return _c
+}
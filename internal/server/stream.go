@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/stream"
+)
+
+const (
+	// StreamBufferSize - количество обновлений, ожидающих отправки подписчику,
+	// при переполнении новые обновления для него отбрасываются
+	StreamBufferSize = 256
+	// StreamKeepAlive - интервал комментариев, не дающих прокси закрыть неактивное соединение
+	StreamKeepAlive = 15 * time.Second
+)
+
+// Параметры запроса потока обновлений
+const (
+	paramMatch = "match"
+	paramType  = "type"
+)
+
+// streamEvent - данные события update, временная метка - время обновления метрики
+type streamEvent struct {
+	model.Metric
+	Timestamp time.Time `json:"timestamp,omitzero"`
+}
+
+// Stream - поток обновлений метрик в формате Server-Sent Events.
+// Параметры match (шаблон имени path.Match) и type можно указать несколько раз,
+// метрика отправляется, если подходит под любой из шаблонов и любой из типов.
+// Если клиент не успевает читать, часть обновлений пропускается,
+// количество пропущенных передается событием dropped.
+func (a *APIServer) Stream(res http.ResponseWriter, req *http.Request) {
+	filter, err := streamFilter(req.URL.Query()[paramMatch], req.URL.Query()[paramType])
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sub := a.hub.Subscribe(StreamBufferSize, filter)
+	if sub == nil {
+		http.Error(res, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer a.hub.Unsubscribe(sub)
+
+	rc := http.NewResponseController(res)
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		a.logger.Error("failed to flush stream", zap.Error(err))
+		return
+	}
+
+	keepAlive := time.NewTicker(StreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(res, ": keepalive\n\n")
+		case m, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if n := sub.TakeDropped(); n > 0 {
+				err = writeEvent(res, "dropped", map[string]uint64{"dropped": n})
+			}
+			if err == nil {
+				err = writeEvent(res, "update", streamEvent{Metric: m, Timestamp: m.Timestamp})
+			}
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			a.logger.Info("stream closed", zap.Error(err))
+			return
+		}
+	}
+}
+
+// writeEvent - событие SSE с данными в формате JSON, JSON не содержит переводов строк
+func writeEvent(res http.ResponseWriter, event string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	_, err = fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event, b)
+	return err
+}
+
+// streamFilter - отбор метрик по шаблонам имени и типам, пустой список не ограничивает отбор
+func streamFilter(patterns []string, types []string) (stream.Filter, error) {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("incorrect match pattern %q: %w", p, err)
+		}
+	}
+	metricTypes := make([]model.MetricType, 0, len(types))
+	for _, t := range types {
+		mt, err := model.NewMetricTypeFromString(t)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", err, t)
+		}
+		metricTypes = append(metricTypes, mt)
+	}
+	if len(patterns) == 0 && len(metricTypes) == 0 {
+		return nil, nil
+	}
+	return func(m *model.Metric) bool {
+		if len(metricTypes) > 0 && !slices.Contains(metricTypes, m.MType) {
+			return false
+		}
+		if len(patterns) == 0 {
+			return true
+		}
+		return slices.ContainsFunc(patterns, func(p string) bool {
+			ok, _ := path.Match(p, m.ID)
+			return ok
+		})
+	}, nil
+}
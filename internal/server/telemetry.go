@@ -0,0 +1,131 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/telemetry"
+)
+
+// Собственные метрики сервера
+const (
+	metricRequests        = "http_requests_total"
+	metricRequestDuration = "http_request_duration_seconds"
+	metricUpdatesAccepted = "updates_accepted_total"
+	metricUpdatesRejected = "updates_rejected_total"
+	metricSeries          = "storage_series"
+	metricGoroutines      = "go_goroutines"
+)
+
+// Метки собственных метрик
+const (
+	labelRoute  = "route"
+	labelMethod = "method"
+	labelStatus = "status"
+	labelReason = "reason"
+)
+
+// routeUnmatched - значение метки route для запросов, не подошедших ни под один маршрут
+const routeUnmatched = "unmatched"
+
+// registerTelemetry - метаданные и вычисляемые метрики сервера
+func (a *APIServer) registerTelemetry() {
+	t := a.telemetry
+	t.Describe(metricRequests, model.Metadata{
+		Type: model.Counter,
+		Help: "HTTP requests by route pattern, method and status code.",
+	})
+	t.Describe(metricRequestDuration, model.Metadata{
+		Type: model.Histogram,
+		Help: "HTTP request handling duration by route pattern.",
+		Unit: "seconds",
+	})
+	t.Describe(metricUpdatesAccepted, model.Metadata{
+		Type: model.Counter,
+		Help: "Metric updates accepted by storage from all receivers.",
+	})
+	t.Describe(metricUpdatesRejected, model.Metadata{
+		Type: model.Counter,
+		Help: "Metric updates rejected by validation or storage, by reason.",
+	})
+	t.Describe(metricSeries, model.Metadata{
+		Type: model.Gauge,
+		Help: "Number of series in storage.",
+	})
+	t.Describe(metricGoroutines, model.Metadata{
+		Type: model.Gauge,
+		Help: "Number of goroutines that currently exist.",
+	})
+	t.GaugeFunc(metricSeries, nil, func() float64 {
+		return float64(len(a.storage.List()))
+	})
+	t.GaugeFunc(metricGoroutines, nil, func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+}
+
+// Telemetry - собственные метрики сервера, в них же можно учитывать метрики
+// других компонентов процесса, например хранилища
+func (a *APIServer) Telemetry() *telemetry.Registry {
+	return a.telemetry
+}
+
+// instrument - учитывает запросы и время их обработки по шаблону маршрута,
+// чтобы значения параметров пути не порождали новые ряды
+func (a *APIServer) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(res, req.ProtoMajor)
+		next.ServeHTTP(ww, req)
+
+		route := routeUnmatched
+		if rc := chi.RouteContext(req.Context()); rc != nil && rc.RoutePattern() != "" {
+			route = rc.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		a.telemetry.Add(metricRequests, model.NewLabels(
+			labelRoute, route,
+			labelMethod, req.Method,
+			labelStatus, strconv.Itoa(status),
+		), 1)
+		a.telemetry.Observe(metricRequestDuration, model.NewLabels(labelRoute, route), time.Since(start).Seconds())
+	})
+}
+
+// InternalMetrics - собственные метрики сервера в текстовом формате, как в List
+func (a *APIServer) InternalMetrics(res http.ResponseWriter, _ *http.Request) {
+	metrics := a.telemetry.Snapshot()
+	metadata := a.telemetry.Metadata()
+
+	var b bytes.Buffer
+	for i, m := range metrics {
+		if md, ok := metadata[m.ID]; ok && (i == 0 || metrics[i-1].ID != m.ID) {
+			writeMetadataText(&b, m.ID, md)
+		}
+		writeMetricText(&b, m)
+	}
+
+	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err := res.Write(b.Bytes()); err != nil {
+		a.logger.Error("failed to write body", zap.Error(err))
+	}
+}
+
+// RunTelemetryExport - выгружает собственные метрики в хранилище сервера с префиксом
+// telemetry.Prefix с интервалом interval до завершения ctx
+func (a *APIServer) RunTelemetryExport(ctx context.Context, interval time.Duration) {
+	e := telemetry.NewExporter(a.telemetry, telemetry.Prefix)
+	e.Run(ctx, telemetryStorage{a.ingest}, interval, a.logger)
+}
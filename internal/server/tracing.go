@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+// tracer - спаны обработчиков и вызовов хранилища, провайдер задается tracing.Setup
+var tracer = otel.Tracer("github.com/mikeziminio/go-custom-metrics/internal/server")
+
+// Атрибуты спанов вызовов хранилища
+const (
+	attrRequestID  = attribute.Key("request_id")
+	attrMetricType = attribute.Key("metric.type")
+	attrMetricID   = attribute.Key("metric.id")
+)
+
+// Tracing - спан на каждый запрос, продолжающий трассировку клиента из заголовка traceparent.
+// Имя спана - метод и шаблон маршрута, как в собственных метриках сервера.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := tracer.Start(ctx, req.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(req.Method),
+				semconv.URLPath(req.URL.Path),
+				attrRequestID.String(middleware.GetReqID(ctx)),
+			),
+		)
+		defer span.End()
+		ww := middleware.NewWrapResponseWriter(res, req.ProtoMajor)
+		next.ServeHTTP(ww, req.WithContext(ctx))
+
+		route := routeUnmatched
+		if rc := chi.RouteContext(req.Context()); rc != nil && rc.RoutePattern() != "" {
+			route = rc.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetName(req.Method + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// tracedStorage - хранилище, вызовы которого записываются дочерними спанами спана запроса
+func (a *APIServer) tracedStorage(ctx context.Context) Storage {
+	return TracedStorage(ctx, a.storage)
+}
+
+// TracedStorage - storage, вызовы которого записываются дочерними спанами спана из ctx,
+// например спана вызова gRPC API
+func TracedStorage(ctx context.Context, storage Storage) Storage {
+	return &tracingStorage{Storage: storage, ctx: ctx}
+}
+
+// contextStorage - хранилище для query.Engine и otlp.Receiver, которые передают контекст запроса
+// в каждый вызов: вызовы выполняются через tracingStorage и попадают в трассировку запроса
+type contextStorage struct {
+	storage Storage
+}
+
+func (s contextStorage) traced(ctx context.Context) *tracingStorage {
+	return &tracingStorage{Storage: s.storage, ctx: ctx}
+}
+
+func (s contextStorage) Update(ctx context.Context, m model.Metric) error {
+	return s.traced(ctx).Update(m)
+}
+
+func (s contextStorage) List(ctx context.Context) map[string]model.Metric {
+	return s.traced(ctx).List()
+}
+
+func (s contextStorage) Get(
+	ctx context.Context,
+	metricType model.MetricType,
+	metricName string,
+	labels model.Labels,
+) (*model.Metric, error) {
+	return s.traced(ctx).Get(metricType, metricName, labels)
+}
+
+func (s contextStorage) Range(
+	ctx context.Context,
+	metricType model.MetricType,
+	metricName string,
+	labels model.Labels,
+	from, to time.Time,
+	step time.Duration,
+) ([]model.Sample, error) {
+	return s.traced(ctx).Range(metricType, metricName, labels, from, to, step)
+}
+
+// tracingStorage - спан "storage.<метод>" на каждый вызов хранилища, ошибка вызова
+// записывается в спан
+type tracingStorage struct {
+	Storage
+	ctx context.Context
+}
+
+func (s *tracingStorage) start(name string, attrs ...attribute.KeyValue) trace.Span {
+	_, span := tracer.Start(s.ctx, "storage."+name, trace.WithAttributes(attrs...))
+	return span
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *tracingStorage) Update(m model.Metric) error {
+	span := s.start("Update", attrMetricType.String(string(m.MType)), attrMetricID.String(m.ID))
+	err := s.Storage.Update(m)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracingStorage) List() map[string]model.Metric {
+	span := s.start("List")
+	metrics := s.Storage.List()
+	span.End()
+	return metrics
+}
+
+func (s *tracingStorage) Get(metricType model.MetricType, metricName string, labels model.Labels) (*model.Metric, error) {
+	span := s.start("Get", attrMetricType.String(string(metricType)), attrMetricID.String(metricName))
+	m, err := s.Storage.Get(metricType, metricName, labels)
+	endSpan(span, err)
+	return m, err
+}
+
+func (s *tracingStorage) Range(
+	metricType model.MetricType,
+	metricName string,
+	labels model.Labels,
+	from, to time.Time,
+	step time.Duration,
+) ([]model.Sample, error) {
+	span := s.start("Range", attrMetricType.String(string(metricType)), attrMetricID.String(metricName))
+	samples, err := s.Storage.Range(metricType, metricName, labels, from, to, step)
+	endSpan(span, err)
+	return samples, err
+}
+
+func (s *tracingStorage) UpdateMetadata(metricName string, md model.Metadata) error {
+	span := s.start("UpdateMetadata", attrMetricID.String(metricName))
+	err := s.Storage.UpdateMetadata(metricName, md)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracingStorage) Metadata(metricName string) (*model.Metadata, error) {
+	span := s.start("Metadata", attrMetricID.String(metricName))
+	md, err := s.Storage.Metadata(metricName)
+	endSpan(span, err)
+	return md, err
+}
+
+func (s *tracingStorage) ListMetadata() map[string]model.Metadata {
+	span := s.start("ListMetadata")
+	md := s.Storage.ListMetadata()
+	span.End()
+	return md
+}
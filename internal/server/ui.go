@@ -0,0 +1,26 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// uiFiles - статические файлы интерфейса графиков, данные загружаются
+// из браузера через /api/v1/query_range
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// UIPrefix - путь, по которому доступен интерфейс графиков
+const UIPrefix = "/ui/"
+
+// UI - обработчик статических файлов интерфейса графиков
+func UI() http.Handler {
+	files, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		// каталог встроен при сборке, ошибка возможна только при его переименовании
+		panic(err)
+	}
+	return http.StripPrefix(UIPrefix, http.FileServerFS(files))
+}
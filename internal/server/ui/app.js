@@ -0,0 +1,326 @@
+// Графики истории рядов по запросам к /api/v1/query_range.
+// Состояние (запросы и интервал) хранится в адресе страницы, сохраненные виды - в localStorage.
+(function () {
+  "use strict";
+
+  var COLORS = ["#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+    "#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"];
+  // MAX_POINTS - примерное количество точек на ряд, определяет шаг запроса
+  var MAX_POINTS = 600;
+  var VIEWS_KEY = "ui.views";
+  var SVG_NS = "http://www.w3.org/2000/svg";
+
+  var $ = function (id) { return document.getElementById(id); };
+  var state = loadState();
+
+  function defaultState() {
+    return { queries: [], range: "3600", from: 0, to: 0 };
+  }
+
+  function loadState() {
+    try {
+      var s = JSON.parse(decodeURIComponent(location.hash.slice(1)));
+      if (s && Array.isArray(s.queries)) return s;
+    } catch (e) { /* пустой или некорректный адрес */ }
+    var q = new URLSearchParams(location.search).get("query");
+    var s2 = defaultState();
+    if (q) s2.queries.push(q);
+    return s2;
+  }
+
+  function saveState() {
+    history.replaceState(null, "", "#" + encodeURIComponent(JSON.stringify(state)));
+  }
+
+  function loadViews() {
+    try {
+      return JSON.parse(localStorage.getItem(VIEWS_KEY)) || {};
+    } catch (e) {
+      return {};
+    }
+  }
+
+  // interval - [start, end] в секундах unix time
+  function interval() {
+    if (state.range === "custom" && state.from && state.to) {
+      return [state.from / 1000, state.to / 1000];
+    }
+    var end = Date.now() / 1000;
+    return [end - parseInt(state.range, 10), end];
+  }
+
+  function fetchRange(query, start, end, step) {
+    var params = new URLSearchParams({ query: query, start: start, end: end, step: step });
+    return fetch("/api/v1/query_range?" + params).then(function (res) {
+      return res.json();
+    }).then(function (body) {
+      if (body.status !== "success") throw new Error(body.error);
+      return body.data.result;
+    });
+  }
+
+  function seriesName(query, metric) {
+    var name = metric.__name__ || "";
+    var labels = Object.keys(metric).filter(function (k) { return k !== "__name__"; }).sort()
+      .map(function (k) { return k + "=\"" + metric[k] + "\""; });
+    if (!name && !labels.length) return query;
+    return name + (labels.length ? "{" + labels.join(",") + "}" : "");
+  }
+
+  function render() {
+    saveState();
+    renderQueries({});
+    var iv = interval();
+    var step = Math.max(1, Math.ceil((iv[1] - iv[0]) / MAX_POINTS));
+    var errors = {};
+    Promise.all(state.queries.map(function (q) {
+      return fetchRange(q, iv[0], iv[1], step).then(function (result) {
+        return result.map(function (s) {
+          return {
+            name: seriesName(q, s.metric),
+            points: s.values.map(function (v) { return [v[0], parseFloat(v[1])]; })
+          };
+        });
+      }, function (err) {
+        errors[q] = err.message;
+        return [];
+      });
+    })).then(function (results) {
+      var series = [].concat.apply([], results);
+      renderQueries(errors);
+      drawChart(series, iv[0], iv[1]);
+      renderLegend(series);
+    });
+  }
+
+  function renderQueries(errors) {
+    var ul = $("queries");
+    ul.textContent = "";
+    state.queries.forEach(function (q, i) {
+      var li = document.createElement("li");
+      li.appendChild(document.createTextNode(q));
+      if (errors[q]) {
+        var e = document.createElement("span");
+        e.className = "error";
+        e.textContent = errors[q];
+        li.appendChild(e);
+      }
+      var rm = document.createElement("button");
+      rm.type = "button";
+      rm.title = "Remove";
+      rm.textContent = "×";
+      rm.addEventListener("click", function () {
+        state.queries.splice(i, 1);
+        render();
+      });
+      li.appendChild(rm);
+      ul.appendChild(li);
+    });
+  }
+
+  function renderLegend(series) {
+    var ul = $("legend");
+    ul.textContent = "";
+    series.forEach(function (s, i) {
+      var li = document.createElement("li");
+      var swatch = document.createElement("i");
+      swatch.style.background = COLORS[i % COLORS.length];
+      li.appendChild(swatch);
+      li.appendChild(document.createTextNode(s.name));
+      ul.appendChild(li);
+    });
+  }
+
+  function el(name, attrs, text) {
+    var e = document.createElementNS(SVG_NS, name);
+    Object.keys(attrs).forEach(function (k) { e.setAttribute(k, attrs[k]); });
+    if (text !== undefined) e.textContent = text;
+    return e;
+  }
+
+  // ticks - "круглые" значения делений оси в интервале [min, max]
+  function ticks(min, max, count) {
+    var span = max - min;
+    var step = Math.pow(10, Math.floor(Math.log10(span / count)));
+    var err = count / span * step;
+    if (err <= 0.15) step *= 10;
+    else if (err <= 0.35) step *= 5;
+    else if (err <= 0.75) step *= 2;
+    var res = [];
+    for (var v = Math.ceil(min / step) * step; v <= max + step / 1e6; v += step) res.push(v);
+    return res;
+  }
+
+  function formatValue(v) {
+    var a = Math.abs(v);
+    if (a >= 1e9) return (v / 1e9).toPrecision(3) + "G";
+    if (a >= 1e6) return (v / 1e6).toPrecision(3) + "M";
+    if (a >= 1e3) return (v / 1e3).toPrecision(3) + "k";
+    return String(parseFloat(v.toPrecision(3)));
+  }
+
+  function formatTime(sec, span) {
+    var d = new Date(sec * 1000);
+    var hm = d.toTimeString().slice(0, span < 600 ? 8 : 5);
+    return span > 86400 ? (d.getMonth() + 1) + "/" + d.getDate() + " " + hm : hm;
+  }
+
+  function drawChart(series, start, end) {
+    var box = $("chart");
+    box.textContent = "";
+    var width = box.clientWidth || 800;
+    var height = 400;
+    var m = { top: 10, right: 20, bottom: 25, left: 60 };
+    var svg = el("svg", { viewBox: "0 0 " + width + " " + height });
+    box.appendChild(svg);
+
+    var min = Infinity, max = -Infinity;
+    series.forEach(function (s) {
+      s.points.forEach(function (p) {
+        if (isFinite(p[1])) {
+          min = Math.min(min, p[1]);
+          max = Math.max(max, p[1]);
+        }
+      });
+    });
+    if (min > max) {
+      svg.appendChild(el("text", { x: width / 2, y: height / 2, "text-anchor": "middle", "class": "empty" },
+        state.queries.length ? "No data" : "Add an expression to plot"));
+      return;
+    }
+    if (min === max) {
+      min -= Math.abs(min) / 10 || 1;
+      max += Math.abs(max) / 10 || 1;
+    }
+
+    var x = function (t) { return m.left + (t - start) / (end - start) * (width - m.left - m.right); };
+    var y = function (v) { return height - m.bottom - (v - min) / (max - min) * (height - m.top - m.bottom); };
+
+    ticks(min, max, 6).forEach(function (v) {
+      svg.appendChild(el("line", { x1: m.left, x2: width - m.right, y1: y(v), y2: y(v), "class": "grid" }));
+      svg.appendChild(el("text", { x: m.left - 6, y: y(v) + 4, "text-anchor": "end" }, formatValue(v)));
+    });
+    var span = end - start;
+    for (var i = 0; i <= 6; i++) {
+      var t = start + span * i / 6;
+      svg.appendChild(el("text", { x: x(t), y: height - 6, "text-anchor": "middle" }, formatTime(t, span)));
+    }
+    svg.appendChild(el("line", { x1: m.left, x2: m.left, y1: m.top, y2: height - m.bottom, "class": "axis" }));
+    svg.appendChild(el("line", {
+      x1: m.left, x2: width - m.right, y1: height - m.bottom, y2: height - m.bottom, "class": "axis"
+    }));
+
+    series.forEach(function (s, i) {
+      // нечисловые значения разрывают линию
+      var d = "", move = true;
+      s.points.forEach(function (p) {
+        if (!isFinite(p[1])) {
+          move = true;
+          return;
+        }
+        d += (move ? "M" : "L") + x(p[0]).toFixed(1) + " " + y(p[1]).toFixed(1);
+        move = false;
+      });
+      var path = el("path", { d: d, stroke: COLORS[i % COLORS.length] });
+      path.appendChild(el("title", {}, s.name));
+      svg.appendChild(path);
+    });
+  }
+
+  function renderViews(selected) {
+    var sel = $("views");
+    var views = loadViews();
+    sel.length = 1;
+    Object.keys(views).sort().forEach(function (name) {
+      sel.add(new Option(name, name, false, name === selected));
+    });
+  }
+
+  function toLocalInput(ms) {
+    var d = new Date(ms - new Date(ms).getTimezoneOffset() * 60000);
+    return d.toISOString().slice(0, 19);
+  }
+
+  function syncRangeInputs() {
+    $("range").value = state.range;
+    $("custom").hidden = state.range !== "custom";
+    if (state.range === "custom") {
+      var iv = interval();
+      $("from").value = toLocalInput((state.from || iv[0] * 1000));
+      $("to").value = toLocalInput((state.to || iv[1] * 1000));
+    }
+  }
+
+  $("add").addEventListener("submit", function (e) {
+    e.preventDefault();
+    var q = $("query").value.trim();
+    if (q && state.queries.indexOf(q) < 0) state.queries.push(q);
+    $("query").value = "";
+    render();
+  });
+
+  $("range").addEventListener("change", function () {
+    if (this.value === "custom") {
+      var iv = interval();
+      state.from = Math.round(iv[0] * 1000);
+      state.to = Math.round(iv[1] * 1000);
+    }
+    state.range = this.value;
+    syncRangeInputs();
+    render();
+  });
+
+  ["from", "to"].forEach(function (id) {
+    $(id).addEventListener("change", function () {
+      var ms = new Date(this.value).getTime();
+      if (!isNaN(ms)) {
+        state[id] = ms;
+        render();
+      }
+    });
+  });
+
+  $("reload").addEventListener("click", render);
+
+  $("save").addEventListener("click", function () {
+    var name = prompt("View name", $("views").value);
+    if (!name) return;
+    var views = loadViews();
+    views[name] = state;
+    localStorage.setItem(VIEWS_KEY, JSON.stringify(views));
+    renderViews(name);
+  });
+
+  $("delete").addEventListener("click", function () {
+    var name = $("views").value;
+    if (!name) return;
+    var views = loadViews();
+    delete views[name];
+    localStorage.setItem(VIEWS_KEY, JSON.stringify(views));
+    renderViews("");
+  });
+
+  $("views").addEventListener("change", function () {
+    var view = loadViews()[this.value];
+    if (!view) return;
+    state = Object.assign(defaultState(), view);
+    syncRangeInputs();
+    render();
+  });
+
+  window.addEventListener("resize", function () {
+    clearTimeout(window.resizeTimer);
+    window.resizeTimer = setTimeout(render, 200);
+  });
+
+  fetch("/api/v1/label/__name__/values").then(function (res) {
+    return res.json();
+  }).then(function (body) {
+    var list = $("names");
+    (body.data || []).forEach(function (name) { list.appendChild(new Option(name)); });
+  }).catch(function () { /* подсказки необязательны */ });
+
+  renderViews("");
+  syncRangeInputs();
+  render();
+})();
@@ -0,0 +1,341 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/coder/websocket"
+	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/stream"
+)
+
+const (
+	// WSSendBuffer - количество сообщений, ожидающих отправки клиенту
+	WSSendBuffer = 16
+	// WSFlushInterval - интервал отправки накопленных обновлений, за интервал
+	// по каждому ряду отправляется только последнее значение
+	WSFlushInterval = 100 * time.Millisecond
+	// WSPingInterval - интервал отправки ping
+	WSPingInterval = 30 * time.Second
+	// WSPongWait - соединение закрывается, если за это время клиент не ответил на ping
+	WSPongWait = 10 * time.Second
+	// WSWriteTimeout - ограничение времени записи одного кадра
+	WSWriteTimeout = 10 * time.Second
+)
+
+// Действия клиента
+const (
+	wsActionSubscribe   = "subscribe"
+	wsActionUnsubscribe = "unsubscribe"
+)
+
+// Типы сообщений сервера
+const (
+	wsTypeSnapshot = "snapshot"
+	wsTypeUpdate   = "update"
+	wsTypeError    = "error"
+)
+
+// errWSInvalidUTF8 - текстовое сообщение клиента не в UTF-8, соединение закрывается кодом 1007
+var errWSInvalidUTF8 = errors.New("websocket: invalid utf-8 in text message")
+
+// wsRequest - сообщение клиента. Unsubscribe без метрик отменяет все подписки.
+type wsRequest struct {
+	Action  string        `json:"action"`
+	Metrics []wsMetricRef `json:"metrics"`
+	// err - ошибка разбора сообщения
+	err error
+}
+
+// wsMetricRef - ряд метрики, на который подписывается клиент
+type wsMetricRef struct {
+	ID     string           `json:"id"`
+	MType  model.MetricType `json:"type"`
+	Labels model.Labels     `json:"labels,omitempty"`
+}
+
+// wsResponse - сообщение сервера: snapshot - текущие значения рядов, на которые клиент
+// только что подписался, update - изменившиеся с предыдущего сообщения ряды
+type wsResponse struct {
+	Type    string        `json:"type"`
+	Metrics []streamEvent `json:"metrics,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// wsSubscriptions - ряды, на которые подписан клиент, ключ - model.SeriesKey.
+// Читается из Hub.Publish, изменяется циклом соединения.
+type wsSubscriptions struct {
+	refs map[string]wsMetricRef
+	mu   sync.RWMutex
+}
+
+func (s *wsSubscriptions) contains(m *model.Metric) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.refs[m.Key()]
+	return ok
+}
+
+// wsClient - соединение подписчика. Обновления копятся в pending и отправляются
+// раз в WSFlushInterval, пока буфер send занят - продолжают копиться, поэтому
+// медленный клиент получает реже, но последние значения.
+type wsClient struct {
+	conn    *websocket.Conn
+	storage Storage
+	logger  *zap.Logger
+	subs    *wsSubscriptions
+	pending map[string]model.Metric
+	send    chan []byte
+	// closeCode, closeReason - передаются клиенту кадром close после закрытия send
+	closeCode   websocket.StatusCode
+	closeReason string
+}
+
+// SetWebSocketOrigins - шаблоны хостов (см. path.Match) источников Origin, страницам которых
+// разрешено подключаться к /api/v1/ws. Источник с хостом запроса и клиенты без заголовка Origin
+// разрешены всегда, остальным браузерным страницам подключение запрещено (403).
+func (a *APIServer) SetWebSocketOrigins(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("incorrect websocket origin pattern %q: %w", p, err)
+		}
+	}
+	a.wsOrigins = slices.Clone(patterns)
+	return nil
+}
+
+// WebSocket - подписка на обновления выбранных рядов метрик. Клиент отправляет
+// {"action":"subscribe","metrics":[{"id":..,"type":..,"labels":{..}}]} и получает
+// текущие значения найденных рядов сообщением snapshot, дальше - изменения сообщениями update.
+// Ряды, которых еще нет в хранилище, придут в update после первой записи.
+func (a *APIServer) WebSocket(res http.ResponseWriter, req *http.Request) {
+	subs := &wsSubscriptions{refs: make(map[string]wsMetricRef)}
+	sub := a.hub.Subscribe(StreamBufferSize, subs.contains)
+	if sub == nil {
+		http.Error(res, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer a.hub.Unsubscribe(sub)
+
+	// при ошибке проверки запроса или Origin ответ клиенту уже отправлен
+	conn, err := websocket.Accept(res, req, &websocket.AcceptOptions{OriginPatterns: a.wsOrigins})
+	if err != nil {
+		a.logger.Info("websocket upgrade failed", zap.Error(err))
+		return
+	}
+	c := &wsClient{
+		conn:      conn,
+		storage:   a.storage,
+		logger:    a.logger,
+		subs:      subs,
+		pending:   make(map[string]model.Metric),
+		send:      make(chan []byte, WSSendBuffer),
+		closeCode: websocket.StatusNormalClosure,
+	}
+	c.run(sub)
+}
+
+func (c *wsClient) run(sub *stream.Subscription) {
+	requests := make(chan wsRequest)
+	readErr := make(chan error, 1)
+	quit := make(chan struct{})
+	defer close(quit)
+	go c.readLoop(requests, readErr, quit)
+
+	writeDone := make(chan struct{})
+	go c.writeLoop(writeDone)
+	defer func() {
+		close(c.send)
+		<-writeDone
+	}()
+
+	flush := time.NewTicker(WSFlushInterval)
+	defer flush.Stop()
+	for {
+		select {
+		case r := <-requests:
+			if err := c.handle(r); err != nil {
+				c.closeCode, c.closeReason = websocket.StatusPolicyViolation, err.Error()
+				return
+			}
+		case err := <-readErr:
+			if errors.Is(err, errWSInvalidUTF8) {
+				c.closeCode, c.closeReason = websocket.StatusInvalidFramePayloadData, "invalid utf-8"
+				return
+			}
+			if websocket.CloseStatus(err) == -1 {
+				c.logger.Info("websocket read failed", zap.Error(err))
+			}
+			return
+		case m, ok := <-sub.C:
+			if !ok {
+				c.closeCode = websocket.StatusGoingAway
+				return
+			}
+			c.pending[m.Key()] = m
+		case <-flush.C:
+			// часть обновлений не поместилась в буфер подписки, перечитываем все ряды
+			if sub.TakeDropped() > 0 {
+				c.resync()
+			}
+			c.flush()
+		}
+	}
+}
+
+// handle - выполняет запрос клиента, ошибка - клиент не успевает читать и соединение нужно закрыть
+func (c *wsClient) handle(r wsRequest) error {
+	if r.err != nil {
+		c.enqueue(wsResponse{Type: wsTypeError, Error: r.err.Error()})
+		return nil
+	}
+	switch r.Action {
+	case wsActionSubscribe:
+		c.subs.mu.Lock()
+		for _, ref := range r.Metrics {
+			c.subs.refs[model.SeriesKey(ref.MType, ref.ID, ref.Labels)] = ref
+		}
+		c.subs.mu.Unlock()
+
+		snapshot := make([]streamEvent, 0, len(r.Metrics))
+		for _, ref := range r.Metrics {
+			m, err := c.storage.Get(ref.MType, ref.ID, ref.Labels)
+			if err != nil {
+				continue
+			}
+			// значение в snapshot не старше накопленного обновления
+			delete(c.pending, m.Key())
+			snapshot = append(snapshot, streamEvent{Metric: *m, Timestamp: m.Timestamp})
+		}
+		if !c.enqueue(wsResponse{Type: wsTypeSnapshot, Metrics: snapshot}) {
+			return errors.New("send buffer is full")
+		}
+	case wsActionUnsubscribe:
+		c.subs.mu.Lock()
+		if len(r.Metrics) == 0 {
+			clear(c.subs.refs)
+			clear(c.pending)
+		}
+		for _, ref := range r.Metrics {
+			key := model.SeriesKey(ref.MType, ref.ID, ref.Labels)
+			delete(c.subs.refs, key)
+			delete(c.pending, key)
+		}
+		c.subs.mu.Unlock()
+	default:
+		c.enqueue(wsResponse{Type: wsTypeError, Error: fmt.Sprintf("unknown action %q", r.Action)})
+	}
+	return nil
+}
+
+// resync - текущие значения всех рядов подписки в pending
+func (c *wsClient) resync() {
+	c.subs.mu.RLock()
+	defer c.subs.mu.RUnlock()
+	for key, ref := range c.subs.refs {
+		if m, err := c.storage.Get(ref.MType, ref.ID, ref.Labels); err == nil {
+			c.pending[key] = *m
+		}
+	}
+}
+
+// flush - отправляет накопленные обновления, если в буфере есть место
+func (c *wsClient) flush() {
+	if len(c.pending) == 0 {
+		return
+	}
+	update := wsResponse{Type: wsTypeUpdate, Metrics: make([]streamEvent, 0, len(c.pending))}
+	for _, key := range slices.Sorted(maps.Keys(c.pending)) {
+		m := c.pending[key]
+		update.Metrics = append(update.Metrics, streamEvent{Metric: m, Timestamp: m.Timestamp})
+	}
+	if c.enqueue(update) {
+		clear(c.pending)
+	}
+}
+
+// enqueue - ставит сообщение в буфер отправки без ожидания, false - буфер заполнен
+func (c *wsClient) enqueue(r wsResponse) bool {
+	b, err := json.Marshal(r)
+	if err != nil {
+		c.logger.Error("failed to marshal websocket message", zap.Error(err))
+		return true
+	}
+	select {
+	case c.send <- b:
+		return true
+	default:
+		return false
+	}
+}
+
+// readLoop - читает запросы клиента, управляющие кадры обрабатываются внутри Read
+func (c *wsClient) readLoop(requests chan<- wsRequest, readErr chan<- error, quit <-chan struct{}) {
+	for {
+		typ, msg, err := c.conn.Read(context.Background())
+		if err == nil && typ == websocket.MessageText && !utf8.Valid(msg) {
+			err = errWSInvalidUTF8
+		}
+		if err != nil {
+			readErr <- err
+			return
+		}
+		var r wsRequest
+		if err := json.Unmarshal(msg, &r); err != nil {
+			r.err = fmt.Errorf("incorrect request: %w", err)
+		}
+		select {
+		case requests <- r:
+		case <-quit:
+			return
+		}
+	}
+}
+
+// writeLoop - отправляет сообщения из send и ping, после закрытия send - кадр close
+func (c *wsClient) writeLoop(done chan<- struct{}) {
+	defer close(done)
+
+	ping := time.NewTicker(WSPingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case msg, ok := <-c.send:
+			if !ok {
+				_ = c.conn.Close(c.closeCode, c.closeReason)
+				return
+			}
+			err = c.write(msg)
+		case <-ping.C:
+			ctx, cancel := context.WithTimeout(context.Background(), WSPongWait)
+			err = c.conn.Ping(ctx)
+			cancel()
+		}
+		if err != nil {
+			c.logger.Info("websocket write failed", zap.Error(err))
+			// закрытие соединения завершает readLoop, а он - цикл соединения
+			_ = c.conn.CloseNow()
+			for range c.send {
+			}
+			return
+		}
+	}
+}
+
+func (c *wsClient) write(msg []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), WSWriteTimeout)
+	defer cancel()
+	return c.conn.Write(ctx, websocket.MessageText, msg)
+}
@@ -0,0 +1,36 @@
+package sign
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// Signer - подпись последовательности сообщений по HMAC-SHA256.
+// Перед каждым сообщением в хеш пишется его длина, чтобы
+// разбиение на сообщения также входило в подпись.
+type Signer struct {
+	key []byte
+}
+
+func New(key string) *Signer {
+	return &Signer{key: []byte(key)}
+}
+
+// Sign - hex-представление подписи сообщений
+func (s *Signer) Sign(msgs ...[]byte) string {
+	h := hmac.New(sha256.New, s.key)
+	var l [binary.MaxVarintLen64]byte
+	for _, msg := range msgs {
+		n := binary.PutUvarint(l[:], uint64(len(msg)))
+		h.Write(l[:n])
+		h.Write(msg)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Verify - проверка подписи сообщений без утечки по времени сравнения
+func (s *Signer) Verify(signature string, msgs ...[]byte) bool {
+	return hmac.Equal([]byte(signature), []byte(s.Sign(msgs...)))
+}
@@ -0,0 +1,52 @@
+package sign
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	s := New("secret")
+
+	// длина сообщения (uvarint) перед каждым сообщением
+	h := hmac.New(sha256.New, []byte("secret"))
+	h.Write([]byte{3})
+	h.Write([]byte("abc"))
+	assert.Equal(t, hex.EncodeToString(h.Sum(nil)), s.Sign([]byte("abc")))
+
+	assert.Equal(t, s.Sign([]byte("a"), []byte("b")), s.Sign([]byte("a"), []byte("b")))
+	// разбиение на сообщения входит в подпись
+	assert.NotEqual(t, s.Sign([]byte("ab"), []byte("c")), s.Sign([]byte("a"), []byte("bc")))
+	assert.NotEqual(t, s.Sign([]byte("abc")), s.Sign([]byte("abc"), nil))
+	assert.NotEqual(t, s.Sign([]byte("abc")), New("other").Sign([]byte("abc")))
+}
+
+func TestVerify(t *testing.T) {
+	s := New("secret")
+	msgs := [][]byte{[]byte("first"), []byte("second")}
+	signature := s.Sign(msgs...)
+
+	testCases := []struct {
+		name      string
+		signer    *Signer
+		signature string
+		msgs      [][]byte
+		expected  bool
+	}{
+		{name: "valid", signer: s, signature: signature, msgs: msgs, expected: true},
+		{name: "wrong key", signer: New("other"), signature: signature, msgs: msgs},
+		{name: "changed message", signer: s, signature: signature, msgs: [][]byte{[]byte("first"), []byte("secon")}},
+		{name: "missing message", signer: s, signature: signature, msgs: msgs[:1]},
+		{name: "empty signature", signer: s, msgs: msgs},
+		{name: "truncated signature", signer: s, signature: signature[:len(signature)-2], msgs: msgs},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.signer.Verify(tc.signature, tc.msgs...))
+		})
+	}
+}
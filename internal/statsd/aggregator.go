@@ -0,0 +1,271 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/server"
+)
+
+// Суффиксы метрик, в которые разворачивается таймер при сбросе
+const (
+	TimerCountSuffix  = ".count"
+	TimerSumSuffix    = ".sum"
+	TimerMinSuffix    = ".min"
+	TimerMaxSuffix    = ".max"
+	TimerMeanSuffix   = ".mean"
+	TimerMedianSuffix = ".median"
+	TimerP90Suffix    = ".p90"
+	TimerP99Suffix    = ".p99"
+)
+
+// Aggregator - накапливает значения StatsD между сбросами в хранилище.
+// Таймеры, кроме агрегатов за интервал, сохраняются как summary с именем таймера,
+// что позволяет оценивать квантили за все время и по нескольким источникам.
+// Счетчики, таймеры и множества обнуляются после каждого сброса,
+// gauge хранят последнее значение, чтобы корректно применять
+// относительные обновления (+N / -N).
+// Значения накапливаются отдельно для каждого набора меток.
+type Aggregator struct {
+	storage  server.Storage
+	counters map[string]*counter
+	gauges   map[string]*gauge
+	timers   map[string]*timer
+	sets     map[string]*set
+	// remainders - дробные части приращений счетчиков (при sample rate < 1), не вошедшие
+	// в округленные значения прошлых сбросов, ключ - model.SeriesKey счетчика
+	remainders map[string]float64
+	mu         sync.Mutex
+}
+
+type series struct {
+	name   string
+	labels model.Labels
+}
+
+type counter struct {
+	series
+	value float64
+}
+
+type gauge struct {
+	series
+	value float64
+	dirty bool
+}
+
+type timer struct {
+	series
+	values []float64
+	count  float64
+}
+
+type set struct {
+	series
+	members map[string]struct{}
+}
+
+func NewAggregator(storage server.Storage) *Aggregator {
+	return &Aggregator{
+		storage:    storage,
+		counters:   make(map[string]*counter),
+		gauges:     make(map[string]*gauge),
+		timers:     make(map[string]*timer),
+		sets:       make(map[string]*set),
+		remainders: make(map[string]float64),
+	}
+}
+
+func (a *Aggregator) Add(s Sample) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := s.Name + s.Labels.String()
+	sr := series{name: s.Name, labels: s.Labels}
+
+	switch s.Type {
+	case Counter:
+		c, ok := a.counters[key]
+		if !ok {
+			c = &counter{series: sr}
+			a.counters[key] = c
+		}
+		c.value += s.Value / s.SampleRate
+	case Gauge:
+		g, ok := a.gauges[key]
+		if !ok {
+			g = &gauge{series: sr, value: a.storedGauge(sr)}
+			a.gauges[key] = g
+		}
+		if s.Relative {
+			g.value += s.Value
+		} else {
+			g.value = s.Value
+		}
+		g.dirty = true
+	case Timer, Hist:
+		t, ok := a.timers[key]
+		if !ok {
+			t = &timer{series: sr}
+			a.timers[key] = t
+		}
+		t.values = append(t.values, s.Value)
+		t.count += 1 / s.SampleRate
+	case Set:
+		st, ok := a.sets[key]
+		if !ok {
+			st = &set{series: sr, members: make(map[string]struct{})}
+			a.sets[key] = st
+		}
+		st.members[s.Member] = struct{}{}
+	}
+}
+
+// storedGauge - значение gauge из хранилища, используется при первом
+// с момента старта обновлении метрики
+func (a *Aggregator) storedGauge(s series) float64 {
+	m, err := a.storage.Get(model.Gauge, s.name, s.labels)
+	if err != nil || m.Value == nil {
+		return 0
+	}
+	return *m.Value
+}
+
+// Flush - переносит накопленные значения в хранилище
+func (a *Aggregator) Flush() error {
+	metrics, errs := a.collect()
+
+	for _, m := range metrics {
+		if err := a.storage.Update(m); err != nil {
+			errs = append(errs, fmt.Errorf("failed to update metric %s: %w", m.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// collect - накопленные метрики и ошибки построения тех, что не удалось сформировать
+func (a *Aggregator) collect() ([]model.Metric, []error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var metrics []model.Metric
+	var errs []error
+
+	for _, c := range a.counters {
+		metrics = append(metrics, c.newCounter(c.name, a.round(model.SeriesKey(model.Counter, c.name, c.labels), c.value)))
+	}
+	clear(a.counters)
+
+	for _, g := range a.gauges {
+		if g.dirty {
+			metrics = append(metrics, g.newGauge(g.name, g.value))
+			g.dirty = false
+		}
+	}
+
+	for _, t := range a.timers {
+		count := a.round(model.SeriesKey(model.Counter, t.name+TimerCountSuffix, t.labels), t.count)
+		tm, err := t.metrics(count)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		metrics = append(metrics, tm...)
+	}
+	clear(a.timers)
+
+	for _, st := range a.sets {
+		metrics = append(metrics, st.newSet(st.name, slices.Sorted(maps.Keys(st.members))))
+	}
+	clear(a.sets)
+
+	return metrics, errs
+}
+
+// round - целая часть приращения счетчика с ключом key вместе с остатком прошлых сбросов,
+// дробная часть переносится на следующий сброс, чтобы счетчики с sample rate не занижались
+func (a *Aggregator) round(key string, v float64) int64 {
+	v += a.remainders[key]
+	n := math.Round(v)
+	if r := v - n; r != 0 {
+		a.remainders[key] = r
+	} else {
+		delete(a.remainders, key)
+	}
+	return int64(n)
+}
+
+// metrics - агрегаты таймера за интервал, count - округленное количество значений с учетом sample rate.
+// Если не удалось построить summary, возвращаются остальные агрегаты вместе с ошибкой.
+func (t *timer) metrics(count int64) ([]model.Metric, error) {
+	slices.Sort(t.values)
+	n := len(t.values)
+	var sum float64
+	for _, v := range t.values {
+		sum += v
+	}
+	metrics := []model.Metric{
+		t.newCounter(t.name+TimerCountSuffix, count),
+		t.newGauge(t.name+TimerSumSuffix, sum),
+		t.newGauge(t.name+TimerMinSuffix, t.values[0]),
+		t.newGauge(t.name+TimerMaxSuffix, t.values[n-1]),
+		t.newGauge(t.name+TimerMeanSuffix, sum/float64(n)),
+		t.newGauge(t.name+TimerMedianSuffix, percentile(t.values, 50)),
+		t.newGauge(t.name+TimerP90Suffix, percentile(t.values, 90)),
+		t.newGauge(t.name+TimerP99Suffix, percentile(t.values, 99)),
+	}
+	sketch, err := model.NewSummary(model.DefaultRelativeAccuracy)
+	if err != nil {
+		return metrics, fmt.Errorf("failed to create summary %s: %w", t.name, err)
+	}
+	for _, v := range t.values {
+		sketch.Observe(v)
+	}
+	return append([]model.Metric{t.newSummary(t.name, sketch)}, metrics...), nil
+}
+
+// percentile - перцентиль по методу nearest-rank, values должны быть отсортированы
+func percentile(values []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(values))))
+	return values[max(rank, 1)-1]
+}
+
+func (s *series) newCounter(name string, delta int64) model.Metric {
+	return model.Metric{
+		ID:     name,
+		MType:  model.Counter,
+		Labels: s.labels,
+		Delta:  &delta,
+	}
+}
+
+func (s *series) newGauge(name string, value float64) model.Metric {
+	return model.Metric{
+		ID:     name,
+		MType:  model.Gauge,
+		Labels: s.labels,
+		Value:  &value,
+	}
+}
+
+func (s *series) newSummary(name string, sketch *model.SummaryData) model.Metric {
+	return model.Metric{
+		ID:      name,
+		MType:   model.Summary,
+		Labels:  s.labels,
+		Summary: sketch,
+	}
+}
+
+func (s *series) newSet(name string, members []string) model.Metric {
+	return model.Metric{
+		ID:      name,
+		MType:   model.Set,
+		Labels:  s.labels,
+		Members: members,
+	}
+}
@@ -0,0 +1,98 @@
+package statsd
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/memstorage"
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+	"github.com/mikeziminio/go-custom-metrics/internal/test/helper"
+)
+
+func TestAggregatorFlush(t *testing.T) {
+	ms := memstorage.New(0)
+	err := ms.Update(model.Metric{
+		ID:    "connections",
+		MType: model.Gauge,
+		Value: helper.NewFloat64(t, 10),
+	})
+	require.NoError(t, err)
+
+	a := NewAggregator(ms)
+	samples, errs := ParsePacket([]byte(
+		"requests:1|c|@0.5\n" +
+			"requests:2|c\n" +
+			"requests:1|c|#env:prod\n" +
+			"connections:+5|g\n" +
+			"connections:-3|g\n" +
+			"latency:10|ms\n" +
+			"latency:30|ms\n" +
+			"latency:20|h\n" +
+			"users:alice|s\n" +
+			"users:bob|s\n" +
+			"users:alice|s\n",
+	))
+	require.Empty(t, errs)
+	for _, s := range samples {
+		a.Add(s)
+	}
+	require.NoError(t, a.Flush())
+
+	var expected []model.Metric
+	s := series{name: "latency"}
+	sketch, err := model.NewSummary(model.DefaultRelativeAccuracy)
+	require.NoError(t, err)
+	for _, v := range []float64{10, 30, 20} {
+		sketch.Observe(v)
+	}
+	expected = append(expected,
+		s.newSummary("latency", sketch),
+		s.newCounter("latency"+TimerCountSuffix, 3),
+		s.newGauge("latency"+TimerSumSuffix, 60),
+		s.newGauge("latency"+TimerMinSuffix, 10),
+		s.newGauge("latency"+TimerMaxSuffix, 30),
+		s.newGauge("latency"+TimerMeanSuffix, 20),
+		s.newGauge("latency"+TimerMedianSuffix, 20),
+		s.newGauge("latency"+TimerP90Suffix, 30),
+		s.newGauge("latency"+TimerP99Suffix, 30),
+	)
+	s = series{name: "requests"}
+	expected = append(expected, s.newCounter("requests", 4))
+	s = series{name: "requests", labels: model.NewLabels("env", "prod")}
+	expected = append(expected, s.newCounter("requests", 1))
+	s = series{name: "connections"}
+	expected = append(expected, s.newGauge("connections", 12))
+	users, err := model.NewSet(model.DefaultSetPrecision)
+	require.NoError(t, err)
+	users.Add("alice")
+	users.Add("bob")
+	expected = append(expected, model.Metric{ID: "users", MType: model.Set, Set: users})
+
+	// время обновления задается хранилищем и не сравнивается
+	stored := slices.Collect(maps.Values(ms.List()))
+	for i := range stored {
+		stored[i].Timestamp = time.Time{}
+	}
+	assert.ElementsMatch(t, expected, stored)
+
+	// после сброса счетчик в агрегаторе обнуляется, а в хранилище продолжает расти
+	a.Add(Sample{Name: "requests", Type: Counter, Value: 1, SampleRate: 1})
+	require.NoError(t, a.Flush())
+	m, err := ms.Get(model.Counter, "requests", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), *m.Delta)
+
+	// дробные приращения при sample rate накапливаются между сбросами
+	for range 10 {
+		a.Add(Sample{Name: "sampled", Type: Counter, Value: 1, SampleRate: 0.3})
+		require.NoError(t, a.Flush())
+	}
+	m, err = ms.Get(model.Counter, "sampled", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(33), *m.Delta)
+}
@@ -0,0 +1,90 @@
+package statsd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/server"
+)
+
+// maxPacketSize - максимальный размер UDP датаграммы
+const maxPacketSize = 65535
+
+// Listener - UDP сервер, принимающий метрики по протоколу StatsD
+type Listener struct {
+	address       string
+	flushInterval float64
+	aggregator    *Aggregator
+	logger        *zap.Logger
+}
+
+func New(address string, flushInterval float64, storage server.Storage, logger *zap.Logger) *Listener {
+	return &Listener{
+		address:       address,
+		flushInterval: flushInterval,
+		aggregator:    NewAggregator(storage),
+		logger:        logger,
+	}
+}
+
+// Run - слушает UDP порт до отмены контекста, при остановке
+// сбрасывает в хранилище все накопленные значения
+func (l *Listener) Run(ctx context.Context) {
+	conn, err := (&net.ListenConfig{}).ListenPacket(ctx, "udp", l.address)
+	if err != nil {
+		l.logger.Fatal("failed to start statsd listener", zap.Error(err))
+	}
+	l.logger.Info("StatsD listener started", zap.String("address", conn.LocalAddr().String()))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.serve(conn)
+	}()
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) * l.flushInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+			<-done
+			l.flush()
+			l.logger.Info("StatsD listener stopped")
+			return
+		case <-ticker.C:
+			l.flush()
+		}
+	}
+}
+
+func (l *Listener) serve(conn net.PacketConn) {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			l.logger.Error("failed to read statsd packet", zap.Error(err))
+			continue
+		}
+		samples, errs := ParsePacket(buf[:n])
+		for _, err := range errs {
+			l.logger.Warn("skip statsd line", zap.Error(err))
+		}
+		for _, s := range samples {
+			l.aggregator.Add(s)
+		}
+	}
+}
+
+func (l *Listener) flush() {
+	if err := l.aggregator.Flush(); err != nil {
+		l.logger.Error("failed to flush statsd metrics", zap.Error(err))
+	}
+}
@@ -0,0 +1,129 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+type SampleType string
+
+const (
+	Counter SampleType = "c"
+	Gauge   SampleType = "g"
+	Timer   SampleType = "ms"
+	Hist    SampleType = "h"
+	Set     SampleType = "s"
+)
+
+var ErrInvalidLine = errors.New("invalid statsd line")
+
+// Sample - одно значение из строки протокола StatsD вида
+// <name>:<value>|<type>[|@<sample rate>][|#<tags>], теги становятся метками метрики
+type Sample struct {
+	Name       string
+	Labels     model.Labels
+	Type       SampleType
+	Value      float64
+	SampleRate float64
+	// Relative - для gauge со знаком +/- значение является приращением
+	Relative bool
+	// Member - значение элемента множества для типа s
+	Member string
+}
+
+func ParseLine(line string) (Sample, error) {
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return Sample{}, fmt.Errorf("%w: %q", ErrInvalidLine, line)
+	}
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 {
+		return Sample{}, fmt.Errorf("%w: %q", ErrInvalidLine, line)
+	}
+
+	s := Sample{
+		Name:       name,
+		Type:       SampleType(parts[1]),
+		SampleRate: 1,
+	}
+	val := parts[0]
+
+	for _, p := range parts[2:] {
+		switch {
+		case strings.HasPrefix(p, "@"):
+			r, err := strconv.ParseFloat(p[1:], 64)
+			if err != nil || r <= 0 || r > 1 {
+				return Sample{}, fmt.Errorf("%w: incorrect sample rate %q", ErrInvalidLine, p)
+			}
+			s.SampleRate = r
+		case strings.HasPrefix(p, "#"):
+			labels, err := parseTags(p[1:])
+			if err != nil {
+				return Sample{}, err
+			}
+			s.Labels = labels
+		default:
+			return Sample{}, fmt.Errorf("%w: unknown section %q", ErrInvalidLine, p)
+		}
+	}
+
+	switch s.Type {
+	case Set:
+		if val == "" {
+			return Sample{}, fmt.Errorf("%w: empty set member", ErrInvalidLine)
+		}
+		s.Member = val
+		return s, nil
+	case Gauge:
+		s.Relative = strings.HasPrefix(val, "+") || strings.HasPrefix(val, "-")
+	case Counter, Timer, Hist:
+	default:
+		return Sample{}, fmt.Errorf("%w: unknown type %q", ErrInvalidLine, s.Type)
+	}
+
+	v, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return Sample{}, fmt.Errorf("%w: incorrect value %q", ErrInvalidLine, val)
+	}
+	s.Value = v
+
+	return s, nil
+}
+
+// parseTags - теги в формате DogStatsD: tag1:value1,tag2:value2,
+// тег без значения сохраняется как метка с пустым значением
+func parseTags(s string) (model.Labels, error) {
+	labels := make(model.Labels)
+	for tag := range strings.SplitSeq(s, ",") {
+		name, value, _ := strings.Cut(tag, ":")
+		labels[name] = value
+	}
+	if err := labels.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLine, err)
+	}
+	return labels, nil
+}
+
+// ParsePacket - разбирает пакет из нескольких строк, разделенных \n.
+// Некорректные строки пропускаются, ошибки по ним возвращаются отдельно.
+func ParsePacket(packet []byte) ([]Sample, []error) {
+	var samples []Sample
+	var errs []error
+	for line := range strings.SplitSeq(string(packet), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		s, err := ParseLine(line)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		samples = append(samples, s)
+	}
+	return samples, errs
+}
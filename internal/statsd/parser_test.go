@@ -0,0 +1,120 @@
+package statsd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+func TestParseLine(t *testing.T) {
+	testCases := []struct {
+		name           string
+		line           string
+		expectedSample Sample
+		expectedErr    bool
+	}{
+		{
+			name: "counter",
+			line: "requests:3|c",
+			expectedSample: Sample{
+				Name:       "requests",
+				Type:       Counter,
+				Value:      3,
+				SampleRate: 1,
+			},
+		},
+		{
+			name: "counter with sample rate",
+			line: "requests:1|c|@0.1",
+			expectedSample: Sample{
+				Name:       "requests",
+				Type:       Counter,
+				Value:      1,
+				SampleRate: 0.1,
+			},
+		},
+		{
+			name: "gauge",
+			line: "temperature:36.6|g",
+			expectedSample: Sample{
+				Name:       "temperature",
+				Type:       Gauge,
+				Value:      36.6,
+				SampleRate: 1,
+			},
+		},
+		{
+			name: "relative gauge",
+			line: "connections:-2|g",
+			expectedSample: Sample{
+				Name:       "connections",
+				Type:       Gauge,
+				Value:      -2,
+				SampleRate: 1,
+				Relative:   true,
+			},
+		},
+		{
+			name: "timer with tags",
+			line: "latency:320|ms|#env:prod,canary",
+			expectedSample: Sample{
+				Name:       "latency",
+				Labels:     model.NewLabels("env", "prod", "canary", ""),
+				Type:       Timer,
+				Value:      320,
+				SampleRate: 1,
+			},
+		},
+		{
+			name: "set",
+			line: "users:alice|s",
+			expectedSample: Sample{
+				Name:       "users",
+				Type:       Set,
+				SampleRate: 1,
+				Member:     "alice",
+			},
+		},
+		{
+			name:        "no type",
+			line:        "requests:3",
+			expectedErr: true,
+		},
+		{
+			name:        "unknown type",
+			line:        "requests:3|x",
+			expectedErr: true,
+		},
+		{
+			name:        "incorrect value",
+			line:        "requests:abc|c",
+			expectedErr: true,
+		},
+		{
+			name:        "incorrect sample rate",
+			line:        "requests:1|c|@2",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := ParseLine(tc.line)
+			if tc.expectedErr {
+				require.ErrorIs(t, err, ErrInvalidLine)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedSample, s)
+		})
+	}
+}
+
+func TestParsePacket(t *testing.T) {
+	samples, errs := ParsePacket([]byte("a:1|c\nbad\n\nb:2|g\n"))
+	assert.Len(t, samples, 2)
+	assert.Len(t, errs, 1)
+}
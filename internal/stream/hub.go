@@ -0,0 +1,94 @@
+package stream
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+// Filter - отбор метрик для подписки, nil - все метрики
+type Filter func(m *model.Metric) bool
+
+// Subscription - подписка на обновления метрик. Обновления, не поместившиеся
+// в буфер C, отбрасываются, чтобы медленный подписчик не задерживал запись.
+type Subscription struct {
+	C       <-chan model.Metric
+	c       chan model.Metric
+	filter  Filter
+	dropped atomic.Uint64
+}
+
+// TakeDropped - количество отброшенных с предыдущего вызова обновлений
+func (s *Subscription) TakeDropped() uint64 {
+	return s.dropped.Swap(0)
+}
+
+// Hub - рассылка обновлений метрик подписчикам
+type Hub struct {
+	subs   map[*Subscription]struct{}
+	closed bool
+	mu     sync.RWMutex
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe - новая подписка с буфером на buffer обновлений,
+// после закрытия Hub - nil
+func (h *Hub) Subscribe(buffer int, filter Filter) *Subscription {
+	c := make(chan model.Metric, buffer)
+	s := &Subscription{C: c, c: c, filter: filter}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	h.subs[s] = struct{}{}
+	return s
+}
+
+// Unsubscribe - отменяет подписку и закрывает ее канал
+func (h *Hub) Unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.c)
+	}
+}
+
+// Active - есть хотя бы одна подписка
+func (h *Hub) Active() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs) > 0
+}
+
+// Publish - отправляет обновление подходящим подписчикам без ожидания
+func (h *Hub) Publish(m model.Metric) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.subs {
+		if s.filter != nil && !s.filter(&m) {
+			continue
+		}
+		select {
+		case s.c <- m:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Close - закрывает каналы всех подписок, новые подписки не принимаются
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs {
+		close(s.c)
+	}
+	clear(h.subs)
+	h.closed = true
+}
@@ -0,0 +1,42 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+func TestHub(t *testing.T) {
+	h := NewHub()
+	assert.False(t, h.Active())
+
+	all := h.Subscribe(1, nil)
+	counters := h.Subscribe(10, func(m *model.Metric) bool { return m.MType == model.Counter })
+	require.True(t, h.Active())
+
+	h.Publish(model.Metric{ID: "a", MType: model.Counter})
+	h.Publish(model.Metric{ID: "b", MType: model.Gauge})
+	h.Publish(model.Metric{ID: "c", MType: model.Counter})
+
+	// буфер all заполнен первым обновлением, остальные отброшены
+	assert.Equal(t, "a", (<-all.C).ID)
+	assert.Equal(t, uint64(2), all.TakeDropped())
+	assert.Zero(t, all.TakeDropped())
+
+	assert.Equal(t, "a", (<-counters.C).ID)
+	assert.Equal(t, "c", (<-counters.C).ID)
+	assert.Zero(t, counters.TakeDropped())
+
+	h.Unsubscribe(all)
+	_, ok := <-all.C
+	assert.False(t, ok)
+
+	h.Close()
+	_, ok = <-counters.C
+	assert.False(t, ok)
+	assert.Nil(t, h.Subscribe(1, nil))
+	assert.False(t, h.Active())
+}
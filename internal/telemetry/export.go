@@ -0,0 +1,151 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+// Prefix - зарезервированный префикс имен собственных метрик сервера в хранилище
+const Prefix = "internal_"
+
+// Storage - хранилище, в которое выгружаются собственные метрики
+type Storage interface {
+	Update(m model.Metric) error
+	UpdateMetadata(metricName string, md model.Metadata) error
+}
+
+// Exporter - выгружает метрики Registry с префиксом имени. Хранилище суммирует
+// счетчики и гистограммы, поэтому для них выгружается приращение с предыдущей
+// успешной выгрузки, для gauge - текущее значение.
+type Exporter struct {
+	registry *Registry
+	prefix   string
+	// prev - выгруженные значения без приращений, ключ - model.Metric.Key с префиксом
+	prev map[string]model.Metric
+	// pending - значения последнего Collect, ожидающие Commit
+	pending map[string]model.Metric
+	// described - имена метрик, метаданные которых уже выгружены
+	described map[string]struct{}
+}
+
+func NewExporter(registry *Registry, prefix string) *Exporter {
+	return &Exporter{
+		registry:  registry,
+		prefix:    prefix,
+		prev:      make(map[string]model.Metric),
+		pending:   make(map[string]model.Metric),
+		described: make(map[string]struct{}),
+	}
+}
+
+// Metadata - метаданные метрик, ключ - имя метрики с префиксом
+func (e *Exporter) Metadata() map[string]model.Metadata {
+	md := e.registry.Metadata()
+	res := make(map[string]model.Metadata, len(md))
+	for name, m := range md {
+		res[e.prefix+name] = m
+	}
+	return res
+}
+
+// Collect - метрики с префиксом для выгрузки. Ряды, не изменившиеся с прошлой выгрузки,
+// тоже выгружаются, чтобы в истории не было пропусков. Приращения считаются выгруженными
+// только после Commit, до этого следующий Collect вернет их повторно.
+func (e *Exporter) Collect() []model.Metric {
+	snapshot := e.registry.Snapshot()
+	clear(e.pending)
+	res := make([]model.Metric, 0, len(snapshot))
+	for _, m := range snapshot {
+		m.ID = e.prefix + m.ID
+		key := m.Key()
+		d, err := delta(m, e.prev[key])
+		if err != nil {
+			// границы гистограмм Registry не меняются, сюда не попадаем
+			continue
+		}
+		e.pending[key] = m
+		res = append(res, d)
+	}
+	return res
+}
+
+// Discard - ряд m из последнего Collect не выгружен, Commit его не учитывает
+func (e *Exporter) Discard(m model.Metric) {
+	delete(e.pending, m.Key())
+}
+
+// Commit - значения последнего Collect выгружены, кроме отмеченных Discard
+func (e *Exporter) Commit() {
+	for key, m := range e.pending {
+		e.prev[key] = m
+	}
+	clear(e.pending)
+}
+
+// Export - выгружает метаданные, которые еще не выгружались, и значения метрик в storage.
+// Ряды, которые не удалось выгрузить, будут выгружены следующим вызовом.
+func (e *Exporter) Export(storage Storage) error {
+	var errs []error
+	for name, md := range e.Metadata() {
+		if _, ok := e.described[name]; ok {
+			continue
+		}
+		if err := storage.UpdateMetadata(name, md); err != nil {
+			errs = append(errs, fmt.Errorf("failed to export metadata %s: %w", name, err))
+			continue
+		}
+		e.described[name] = struct{}{}
+	}
+
+	for _, m := range e.Collect() {
+		if err := storage.Update(m); err != nil {
+			errs = append(errs, fmt.Errorf("failed to export %s: %w", m.Key(), err))
+			e.Discard(m)
+		}
+	}
+	e.Commit()
+	return errors.Join(errs...)
+}
+
+// delta - приращение m относительно prev, prev - нулевая метрика, если ряд еще не выгружался
+func delta(m, prev model.Metric) (model.Metric, error) {
+	switch {
+	case m.MType == model.Counter && prev.Delta != nil:
+		d := *m.Delta - *prev.Delta
+		m.Delta = &d
+	case m.MType == model.Histogram && prev.Histogram != nil:
+		h := m.Histogram.Clone()
+		if len(h.Counts) != len(prev.Histogram.Counts) {
+			return model.Metric{}, fmt.Errorf("%w: bounds mismatch", model.ErrInvalidHistogram)
+		}
+		for i, c := range prev.Histogram.Counts {
+			h.Counts[i] -= c
+		}
+		h.Sum -= prev.Histogram.Sum
+		h.Count -= prev.Histogram.Count
+		m.Histogram = h
+	}
+	return m, nil
+}
+
+// Run - выгружает метрики в storage с интервалом interval до завершения ctx
+func (e *Exporter) Run(ctx context.Context, storage Storage, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Export(storage); err != nil {
+				logger.Error("failed to export telemetry", zap.Error(err))
+			}
+		}
+	}
+}
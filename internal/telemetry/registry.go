@@ -0,0 +1,132 @@
+// Package telemetry - собственные метрики процессов сервера и агента в модели model.Metric
+package telemetry
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+// Registry - счетчики, гистограммы и вычисляемые метрики. Значения накапливаются
+// с запуска процесса, метаданные задаются Describe.
+type Registry struct {
+	metrics  map[string]*model.Metric
+	funcs    map[string]metricFunc
+	metadata map[string]model.Metadata
+	mu       sync.Mutex
+}
+
+// metricFunc - gauge или счетчик, значение которого вычисляется при каждом Snapshot,
+// задана одна из функций gauge и counter
+type metricFunc struct {
+	name    string
+	mtype   model.MetricType
+	labels  model.Labels
+	gauge   func() float64
+	counter func() int64
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics:  make(map[string]*model.Metric),
+		funcs:    make(map[string]metricFunc),
+		metadata: make(map[string]model.Metadata),
+	}
+}
+
+// Describe - метаданные метрики с именем name
+func (r *Registry) Describe(name string, md model.Metadata) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metadata[name] = md
+}
+
+// Add - увеличивает счетчик на delta
+func (r *Registry) Add(name string, labels model.Labels, delta int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := model.SeriesKey(model.Counter, name, labels)
+	m, ok := r.metrics[key]
+	if !ok {
+		m = &model.Metric{ID: name, MType: model.Counter, Labels: labels, Delta: new(int64)}
+		r.metrics[key] = m
+	}
+	*m.Delta += delta
+}
+
+// Observe - добавляет наблюдение в гистограмму с границами model.DefaultBuckets
+func (r *Registry) Observe(name string, labels model.Labels, v float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := model.SeriesKey(model.Histogram, name, labels)
+	m, ok := r.metrics[key]
+	if !ok {
+		h, _ := model.NewHistogram(model.DefaultBuckets) // границы по умолчанию корректны
+		m = &model.Metric{ID: name, MType: model.Histogram, Labels: labels, Histogram: h}
+		r.metrics[key] = m
+	}
+	m.Histogram.Observe(v)
+}
+
+// GaugeFunc - gauge, значение которого возвращает f. f вызывается без блокировки Registry
+// и может обращаться к другим компонентам, например к хранилищу.
+func (r *Registry) GaugeFunc(name string, labels model.Labels, f func() float64) {
+	r.addFunc(metricFunc{name: name, mtype: model.Gauge, labels: labels, gauge: f})
+}
+
+// CounterFunc - счетчик, который ведет другой компонент, значение с запуска возвращает f.
+// f вызывается без блокировки Registry.
+func (r *Registry) CounterFunc(name string, labels model.Labels, f func() int64) {
+	r.addFunc(metricFunc{name: name, mtype: model.Counter, labels: labels, counter: f})
+}
+
+func (r *Registry) addFunc(mf metricFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs[model.SeriesKey(mf.mtype, mf.name, mf.labels)] = mf
+}
+
+// Snapshot - текущие значения всех метрик, упорядоченные по имени и ряду
+func (r *Registry) Snapshot() []model.Metric {
+	r.mu.Lock()
+	res := make([]model.Metric, 0, len(r.metrics)+len(r.funcs))
+	for _, m := range r.metrics {
+		c := *m
+		if m.Delta != nil {
+			delta := *m.Delta
+			c.Delta = &delta
+		}
+		if m.Histogram != nil {
+			c.Histogram = m.Histogram.Clone()
+		}
+		res = append(res, c)
+	}
+	funcs := slices.Collect(maps.Values(r.funcs))
+	r.mu.Unlock()
+
+	for _, mf := range funcs {
+		m := model.Metric{ID: mf.name, MType: mf.mtype, Labels: mf.labels}
+		if mf.counter != nil {
+			d := mf.counter()
+			m.Delta = &d
+		} else {
+			v := mf.gauge()
+			m.Value = &v
+		}
+		res = append(res, m)
+	}
+	slices.SortFunc(res, func(m1, m2 model.Metric) int {
+		return cmp.Or(cmp.Compare(m1.ID, m2.ID), cmp.Compare(m1.Key(), m2.Key()))
+	})
+	return res
+}
+
+// Metadata - метаданные метрик, ключ - имя метрики
+func (r *Registry) Metadata() map[string]model.Metadata {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return maps.Clone(r.metadata)
+}
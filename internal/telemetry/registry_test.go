@@ -0,0 +1,84 @@
+package telemetry
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mikeziminio/go-custom-metrics/internal/model"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.Add("requests", model.NewLabels("code", "500"), 1)
+	r.Add("requests", model.NewLabels("code", "200"), 2)
+	r.Add("requests", model.NewLabels("code", "200"), 3)
+	r.Observe("latency", nil, 0.02)
+	r.Observe("latency", nil, 3)
+	r.GaugeFunc("goroutines", nil, func() float64 { return 7 })
+
+	snapshot := r.Snapshot()
+	require.Len(t, snapshot, 4)
+	assert.Equal(t, "goroutines", snapshot[0].ID)
+	assert.InDelta(t, 7, *snapshot[0].Value, 0)
+	assert.Equal(t, "latency", snapshot[1].ID)
+	assert.Equal(t, uint64(2), snapshot[1].Histogram.Count)
+	assert.Equal(t, model.NewLabels("code", "200"), snapshot[2].Labels)
+	assert.Equal(t, int64(5), *snapshot[2].Delta)
+	assert.Equal(t, int64(1), *snapshot[3].Delta)
+
+	// снимок не меняется при следующих обновлениях
+	r.Add("requests", model.NewLabels("code", "200"), 1)
+	r.Observe("latency", nil, 1)
+	assert.Equal(t, int64(5), *snapshot[2].Delta)
+	assert.Equal(t, uint64(2), snapshot[1].Histogram.Count)
+}
+
+type storageStub struct {
+	updates  []model.Metric
+	metadata map[string]model.Metadata
+}
+
+func (s *storageStub) Update(m model.Metric) error {
+	s.updates = append(s.updates, m)
+	return nil
+}
+
+func (s *storageStub) UpdateMetadata(metricName string, md model.Metadata) error {
+	s.metadata[metricName] = md
+	return nil
+}
+
+func TestExporter(t *testing.T) {
+	r := NewRegistry()
+	r.Describe("requests", model.Metadata{Type: model.Counter, Help: "Requests."})
+	r.Add("requests", nil, 3)
+	r.Observe("latency", nil, 0.02)
+	storage := &storageStub{metadata: make(map[string]model.Metadata)}
+	e := NewExporter(r, Prefix)
+
+	require.NoError(t, e.Export(storage))
+	assert.Equal(t, map[string]model.Metadata{
+		"internal_requests": {Type: model.Counter, Help: "Requests."},
+	}, storage.metadata)
+	require.Len(t, storage.updates, 2)
+	assert.Equal(t, "internal_latency", storage.updates[0].ID)
+	assert.Equal(t, uint64(1), storage.updates[0].Histogram.Count)
+	assert.Equal(t, "internal_requests", storage.updates[1].ID)
+	assert.Equal(t, int64(3), *storage.updates[1].Delta)
+
+	// выгружается приращение с прошлой выгрузки
+	r.Add("requests", nil, 2)
+	r.Observe("latency", nil, 3)
+	storage.updates = nil
+	require.NoError(t, e.Export(storage))
+	require.Len(t, storage.updates, 2)
+	h := storage.updates[0].Histogram
+	assert.Equal(t, uint64(1), h.Count)
+	assert.InDelta(t, 3, h.Sum, 1e-9)
+	assert.Equal(t, uint64(1), h.Counts[slices.Index(model.DefaultBuckets, 5)])
+	assert.NoError(t, h.Validate())
+	assert.Equal(t, int64(2), *storage.updates[1].Delta)
+}
@@ -0,0 +1,119 @@
+// Package tracing - трассировка OpenTelemetry сервера и агента: провайдер спанов
+// с настраиваемым экспортером и передача контекста трассировки в заголовке W3C traceparent.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+// Экспортеры спанов
+const (
+	// ExporterNone - трассировка выключена, контекст входящих запросов все равно передается дальше
+	ExporterNone = "none"
+	// ExporterStdout - спаны в формате JSON в стандартный вывод, для локальной отладки
+	ExporterStdout = "stdout"
+	// ExporterFile - спаны в формате JSON, по одному на строку, в файл Config.Endpoint
+	ExporterFile = "file"
+	// ExporterOTLPHTTP, ExporterOTLPGRPC - отправка в коллектор OpenTelemetry по OTLP,
+	// Config.Endpoint - URL коллектора, например http://localhost:4318
+	ExporterOTLPHTTP = "otlp-http"
+	ExporterOTLPGRPC = "otlp-grpc"
+)
+
+// filePerm - права файла со спанами
+const filePerm = 0o600
+
+var ErrUnknownExporter = errors.New("unknown trace exporter")
+
+// Config - настройки трассировки
+type Config struct {
+	// Exporter - один из Exporter*, пустая строка - ExporterNone
+	Exporter string
+	// Endpoint - путь к файлу для ExporterFile, URL коллектора для OTLP.
+	// Если URL не задан, используются переменные окружения OTEL_EXPORTER_OTLP_*
+	// или адрес по умолчанию.
+	Endpoint string
+}
+
+// Setup - задает глобальные провайдер спанов с экспортером из c и передачу контекста
+// в заголовке traceparent. Возвращает функцию, которая выгружает накопленные спаны
+// и закрывает экспортер, ее нужно вызвать при завершении процесса.
+func Setup(ctx context.Context, serviceName string, c Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if c.Exporter == "" || c.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, c Config) (sdktrace.SpanExporter, error) {
+	switch c.Exporter {
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		f, err := os.OpenFile(c.Endpoint, os.O_CREATE|os.O_WRONLY|os.O_APPEND, filePerm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		return &closingExporter{SpanExporter: exporter, closer: f}, nil
+	case ExporterOTLPHTTP:
+		var opts []otlptracehttp.Option
+		if c.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(c.Endpoint))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case ExporterOTLPGRPC:
+		var opts []otlptracegrpc.Option
+		if c.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(c.Endpoint))
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownExporter, c.Exporter)
+}
+
+// closingExporter - экспортер в файл, который закрывается вместе с экспортером
+type closingExporter struct {
+	sdktrace.SpanExporter
+	closer io.Closer
+}
+
+func (e *closingExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.SpanExporter.Shutdown(ctx), e.closer.Close())
+}
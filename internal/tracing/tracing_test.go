@@ -0,0 +1,32 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup(t *testing.T) {
+	_, err := Setup(context.Background(), "test", Config{Exporter: "jaeger"})
+	require.ErrorIs(t, err, ErrUnknownExporter)
+
+	path := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err := Setup(context.Background(), "test", Config{Exporter: ExporterFile, Endpoint: path})
+	require.NoError(t, err)
+	_, span := otel.Tracer("test").Start(context.Background(), "cycle")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"cycle"`)
+	assert.Contains(t, string(data), `"Value":"test"`)
+
+	// контекст передается в заголовке W3C traceparent
+	assert.Equal(t, []string{"traceparent", "tracestate"}, otel.GetTextMapPropagator().Fields())
+}